@@ -0,0 +1,67 @@
+package resp3
+
+import (
+	"io"
+	"strconv"
+)
+
+// ChunkWriter writes a chunked blob string or blob error, emitting one chunk for each call to Write.
+//
+// A ChunkWriter is created using Writer.NewChunkWriter and must be closed using Close, which writes the terminating
+// zero-length chunk.
+type ChunkWriter struct {
+	rw      *Writer
+	written int64
+	closed  bool
+}
+
+var _ io.WriteCloser = (*ChunkWriter)(nil)
+
+// NewChunkWriter writes a stream header for a blob of the given type and returns a ChunkWriter for writing the chunks
+// of the blob.
+//
+// t must be either TypeBlobError or TypeBlobString. Otherwise NewChunkWriter panics.
+//
+// No other values must be written to rw until the returned ChunkWriter is closed.
+func (rw *Writer) NewChunkWriter(t Type) (*ChunkWriter, error) {
+	if t != TypeBlobError && t != TypeBlobString {
+		panic("resp3: invalid chunk writer type " + strconv.Quote(t.String()))
+	}
+	if err := rw.writeBlobStreamHeader(t); err != nil {
+		return nil, err
+	}
+	return &ChunkWriter{rw: rw}, nil
+}
+
+// Close writes the terminating zero-length chunk.
+//
+// If the ChunkWriter was already closed, ErrClosed is returned.
+func (cw *ChunkWriter) Close() error {
+	if cw.closed {
+		return ErrClosed
+	}
+	cw.closed = true
+	return cw.rw.WriteBlobChunk(nil)
+}
+
+// Write writes p as a single chunk. If p is empty, nothing is written.
+//
+// If the ChunkWriter was already closed, ErrClosed is returned.
+func (cw *ChunkWriter) Write(p []byte) (int, error) {
+	if cw.closed {
+		return 0, ErrClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := cw.rw.WriteBlobChunk(p); err != nil {
+		return 0, err
+	}
+	cw.written += int64(len(p))
+	return len(p), nil
+}
+
+// Written returns the total number of bytes written using Write, excluding any framing.
+func (cw *ChunkWriter) Written() int64 {
+	return cw.written
+}
@@ -0,0 +1,52 @@
+package resp3_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+func TestWriterNewChunkWriter(t *testing.T) {
+	rw, assert := newTestWriter(t)
+
+	cw, err := rw.NewChunkWriter(resp3.TypeBlobString)
+	assert("$?\r\n", nil, err)
+
+	n, err := cw.Write([]byte("hello"))
+	if n != 5 {
+		t.Errorf("got %d written bytes, expected 5", n)
+	}
+	assert(";5\r\nhello\r\n", nil, err)
+
+	n, err = cw.Write(nil)
+	if n != 0 {
+		t.Errorf("got %d written bytes, expected 0", n)
+	}
+	assert("", nil, err)
+
+	_, err = io.Copy(cw, strings.NewReader(" world"))
+	assert(";6\r\n world\r\n", nil, err)
+
+	if got := cw.Written(); got != 11 {
+		t.Errorf("got %d total bytes, expected 11", got)
+	}
+
+	assert(";0\r\n", nil, cw.Close())
+	assert("", resp3.ErrClosed, cw.Close())
+
+	_, err = cw.Write([]byte("hello"))
+	assert("", resp3.ErrClosed, err)
+
+	cw, err = rw.NewChunkWriter(resp3.TypeBlobError)
+	assert("!?\r\n", nil, err)
+	assert(";0\r\n", nil, cw.Close())
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid type")
+		}
+	}()
+	_, _ = rw.NewChunkWriter(resp3.TypeSimpleString)
+}
@@ -0,0 +1,36 @@
+package resp3
+
+import (
+	"io"
+)
+
+// CountingWriter is an io.Writer that discards all data written to it, while counting the number of bytes written
+// and the number of calls to Write.
+//
+// This can be used to measure the output of a Writer, for example in benchmarks, without keeping the data around.
+//
+// The zero value is ready to use. CountingWriter is not safe for concurrent use.
+type CountingWriter struct {
+	bytes int64
+	calls int64
+}
+
+var _ io.Writer = (*CountingWriter)(nil)
+
+// Counts returns the number of bytes written and the number of calls to Write since the CountingWriter was created or
+// last reset.
+func (cw *CountingWriter) Counts() (bytes, calls int64) {
+	return cw.bytes, cw.calls
+}
+
+// Reset sets both counts to 0.
+func (cw *CountingWriter) Reset() {
+	cw.bytes, cw.calls = 0, 0
+}
+
+// Write implements the io.Writer interface. It counts and discards p and never fails.
+func (cw *CountingWriter) Write(p []byte) (int, error) {
+	cw.bytes += int64(len(p))
+	cw.calls++
+	return len(p), nil
+}
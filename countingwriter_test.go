@@ -0,0 +1,33 @@
+package resp3_test
+
+import (
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+func TestCountingWriter(t *testing.T) {
+	var cw resp3.CountingWriter
+	rw := resp3.NewWriter(&cw)
+
+	assertError(t, nil, rw.WriteSimpleString([]byte("OK")))
+	assertError(t, nil, rw.WriteBlobString([]byte("hello")))
+
+	if bytes, calls := cw.Counts(); bytes != 16 || calls != 2 {
+		t.Errorf("got (%d, %d), expected (16, 2)", bytes, calls)
+	}
+
+	cw.Reset()
+	if bytes, calls := cw.Counts(); bytes != 0 || calls != 0 {
+		t.Errorf("got (%d, %d) after reset, expected (0, 0)", bytes, calls)
+	}
+
+	n, err := cw.Write(nil)
+	assertError(t, nil, err)
+	if n != 0 {
+		t.Errorf("got %d written bytes, expected 0", n)
+	}
+	if bytes, calls := cw.Counts(); bytes != 0 || calls != 1 {
+		t.Errorf("got (%d, %d), expected (0, 1)", bytes, calls)
+	}
+}
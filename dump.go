@@ -0,0 +1,205 @@
+package resp3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Fdump reads all values from r until EOF and writes a human-readable, indented representation of each value to w.
+//
+// Each value is written on its own line, starting with the type as returned by Type.String, followed by the value.
+// Values nested in aggregates or chunked blobs are indented by two spaces per level. The number of elements of
+// aggregates and the length of blobs are written in parentheses after the type, while streamed aggregates and
+// chunked blobs are marked as such, for example:
+//
+//	*(2)
+//	  $(5) "hello"
+//	  : 1
+//	~(streamed)
+//	  $(chunked)
+//	    ;(2) "he"
+//	    ;(0) ""
+//	  .
+//
+// Values are traversed the same way as by Reader.Discard with nested set to true and the same limits apply.
+//
+// Fdump is meant for debugging and the exact output format may change in the future.
+func Fdump(w io.Writer, r *Reader) error {
+	return FdumpDepth(w, r, 0)
+}
+
+// FdumpDepth is like Fdump, but only writes values up to the given nesting depth, with top-level values having a
+// depth of 0.
+//
+// Values nested deeper than maxDepth are read and discarded and replaced with a single line containing "...". This
+// limits the output when dumping deeply nested values from untrusted sources. If maxDepth is <= 0, all values are
+// written.
+func FdumpDepth(w io.Writer, r *Reader, maxDepth int) error {
+	d := dumper{w: w, r: r, maxDepth: maxDepth}
+	for {
+		if _, err := r.Peek(); errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err := d.dumpReply(); err != nil {
+			return err
+		}
+	}
+}
+
+type dumper struct {
+	w        io.Writer
+	r        *Reader
+	buf      []byte
+	maxDepth int
+}
+
+// truncated returns true if values at the given depth must be discarded instead of being written.
+func (d *dumper) truncated(depth int) bool {
+	return d.maxDepth > 0 && depth > d.maxDepth
+}
+
+func (d *dumper) printf(depth int, format string, args ...interface{}) error {
+	for i := 0; i < depth; i++ {
+		if _, err := io.WriteString(d.w, "  "); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(d.w, format+"\n", args...)
+	return err
+}
+
+// dumpReply dumps the next top-level value, counting it as a single reply like Discard.
+func (d *dumper) dumpReply() error {
+	d.r.beginReply()
+	defer d.r.endReply()
+	return d.dump(0)
+}
+
+func (d *dumper) dump(depth int) error {
+	t, err := d.r.Peek()
+	if err != nil {
+		return wrapEOF(err, "")
+	}
+
+	name := t.String()
+
+	switch t {
+	case TypeArray, TypeAttribute, TypeMap, TypePush, TypeSet:
+		return d.dumpAggregate(depth, t)
+	case TypeBigNumber:
+		var n big.Int
+		if err := d.r.ReadBigNumber(&n); err != nil {
+			return err
+		}
+		return d.printf(depth, "%s %s", name, n.String())
+	case TypeBlobChunk:
+		b, _, err := d.r.ReadBlobChunk(d.buf[:0])
+		if err != nil {
+			return err
+		}
+		d.buf = b
+		return d.printf(depth, "%s(%d) %q", name, len(b), b)
+	case TypeBlobError, TypeBlobString:
+		return d.dumpBlob(depth, t)
+	case TypeBoolean:
+		b, err := d.r.ReadBoolean()
+		if err != nil {
+			return err
+		}
+		return d.printf(depth, "%s %t", name, b)
+	case TypeDouble:
+		f, err := d.r.ReadDouble()
+		if err != nil {
+			return err
+		}
+		return d.printf(depth, "%s %v", name, f)
+	case TypeEnd:
+		if err := d.r.ReadEnd(); err != nil {
+			return err
+		}
+		return d.printf(depth, "%s", name)
+	case TypeNull:
+		if err := d.r.ReadNull(); err != nil {
+			return err
+		}
+		return d.printf(depth, "%s", name)
+	case TypeNumber:
+		n, err := d.r.ReadNumber()
+		if err != nil {
+			return err
+		}
+		return d.printf(depth, "%s %d", name, n)
+	case TypeSimpleError, TypeSimpleString:
+		b, err := d.r.readSimple(t, d.buf[:0])
+		if err != nil {
+			return err
+		}
+		d.buf = b
+		return d.printf(depth, "%s %q", name, b)
+	case TypeVerbatimString:
+		b, err := d.r.ReadVerbatimString(d.buf[:0])
+		if err != nil {
+			return err
+		}
+		d.buf = b
+		return d.printf(depth, "%s(%d) %q", name, len(b), b)
+	default:
+		return fmt.Errorf("%w: got %q", ErrUnexpectedType, t)
+	}
+}
+
+func (d *dumper) dumpAggregate(depth int, t Type) error {
+	truncated := d.truncated(depth + 1)
+	var streamed bool
+	err := d.r.walkAggregate(t, func(n int64) error {
+		streamed = n < 0
+		var err error
+		if streamed {
+			err = d.printf(depth, "%s(streamed)", t)
+		} else {
+			err = d.printf(depth, "%s(%d)", t, n)
+		}
+		if err == nil && truncated && n != 0 {
+			err = d.printf(depth+1, "...")
+		}
+		return err
+	}, func() error {
+		if truncated {
+			_, err := d.r.Discard(true)
+			return err
+		}
+		return d.dump(depth + 1)
+	})
+	if err != nil || !streamed || truncated {
+		return err
+	}
+	return d.printf(depth+1, "%s", TypeEnd)
+}
+
+func (d *dumper) dumpBlob(depth int, t Type) error {
+	b, chunked, err := d.r.readChunkableBlob(t, d.buf[:0])
+	if err != nil {
+		return err
+	}
+	d.buf = b
+
+	if !chunked {
+		return d.printf(depth, "%s(%d) %q", t, len(b), b)
+	}
+
+	if err := d.printf(depth, "%s(chunked)", t); err != nil {
+		return err
+	}
+	if d.truncated(depth + 1) {
+		if err := d.printf(depth+1, "..."); err != nil {
+			return err
+		}
+		return d.r.discardBlobChunks()
+	}
+	d.buf, err = d.r.readBlobChunks(d.buf[:0], func(chunk []byte) error {
+		return d.printf(depth+1, "%s(%d) %q", TypeBlobChunk, len(chunk), chunk)
+	})
+	return err
+}
@@ -0,0 +1,206 @@
+package resp3_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+func TestFdump(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   string
+		out  string
+		err  error
+	}{
+		{name: "Empty"},
+		{
+			name: "Scalars",
+			in: "(123456789123456789123456789\r\n#t\r\n,1.5\r\n_\r\n:-10\r\n" +
+				"+OK\r\n-ERR hello\r\n$5\r\nhello\r\n!3\r\nERR\r\n=7\r\ntxt:abc\r\n",
+			out: `( 123456789123456789123456789
+# true
+, 1.5
+_
+: -10
++ "OK"
+- "ERR hello"
+$(5) "hello"
+!(3) "ERR"
+=(7) "txt:abc"
+`,
+		},
+		{
+			name: "Aggregates",
+			in:   "*2\r\n$5\r\nhello\r\n%1\r\n+a\r\n~1\r\n:1\r\n|1\r\n+ttl\r\n:10\r\n>1\r\n+message\r\n",
+			out: `*(2)
+  $(5) "hello"
+  %(1)
+    + "a"
+    ~(1)
+      : 1
+|(1)
+  + "ttl"
+  : 10
+>(1)
+  + "message"
+`,
+		},
+		{
+			name: "Streamed",
+			in:   "*?\r\n:1\r\n$?\r\n;2\r\nhe\r\n;3\r\nllo\r\n;0\r\n.\r\n",
+			out: `*(streamed)
+  : 1
+  $(chunked)
+    ;(2) "he"
+    ;(3) "llo"
+    ;(0) ""
+  .
+`,
+		},
+		{
+			name: "RESP2Null",
+			in:   "*-1\r\n$-1\r\n",
+			out:  "_\n_\n",
+		},
+		{
+			name: "Incomplete",
+			in:   "*2\r\n:1\r\n",
+			out:  "*(2)\n  : 1\n",
+			err:  resp3.ErrUnexpectedEOL,
+		},
+		{
+			name: "MapTooLong",
+			in:   "%4611686018427387904\r\n",
+			out:  "%(4611686018427387904)\n",
+			err:  resp3.ErrInvalidAggregateTypeLength,
+		},
+		{
+			name: "Invalid",
+			in:   "+OK\r\nA",
+			out:  "+ \"OK\"\n",
+			err:  resp3.ErrInvalidType,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var out strings.Builder
+			rr, _ := newTestReader(c.in)
+			assertError(t, c.err, resp3.Fdump(&out, rr))
+			if got := out.String(); got != c.out {
+				t.Errorf("got\n%s\nexpected\n%s", got, c.out)
+			}
+		})
+	}
+}
+
+func TestFdumpDepth(t *testing.T) {
+	const in = "*2\r\n:1\r\n*2\r\n%1\r\n+a\r\n:2\r\n$?\r\n;1\r\na\r\n;0\r\n" +
+		"~?\r\n*1\r\n:3\r\n.\r\n:4\r\n"
+
+	for _, c := range []struct {
+		depth int
+		out   string
+	}{
+		{
+			depth: 0,
+			out: `*(2)
+  : 1
+  *(2)
+    %(1)
+      + "a"
+      : 2
+    $(chunked)
+      ;(1) "a"
+      ;(0) ""
+~(streamed)
+  *(1)
+    : 3
+  .
+: 4
+`,
+		},
+		{
+			depth: 1,
+			out: `*(2)
+  : 1
+  *(2)
+    ...
+~(streamed)
+  *(1)
+    ...
+  .
+: 4
+`,
+		},
+		{
+			depth: 2,
+			out: `*(2)
+  : 1
+  *(2)
+    %(1)
+      ...
+    $(chunked)
+      ...
+~(streamed)
+  *(1)
+    : 3
+  .
+: 4
+`,
+		},
+	} {
+		var out strings.Builder
+		rr, _ := newTestReader(in)
+		assertError(t, nil, resp3.FdumpDepth(&out, rr, c.depth))
+		if got := out.String(); got != c.out {
+			t.Errorf("got\n%s\nexpected\n%s", got, c.out)
+		}
+	}
+}
+
+func TestFdumpLimits(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		in    string
+		setup func(rr *resp3.Reader)
+		err   error
+	}{
+		{
+			name:  "MaxChunks",
+			in:    "*?\r\n:1\r\n:2\r\n.\r\n",
+			setup: func(rr *resp3.Reader) { rr.MaxChunks = 1 },
+			err:   resp3.ErrTooManyChunks,
+		},
+		{
+			name:  "MaxChunksBlob",
+			in:    "$?\r\n;1\r\na\r\n;1\r\nb\r\n;0\r\n",
+			setup: func(rr *resp3.Reader) { rr.MaxChunks = 1 },
+			err:   resp3.ErrTooManyChunks,
+		},
+		{
+			name:  "MaxReplyDepth",
+			in:    "*1\r\n*1\r\n:1\r\n",
+			setup: func(rr *resp3.Reader) { rr.MaxReplyDepth = 1 },
+			err:   resp3.ErrValueTooDeep,
+		},
+		{
+			name:  "MaxReplyNodes",
+			in:    "*2\r\n*2\r\n:1\r\n:2\r\n*0\r\n",
+			setup: func(rr *resp3.Reader) { rr.MaxReplyNodes = 3 },
+			err:   resp3.ErrAggregateTooLong,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rr, _ := newTestReader(c.in)
+			c.setup(rr)
+			_, err := rr.Discard(true)
+			assertError(t, c.err, err)
+
+			rr, _ = newTestReader(c.in)
+			c.setup(rr)
+			assertError(t, c.err, resp3.Fdump(ioutil.Discard, rr))
+		})
+	}
+}
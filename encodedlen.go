@@ -0,0 +1,81 @@
+package resp3
+
+// The EncodedLen functions return the number of bytes written by the corresponding Writer methods.
+//
+// The returned lengths assume that the Writer is configured to write RESP3 (Writer.Protocol is not 2) and uses the
+// default \r\n line ending (Writer.LineEnding is empty or \r\n). If LineEnding is \n, every line written by the Writer
+// is one byte shorter than accounted for, including the trailing line of blob strings and blob errors.
+
+// EncodedLenArrayHeader returns the number of bytes written by Writer.WriteArrayHeader(n).
+func EncodedLenArrayHeader(n int64) int {
+	return encodedLenNumberLine(n)
+}
+
+// EncodedLenAttributeHeader returns the number of bytes written by Writer.WriteAttributeHeader(n).
+func EncodedLenAttributeHeader(n int64) int {
+	return encodedLenNumberLine(n)
+}
+
+// EncodedLenBlobError returns the number of bytes written by Writer.WriteBlobError for an error of length n.
+func EncodedLenBlobError(n int) int {
+	return encodedLenBlob(n)
+}
+
+// EncodedLenBlobString returns the number of bytes written by Writer.WriteBlobString for a string of length n.
+func EncodedLenBlobString(n int) int {
+	return encodedLenBlob(n)
+}
+
+// EncodedLenInteger returns the number of bytes written by Writer.WriteNumber(n).
+func EncodedLenInteger(n int64) int {
+	return encodedLenNumberLine(n)
+}
+
+// EncodedLenMapHeader returns the number of bytes written by Writer.WriteMapHeader(n).
+func EncodedLenMapHeader(n int64) int {
+	return encodedLenNumberLine(n)
+}
+
+// EncodedLenPushHeader returns the number of bytes written by Writer.WritePushHeader(n).
+func EncodedLenPushHeader(n int64) int {
+	return encodedLenNumberLine(n)
+}
+
+// EncodedLenSetHeader returns the number of bytes written by Writer.WriteSetHeader(n).
+func EncodedLenSetHeader(n int64) int {
+	return encodedLenNumberLine(n)
+}
+
+// EncodedLenSimpleError returns the number of bytes written by Writer.WriteSimpleError for an error of length n.
+func EncodedLenSimpleError(n int) int {
+	return len("-") + n + len("\r\n")
+}
+
+// EncodedLenSimpleString returns the number of bytes written by Writer.WriteSimpleString for a string of length n.
+func EncodedLenSimpleString(n int) int {
+	return len("+") + n + len("\r\n")
+}
+
+// EncodedLenVerbatimString returns the number of bytes written by Writer.WriteVerbatimString for a string of length
+// n, excluding the format prefix.
+func EncodedLenVerbatimString(n int) int {
+	return encodedLenBlob(verbatimPrefixLength + len(":") + n)
+}
+
+func encodedLenBlob(n int) int {
+	return encodedLenNumberLine(int64(n)) + n + len("\r\n")
+}
+
+// encodedLenNumberLine returns the length of a line consisting of a type byte followed by the number n.
+func encodedLenNumberLine(n int64) int {
+	l := len("$") + len("\r\n") + 1
+	u := uint64(n)
+	if n < 0 {
+		l++
+		u = -u
+	}
+	for ; u >= 10; u /= 10 {
+		l++
+	}
+	return l
+}
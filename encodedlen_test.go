@@ -0,0 +1,65 @@
+package resp3_test
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+func TestEncodedLen(t *testing.T) {
+	var b bytes.Buffer
+	rw := resp3.NewWriter(&b)
+
+	for _, n := range []int64{0, 1, 9, 10, 99, 100, 12345, math.MaxInt64} {
+		for _, c := range []struct {
+			name  string
+			len   func(int64) int
+			write func(int64) error
+		}{
+			{"ArrayHeader", resp3.EncodedLenArrayHeader, rw.WriteArrayHeader},
+			{"AttributeHeader", resp3.EncodedLenAttributeHeader, rw.WriteAttributeHeader},
+			{"MapHeader", resp3.EncodedLenMapHeader, rw.WriteMapHeader},
+			{"PushHeader", resp3.EncodedLenPushHeader, rw.WritePushHeader},
+			{"SetHeader", resp3.EncodedLenSetHeader, rw.WriteSetHeader},
+			{"Integer", resp3.EncodedLenInteger, rw.WriteNumber},
+			{"NegativeInteger", func(n int64) int { return resp3.EncodedLenInteger(-n) },
+				func(n int64) error { return rw.WriteNumber(-n) }},
+		} {
+			b.Reset()
+			assertError(t, nil, c.write(n))
+			if got := c.len(n); got != b.Len() {
+				t.Errorf("%s(%d): got %d, expected %d", c.name, n, got, b.Len())
+			}
+		}
+	}
+
+	b.Reset()
+	assertError(t, nil, rw.WriteNumber(math.MinInt64))
+	if got := resp3.EncodedLenInteger(math.MinInt64); got != b.Len() {
+		t.Errorf("Integer(%d): got %d, expected %d", int64(math.MinInt64), got, b.Len())
+	}
+
+	for _, n := range []int{0, 1, 9, 10, 1000} {
+		s := strings.Repeat("a", n)
+		for _, c := range []struct {
+			name  string
+			len   func(int) int
+			write func() error
+		}{
+			{"BlobError", resp3.EncodedLenBlobError, func() error { return rw.WriteBlobError([]byte(s)) }},
+			{"BlobString", resp3.EncodedLenBlobString, func() error { return rw.WriteBlobString([]byte(s)) }},
+			{"SimpleError", resp3.EncodedLenSimpleError, func() error { return rw.WriteSimpleError([]byte(s)) }},
+			{"SimpleString", resp3.EncodedLenSimpleString, func() error { return rw.WriteSimpleString([]byte(s)) }},
+			{"VerbatimString", resp3.EncodedLenVerbatimString, func() error { return rw.WriteVerbatimString("txt", s) }},
+		} {
+			b.Reset()
+			assertError(t, nil, c.write())
+			if got := c.len(n); got != b.Len() {
+				t.Errorf("%s(%d): got %d, expected %d", c.name, n, got, b.Len())
+			}
+		}
+	}
+}
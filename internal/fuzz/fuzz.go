@@ -28,6 +28,7 @@ var ReaderFuncs = []struct {
 	{Name: "Set", Func: func(rr *resp3.Reader) error { _, _, err := rr.ReadSetHeader(); return err }},
 	{Name: "SimpleError", Func: func(rr *resp3.Reader) error { _, err := rr.ReadSimpleError(nil); return err }},
 	{Name: "SimpleString", Func: func(rr *resp3.Reader) error { _, err := rr.ReadSimpleString(nil); return err }},
+	{Name: "Value", Func: func(rr *resp3.Reader) error { _, err := rr.ReadValue(); return err }},
 	{Name: "VerbatimString", Func: func(rr *resp3.Reader) error { _, err := rr.ReadVerbatimString(nil); return err }},
 
 	{Name: "Discard", Func: func(rr *resp3.Reader) error { _, err := rr.Discard(false); return err }},
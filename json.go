@@ -0,0 +1,300 @@
+package resp3
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ReadJSON reads the next value, including all nested values, and writes its JSON representation to w.
+//
+// Values are converted as follows:
+//
+//	TypeArray, TypePush, TypeSet        array
+//	TypeBigNumber                       string containing the decimal representation of the number
+//	TypeBlobError, TypeSimpleError      object with a single "error" key containing the error as string
+//	TypeBlobString, TypeSimpleString    string
+//	TypeBoolean                         true or false
+//	TypeDouble                          number, or one of the strings "inf", "-inf" or "nan"
+//	TypeMap                             object
+//	TypeNull                            null
+//	TypeNumber                          number
+//	TypeVerbatimString                  string (without the format prefix)
+//
+// Strings that are not valid UTF-8 are encoded using standard base64 encoding.
+//
+// Map keys are always converted to strings. Numbers, doubles and big numbers use their decimal representation,
+// booleans are converted to "true" or "false" and null to "null". If a map key is an aggregate or an error,
+// ErrUnexpectedType is returned.
+//
+// Note that the conversion is lossy: sets and push data can not be distinguished from arrays, big numbers and
+// special doubles can not be distinguished from strings and the original type of map keys is lost. Attributes
+// preceding a value are discarded.
+//
+// The output is only written to w after the whole value was read successfully.
+func (rr *Reader) ReadJSON(w io.Writer) error {
+	c := jsonConverter{r: rr}
+	if err := c.convert(); err != nil {
+		return err
+	}
+	_, err := w.Write(c.out)
+	return err
+}
+
+type jsonConverter struct {
+	r   *Reader
+	out []byte
+	buf []byte
+}
+
+func (c *jsonConverter) convert() error {
+	t, err := c.r.Peek()
+	if err != nil {
+		return wrapEOF(err, "")
+	}
+
+	switch t {
+	case TypeArray, TypePush, TypeSet:
+		return c.convertAggregate(t)
+	case TypeAttribute:
+		if _, err := c.r.Discard(true); err != nil {
+			return err
+		}
+		return c.convert()
+	case TypeBigNumber:
+		var n big.Int
+		if err := c.r.ReadBigNumber(&n); err != nil {
+			return err
+		}
+		c.out = append(c.out, '"')
+		c.out = n.Append(c.out, 10)
+		c.out = append(c.out, '"')
+	case TypeBlobError, TypeSimpleError:
+		b, err := c.readString(t)
+		if err != nil {
+			return err
+		}
+		c.out = append(c.out, `{"error":`...)
+		c.out = appendJSONString(c.out, b)
+		c.out = append(c.out, '}')
+	case TypeBlobString, TypeSimpleString:
+		b, err := c.readString(t)
+		if err != nil {
+			return err
+		}
+		c.out = appendJSONString(c.out, b)
+	case TypeBoolean:
+		b, err := c.r.ReadBoolean()
+		if err != nil {
+			return err
+		}
+		c.out = strconv.AppendBool(c.out, b)
+	case TypeDouble:
+		f, err := c.r.ReadDouble()
+		if err != nil {
+			return err
+		}
+		c.out = appendJSONDouble(c.out, f)
+	case TypeMap:
+		return c.convertMap()
+	case TypeNull:
+		if err := c.r.ReadNull(); err != nil {
+			return err
+		}
+		c.out = append(c.out, "null"...)
+	case TypeNumber:
+		n, err := c.r.ReadNumber()
+		if err != nil {
+			return err
+		}
+		c.out = strconv.AppendInt(c.out, n, 10)
+	case TypeVerbatimString:
+		b, err := c.readString(t)
+		if err != nil {
+			return err
+		}
+		c.out = appendJSONString(c.out, b[verbatimPrefixLength+1:])
+	default:
+		return fmt.Errorf("%w: got %q", ErrUnexpectedType, t)
+	}
+
+	return nil
+}
+
+func (c *jsonConverter) convertAggregate(t Type) error {
+	n, chunked, err := c.r.readAggregateHeader(t)
+	if err != nil {
+		return err
+	}
+	c.out = append(c.out, '[')
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := c.r.moreChunks(i); err != nil || !more {
+				c.out = append(c.out, ']')
+				return err
+			}
+		}
+		if i > 0 {
+			c.out = append(c.out, ',')
+		}
+		if err := c.convert(); err != nil {
+			return err
+		}
+	}
+	c.out = append(c.out, ']')
+	return nil
+}
+
+func (c *jsonConverter) convertMap() error {
+	n, chunked, err := c.r.readAggregateHeader(TypeMap)
+	if err != nil {
+		return err
+	}
+	c.out = append(c.out, '{')
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := c.r.moreChunks(i); err != nil || !more {
+				c.out = append(c.out, '}')
+				return err
+			}
+		}
+		if i > 0 {
+			c.out = append(c.out, ',')
+		}
+		if err := c.convertKey(); err != nil {
+			return err
+		}
+		c.out = append(c.out, ':')
+		if err := c.convert(); err != nil {
+			return err
+		}
+	}
+	c.out = append(c.out, '}')
+	return nil
+}
+
+func (c *jsonConverter) convertKey() error {
+	t, err := c.r.Peek()
+	if err != nil {
+		return wrapEOF(err, "")
+	}
+
+	var b []byte
+
+	switch t {
+	case TypeBigNumber:
+		var n big.Int
+		if err := c.r.ReadBigNumber(&n); err != nil {
+			return err
+		}
+		b = n.Append(c.buf[:0], 10)
+	case TypeBlobString, TypeSimpleString, TypeVerbatimString:
+		if b, err = c.readString(t); err != nil {
+			return err
+		}
+		if t == TypeVerbatimString {
+			b = b[verbatimPrefixLength+1:]
+		}
+	case TypeBoolean:
+		v, err := c.r.ReadBoolean()
+		if err != nil {
+			return err
+		}
+		b = strconv.AppendBool(c.buf[:0], v)
+	case TypeDouble:
+		f, err := c.r.ReadDouble()
+		if err != nil {
+			return err
+		}
+		b = appendJSONDouble(c.buf[:0], f)
+		if b[0] == '"' {
+			b = b[1 : len(b)-1]
+		}
+	case TypeNull:
+		if err := c.r.ReadNull(); err != nil {
+			return err
+		}
+		b = append(c.buf[:0], "null"...)
+	case TypeNumber:
+		n, err := c.r.ReadNumber()
+		if err != nil {
+			return err
+		}
+		b = strconv.AppendInt(c.buf[:0], n, 10)
+	default:
+		return fmt.Errorf("%w: can not use %q as JSON object key", ErrUnexpectedType, t)
+	}
+
+	c.buf = b
+	c.out = appendJSONString(c.out, b)
+	return nil
+}
+
+func (c *jsonConverter) readString(t Type) ([]byte, error) {
+	var b []byte
+	var err error
+
+	switch t {
+	case TypeBlobError, TypeBlobString:
+		var chunked bool
+		if b, chunked, err = c.r.readChunkableBlob(t, c.buf[:0]); err == nil && chunked {
+			b, err = c.r.ReadBlobChunks(b)
+		}
+	case TypeVerbatimString:
+		b, err = c.r.ReadVerbatimString(c.buf[:0])
+	default:
+		b, err = c.r.readSimple(t, c.buf[:0])
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	c.buf = b
+	return b, nil
+}
+
+func appendJSONDouble(dst []byte, f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return append(dst, `"inf"`...)
+	case math.IsInf(f, -1):
+		return append(dst, `"-inf"`...)
+	case math.IsNaN(f):
+		return append(dst, `"nan"`...)
+	default:
+		return strconv.AppendFloat(dst, f, 'g', -1, 64)
+	}
+}
+
+const jsonHex = "0123456789abcdef"
+
+func appendJSONString(dst []byte, b []byte) []byte {
+	dst = append(dst, '"')
+	if !utf8.Valid(b) {
+		n := len(dst)
+		dst = append(dst, make([]byte, base64.StdEncoding.EncodedLen(len(b)))...)
+		base64.StdEncoding.Encode(dst[n:], b)
+		return append(dst, '"')
+	}
+	for _, c := range b {
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', jsonHex[c>>4], jsonHex[c&0xF])
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
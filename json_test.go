@@ -0,0 +1,81 @@
+package resp3_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+func TestReaderReadJSON(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		out  string
+		err  error
+		rest string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: ".\r\n", err: resp3.ErrUnexpectedType},
+
+		{in: "(123456789123456789123456789\r\n", out: `"123456789123456789123456789"`},
+		{in: "#t\r\n", out: `true`},
+		{in: "#f\r\n", out: `false`},
+		{in: ",1.5\r\n", out: `1.5`},
+		{in: ",-10\r\n", out: `-10`},
+		{in: ",inf\r\n", out: `"inf"`},
+		{in: ",-inf\r\n", out: `"-inf"`},
+		{in: "_\r\n", out: `null`},
+		{in: "$-1\r\n", out: `null`},
+		{in: ":-10\r\n", out: `-10`},
+
+		{in: "+OK\r\n", out: `"OK"`},
+		{in: "$10\r\nhello\r\n\"\\\t\r\n", out: `"hello\r\n\"\\\t"`},
+		{in: "$1\r\n\x01\r\n", out: `"\u0001"`},
+		{in: "$3\r\n\xff\xfe\xfd\r\n", out: `"//79"`},
+		{in: "$?\r\n;5\r\nhello\r\n;0\r\n", out: `"hello"`},
+		{in: "=9\r\ntxt:hello\r\n", out: `"hello"`},
+		{in: "-ERR hello\r\n", out: `{"error":"ERR hello"}`},
+		{in: "!3\r\nERR\r\n", out: `{"error":"ERR"}`},
+
+		{in: "*0\r\n", out: `[]`},
+		{in: "*3\r\n:1\r\n+a\r\n*1\r\n_\r\n", out: `[1,"a",[null]]`},
+		{in: "*?\r\n:1\r\n:2\r\n.\r\n", out: `[1,2]`},
+		{in: "*?\r\n.\r\n", out: `[]`},
+		{in: "~2\r\n:1\r\n:2\r\n", out: `[1,2]`},
+		{in: ">1\r\n+message\r\n", out: `["message"]`},
+		{in: "*2\r\n:1\r\n", err: resp3.ErrUnexpectedEOL},
+
+		{in: "%0\r\n", out: `{}`},
+		{in: "%2\r\n+b\r\n:1\r\n+a\r\n:2\r\n", out: `{"b":1,"a":2}`},
+		{in: "%?\r\n+a\r\n:1\r\n.\r\n", out: `{"a":1}`},
+		{
+			in:  "%6\r\n:1\r\n_\r\n,1.5\r\n_\r\n#t\r\n_\r\n_\r\n_\r\n(12\r\n_\r\n,inf\r\n_\r\n",
+			out: `{"1":null,"1.5":null,"true":null,"null":null,"12":null,"inf":null}`,
+		},
+		{in: "%1\r\n*0\r\n:1\r\n", err: resp3.ErrUnexpectedType},
+		{in: "%1\r\n-ERR\r\n:1\r\n", err: resp3.ErrUnexpectedType},
+
+		{in: "|1\r\n+ttl\r\n:10\r\n:1\r\n", out: `1`},
+
+		{in: ":1\r\n:2\r\n", out: `1`, rest: ":2\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		var out strings.Builder
+		assertError(t, c.err, rr.ReadJSON(&out))
+		if got := out.String(); got != c.out {
+			t.Errorf("got %s, expected %s", got, c.out)
+		}
+		if c.err != nil {
+			continue
+		}
+		if !json.Valid([]byte(c.out)) {
+			t.Errorf("output %s is not valid JSON", c.out)
+		}
+		if got := rest(); got != c.rest {
+			t.Errorf("got %q left in input, expected %q", got, c.rest)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package resp3
+
+// MapStreamer writes a streamed map or attribute, ensuring that keys and values are always written in pairs.
+//
+// A MapStreamer is created using Writer.NewMapStreamer or Writer.NewAttributeStreamer and must be closed using Close,
+// which writes the end marker.
+type MapStreamer struct {
+	rw     *Writer
+	pairs  int64
+	closed bool
+}
+
+// NewAttributeStreamer writes a stream header for an attribute and returns a MapStreamer for writing the entries of
+// the attribute.
+//
+// No other values must be written to rw until the returned MapStreamer is closed, except by the functions passed to
+// WritePair.
+func (rw *Writer) NewAttributeStreamer() (*MapStreamer, error) {
+	if err := rw.WriteAttributeStreamHeader(); err != nil {
+		return nil, err
+	}
+	return &MapStreamer{rw: rw}, nil
+}
+
+// NewMapStreamer writes a stream header for a map and returns a MapStreamer for writing the entries of the map.
+//
+// No other values must be written to rw until the returned MapStreamer is closed, except by the functions passed to
+// WritePair.
+func (rw *Writer) NewMapStreamer() (*MapStreamer, error) {
+	if err := rw.WriteMapStreamHeader(); err != nil {
+		return nil, err
+	}
+	return &MapStreamer{rw: rw}, nil
+}
+
+// Close writes the end marker.
+//
+// If the MapStreamer was already closed, ErrClosed is returned.
+func (ms *MapStreamer) Close() error {
+	if ms.closed {
+		return ErrClosed
+	}
+	ms.closed = true
+	return ms.rw.WriteEnd()
+}
+
+// Pairs returns the number of key-value pairs written using WritePair.
+func (ms *MapStreamer) Pairs() int64 {
+	return ms.pairs
+}
+
+// WritePair writes a single entry by calling writeKey and writeValue, which must each write exactly one value, in
+// that order.
+//
+// If writeKey returns an error, writeValue is not called. If either function returns an error, the entry may be
+// incomplete and the stream should not be used anymore.
+//
+// If the MapStreamer was already closed, ErrClosed is returned.
+func (ms *MapStreamer) WritePair(writeKey, writeValue func(*Writer) error) error {
+	if ms.closed {
+		return ErrClosed
+	}
+	if err := writeKey(ms.rw); err != nil {
+		return err
+	}
+	if err := writeValue(ms.rw); err != nil {
+		return err
+	}
+	ms.pairs++
+	return nil
+}
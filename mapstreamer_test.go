@@ -0,0 +1,75 @@
+package resp3_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+func TestWriterNewMapStreamer(t *testing.T) {
+	rw, assert := newTestWriter(t)
+
+	ms, err := rw.NewMapStreamer()
+	assert("%?\r\n", nil, err)
+
+	writeKey := func(k string) func(*resp3.Writer) error {
+		return func(rw *resp3.Writer) error {
+			return rw.WriteSimpleString([]byte(k))
+		}
+	}
+
+	assert("+a\r\n:1\r\n", nil, ms.WritePair(writeKey("a"), func(rw *resp3.Writer) error {
+		return rw.WriteNumber(1)
+	}))
+	assert("+b\r\n*1\r\n_\r\n", nil, ms.WritePair(writeKey("b"), func(rw *resp3.Writer) error {
+		if err := rw.WriteArrayHeader(1); err != nil {
+			return err
+		}
+		return rw.WriteNull()
+	}))
+
+	if got := ms.Pairs(); got != 2 {
+		t.Errorf("got %d pairs, expected 2", got)
+	}
+
+	assert(".\r\n", nil, ms.Close())
+	assert("", resp3.ErrClosed, ms.Close())
+	assert("", resp3.ErrClosed, ms.WritePair(writeKey("c"), writeKey("d")))
+}
+
+func TestWriterNewMapStreamerError(t *testing.T) {
+	rw, assert := newTestWriter(t)
+
+	ms, err := rw.NewMapStreamer()
+	assert("%?\r\n", nil, err)
+
+	errTest := errors.New("test")
+	called := false
+	assert("", errTest, ms.WritePair(func(*resp3.Writer) error {
+		return errTest
+	}, func(*resp3.Writer) error {
+		called = true
+		return nil
+	}))
+	if called {
+		t.Error("value function called after key function failed")
+	}
+	if got := ms.Pairs(); got != 0 {
+		t.Errorf("got %d pairs, expected 0", got)
+	}
+}
+
+func TestWriterNewAttributeStreamer(t *testing.T) {
+	rw, assert := newTestWriter(t)
+
+	ms, err := rw.NewAttributeStreamer()
+	assert("|?\r\n", nil, err)
+
+	assert("+ttl\r\n:10\r\n", nil, ms.WritePair(func(rw *resp3.Writer) error {
+		return rw.WriteSimpleString([]byte("ttl"))
+	}, func(rw *resp3.Writer) error {
+		return rw.WriteNumber(10)
+	}))
+	assert(".\r\n", nil, ms.Close())
+}
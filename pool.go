@@ -0,0 +1,59 @@
+package resp3
+
+import (
+	"io"
+	"sync"
+)
+
+var readerPool = sync.Pool{
+	New: func() interface{} {
+		return NewReader(nil)
+	},
+}
+
+var writerPool = sync.Pool{
+	New: func() interface{} {
+		return NewWriter(nil)
+	},
+}
+
+// GetReader returns a *Reader from a package-level pool that uses the given io.Reader for reads.
+//
+// The returned Reader behaves the same as one returned by NewReader. It can be returned to the pool using PutReader
+// once it is no longer used.
+func GetReader(r io.Reader) *Reader {
+	rr := readerPool.Get().(*Reader)
+	rr.Reset(r)
+	return rr
+}
+
+// PutReader resets rr and returns it to the pool used by GetReader.
+//
+// All references to the underlying io.Reader are released and all exported fields are set to their zero values.
+// rr must not be used after calling PutReader.
+func PutReader(rr *Reader) {
+	rr.Reset(nil)
+	*rr = Reader{ownbr: rr.ownbr, tokenBuf: rr.tokenBuf[:0]}
+	rr.br = rr.ownbr
+	readerPool.Put(rr)
+}
+
+// GetWriter returns a *Writer from a package-level pool that uses the given io.Writer for writes.
+//
+// The returned Writer behaves the same as one returned by NewWriter. It can be returned to the pool using PutWriter
+// once it is no longer used.
+func GetWriter(w io.Writer) *Writer {
+	rw := writerPool.Get().(*Writer)
+	rw.Reset(w)
+	return rw
+}
+
+// PutWriter resets rw and returns it to the pool used by GetWriter.
+//
+// All references to the underlying io.Writer are released and all exported fields and options are reset to their
+// defaults. Any data buffered using WithBuffer is discarded. rw must not be used after calling PutWriter.
+func PutWriter(rw *Writer) {
+	*rw = Writer{buf: rw.buf[:0]}
+	rw.Reset(nil)
+	writerPool.Put(rw)
+}
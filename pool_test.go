@@ -0,0 +1,92 @@
+package resp3_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+func TestReaderPool(t *testing.T) {
+	rr := resp3.GetReader(strings.NewReader(":1\r\n"))
+	rr.Protocol = 2
+	if n, err := rr.ReadNumber(); err != nil || n != 1 {
+		t.Fatalf("got (%d, %v), expected (1, nil)", n, err)
+	}
+	resp3.PutReader(rr)
+	assertError(t, resp3.ErrClosed, rr.ReadEnd())
+	if rr.Protocol != 0 {
+		t.Errorf("got protocol %d after PutReader, expected 0", rr.Protocol)
+	}
+
+	rr = resp3.GetReader(strings.NewReader(".\r\n"))
+	assertError(t, nil, rr.ReadEnd())
+	resp3.PutReader(rr)
+}
+
+func TestWriterPool(t *testing.T) {
+	var b bytes.Buffer
+	rw := resp3.GetWriter(&b)
+	rw.Protocol = 2
+	assertError(t, nil, rw.WriteNull())
+	resp3.PutWriter(rw)
+	assertError(t, resp3.ErrClosed, rw.WriteNull())
+	if rw.Protocol != 0 {
+		t.Errorf("got protocol %d after PutWriter, expected 0", rw.Protocol)
+	}
+
+	rw = resp3.GetWriter(&b)
+	assertError(t, nil, rw.WriteNull())
+	resp3.PutWriter(rw)
+	assertBytes(t, "$-1\r\n_\r\n", b.Bytes())
+}
+
+func BenchmarkReaderPool(b *testing.B) {
+	const in = "+OK\r\n"
+
+	b.Run("NewReader", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rr := resp3.NewReader(strings.NewReader(in))
+			if _, err := rr.ReadSimpleString(nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("GetReader", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rr := resp3.GetReader(strings.NewReader(in))
+			if _, err := rr.ReadSimpleString(nil); err != nil {
+				b.Fatal(err)
+			}
+			resp3.PutReader(rr)
+		}
+	})
+}
+
+func BenchmarkWriterPool(b *testing.B) {
+	b.Run("NewWriter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rw := resp3.NewWriter(ioutil.Discard)
+			if err := rw.WriteNumber(1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("GetWriter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rw := resp3.GetWriter(ioutil.Discard)
+			if err := rw.WriteNumber(1); err != nil {
+				b.Fatal(err)
+			}
+			resp3.PutWriter(rw)
+		}
+	})
+}
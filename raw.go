@@ -0,0 +1,321 @@
+package resp3
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// WriteNextValueTo reads the next value, including all nested values, and writes its raw RESP encoding to w,
+// returning the number of bytes written.
+//
+// In contrast to ReadValue, attributes preceding a value are not discarded but written together with the value. The
+// value is not decoded and scalar values are not validated beyond their framing, so this can be used to forward or
+// capture single replies.
+//
+// Lengths of aggregates and blobs are written in canonical form and line endings are always written as \r\n, even if
+// the input used a different representation accepted by the Reader (for example when AllowBareLF is true).
+//
+// The output is only written to w after the whole value was read successfully.
+func (rr *Reader) WriteNextValueTo(w io.Writer) (int64, error) {
+	b, err := rr.appendRawValue(nil)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// Consume decodes exactly one value, including all nested values, from the start of b and returns the number of bytes
+// occupied by the value together with its type.
+//
+// Like WriteNextValueTo, attributes preceding a value are consumed together with the value. In this case the returned
+// type is the type of the value following the attributes. RESP2 nulls ($-1 and *-1) are returned as TypeNull.
+//
+// Consume works directly on b without using a Reader and does not allocate unless an error is returned. Values are
+// framed like by a Reader with default options, so custom types and bare \n line endings are not supported, and
+// aggregates nested deeper than DefaultMaxReplyDepth are rejected with an error wrapping ErrValueTooDeep. Scalar
+// values are not validated beyond their framing.
+//
+// If b does not start with a complete value, an error wrapping ErrUnexpectedEOL is returned.
+func Consume(b []byte) (consumed int, ty Type, err error) {
+	c := consumer{b: b}
+	for {
+		if ty, err = c.value(0); err != nil {
+			return 0, TypeInvalid, err
+		}
+		if ty != TypeAttribute {
+			return c.off, ty, nil
+		}
+	}
+}
+
+// consumer implements Consume by scanning a byte slice.
+type consumer struct {
+	b   []byte
+	off int
+}
+
+func (c *consumer) value(depth int) (Type, error) {
+	if c.off >= len(c.b) {
+		return TypeInvalid, fmt.Errorf("%w: expected value", ErrUnexpectedEOL)
+	}
+	t := types[c.b[c.off]]
+	switch t {
+	case TypeInvalid:
+		return TypeInvalid, fmt.Errorf("%w: got %q", ErrInvalidType, c.b[c.off])
+	case TypeArray, TypeAttribute, TypeMap, TypePush, TypeSet:
+		return c.aggregate(t, depth)
+	case TypeBlobChunk, TypeBlobError, TypeBlobString, TypeVerbatimString:
+		return c.blob(t)
+	default:
+		_, err := c.line()
+		return t, err
+	}
+}
+
+// line skips the line at the current offset and returns its content without the type byte and the \r\n.
+func (c *consumer) line() ([]byte, error) {
+	i := bytes.IndexByte(c.b[c.off:], '\n')
+	if i < 0 {
+		return nil, fmt.Errorf("%w: expected \\r\\n", ErrUnexpectedEOL)
+	}
+	end := c.off + i
+	if end-1 <= c.off || c.b[end-1] != '\r' {
+		return nil, fmt.Errorf("%w: expected \\r\\n, got \\n", ErrUnexpectedEOL)
+	}
+	l := c.b[c.off+1 : end-1]
+	c.off = end + 1
+	return l, nil
+}
+
+func (c *consumer) aggregate(t Type, depth int) (Type, error) {
+	if depth >= DefaultMaxReplyDepth {
+		return TypeInvalid, fmt.Errorf("%w: %q exceeds nesting depth limit of %d", ErrValueTooDeep, t,
+			DefaultMaxReplyDepth)
+	}
+	l, err := c.line()
+	if err != nil {
+		return TypeInvalid, err
+	}
+	perEntry := int64(1)
+	if t == TypeAttribute || t == TypeMap {
+		perEntry = 2
+	}
+	if len(l) == 1 && l[0] == '?' {
+		for {
+			if c.off < len(c.b) && c.b[c.off] == byte(TypeEnd) {
+				_, err := c.line()
+				return t, err
+			}
+			if err := c.values(perEntry, depth+1); err != nil {
+				return TypeInvalid, err
+			}
+		}
+	}
+	n, ok := parseLength(l)
+	if ok && n == -1 && t == TypeArray {
+		return TypeNull, nil
+	}
+	if !ok || n < 0 {
+		return TypeInvalid, fmt.Errorf("%w: got %q", ErrInvalidAggregateTypeLength, l)
+	}
+	return t, c.values(n*perEntry, depth+1)
+}
+
+func (c *consumer) values(n int64, depth int) error {
+	for ; n > 0; n-- {
+		if _, err := c.value(depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *consumer) blob(t Type) (Type, error) {
+	l, err := c.line()
+	if err != nil {
+		return TypeInvalid, err
+	}
+	if len(l) == 1 && l[0] == '?' && (t == TypeBlobError || t == TypeBlobString) {
+		for {
+			if c.off < len(c.b) && c.b[c.off] != byte(TypeBlobChunk) {
+				return TypeInvalid, fmt.Errorf("%w: expected %q, got %q", ErrUnexpectedType, TypeBlobChunk, c.b[c.off])
+			}
+			l, err := c.line()
+			if err != nil {
+				return TypeInvalid, err
+			}
+			if n, err := c.body(TypeBlobChunk, l); err != nil {
+				return TypeInvalid, err
+			} else if n == 0 {
+				return t, nil
+			}
+		}
+	}
+	n, err := c.body(t, l)
+	if err != nil {
+		return TypeInvalid, err
+	}
+	if n < 0 {
+		return TypeNull, nil
+	}
+	return t, nil
+}
+
+// body parses the length l of a blob of type t and skips the blob body. For RESP2 null blob strings -1 is returned.
+func (c *consumer) body(t Type, l []byte) (int64, error) {
+	n, ok := parseLength(l)
+	switch {
+	case !ok:
+		return 0, fmt.Errorf("%w: got %q", ErrInvalidNumber, l)
+	case n == -1 && t == TypeBlobString:
+		return n, nil
+	case n < 0 && t == TypeBlobChunk:
+		return 0, fmt.Errorf("%w: got length %d", ErrInvalidBlobChunkLength, n)
+	case n < 0:
+		return 0, fmt.Errorf("%w: got length %d", ErrInvalidBlobLength, n)
+	case n == 0 && t == TypeBlobChunk:
+		// the final chunk has no body
+		return n, nil
+	case int64(len(c.b)-c.off) < n+2:
+		return 0, fmt.Errorf("%w: expected %d more bytes", ErrUnexpectedEOL, n+2-int64(len(c.b)-c.off))
+	case c.b[c.off+int(n)] != '\r' || c.b[c.off+int(n)+1] != '\n':
+		return 0, fmt.Errorf("%w: expected \\r\\n after %d bytes", ErrUnexpectedEOL, n)
+	}
+	c.off += int(n) + 2
+	return n, nil
+}
+
+// parseLength parses the length of a blob or aggregate. Lengths with more than 18 digits are rejected, which avoids
+// overflows.
+func parseLength(l []byte) (int64, bool) {
+	neg := len(l) > 0 && l[0] == '-'
+	if neg {
+		l = l[1:]
+	}
+	if len(l) == 0 || len(l) > 18 {
+		return 0, false
+	}
+	var n int64
+	for _, b := range l {
+		if b < '0' || b > '9' {
+			return 0, false
+		}
+		n = n*10 + int64(b-'0')
+	}
+	if neg {
+		n = -n
+	}
+	return n, true
+}
+
+func appendHeader(dst []byte, t Type, n int64) []byte {
+	dst = append(dst, byte(t))
+	dst = strconv.AppendInt(dst, n, 10)
+	return append(dst, '\r', '\n')
+}
+
+func (rr *Reader) appendRawValue(dst []byte) ([]byte, error) {
+	t, err := rr.Peek()
+	if err != nil {
+		return nil, wrapEOF(err, "")
+	}
+
+	switch t {
+	case TypeArray, TypeAttribute, TypeMap, TypePush, TypeSet:
+		dst, err = rr.appendRawAggregate(t, dst)
+		if err == nil && t == TypeAttribute {
+			return rr.appendRawValue(dst)
+		}
+	case TypeBlobError, TypeBlobString, TypeVerbatimString:
+		dst, err = rr.appendRawBlob(t, dst)
+	case TypeBigNumber, TypeBoolean, TypeDouble, TypeNull, TypeNumber, TypeSimpleError, TypeSimpleString:
+		if t == TypeNull {
+			// RESP2 nulls ($-1 and *-1) are reported as TypeNull by Peek, but are written unchanged
+			t, _ = rr.peek()
+		}
+		if dst, err = rr.readSimple(t, append(dst, byte(t))); err == nil {
+			dst = append(dst, '\r', '\n')
+		}
+	default:
+		err = fmt.Errorf("%w: got %q", ErrUnexpectedType, t)
+	}
+
+	if err != nil {
+		return nil, wrapEOF(err, "")
+	}
+	return dst, nil
+}
+
+func (rr *Reader) appendRawAggregate(t Type, dst []byte) ([]byte, error) {
+	n, chunked, err := rr.readAggregateHeader(t)
+	if err != nil {
+		return nil, err
+	}
+	if chunked {
+		dst = append(dst, byte(t), '?', '\r', '\n')
+		for i := int64(0); ; i++ {
+			if more, err := rr.moreChunks(i); err != nil {
+				return nil, err
+			} else if !more {
+				return append(dst, endBytes...), nil
+			}
+			if dst, err = rr.appendRawValue(dst); err != nil {
+				return nil, err
+			}
+		}
+	}
+	dst = appendHeader(dst, t, n)
+	if t == TypeAttribute || t == TypeMap {
+		if n > math.MaxInt64/2 {
+			return nil, fmt.Errorf("%w: %q with %d entries is too long", ErrInvalidAggregateTypeLength, t, n)
+		}
+		n *= 2
+	}
+	for ; n > 0; n-- {
+		if dst, err = rr.appendRawValue(dst); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+func (rr *Reader) appendRawBlob(t Type, dst []byte) ([]byte, error) {
+	if t != TypeVerbatimString && rr.consumeLine([]byte{byte(t), '?'}) {
+		dst = append(dst, byte(t), '?', '\r', '\n')
+		var buf [512]byte
+		for i := int64(0); ; i++ {
+			b, last, err := rr.ReadBlobChunk(buf[:0])
+			if err != nil {
+				return nil, err
+			}
+			dst = appendHeader(dst, TypeBlobChunk, int64(len(b)))
+			if last {
+				return dst, nil
+			}
+			if err := rr.checkChunks(i); err != nil {
+				return nil, err
+			}
+			dst = append(dst, b...)
+			dst = append(dst, '\r', '\n')
+		}
+	}
+	if err := rr.expect(t); err != nil {
+		return nil, err
+	}
+	n, err := rr.readNumber()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("%w: got length %d", ErrInvalidBlobLength, n)
+	}
+	dst = appendHeader(dst, t, n)
+	if dst, err = rr.readBlobBody(dst, int(n)); err != nil {
+		return nil, err
+	}
+	return append(dst, '\r', '\n'), nil
+}
@@ -0,0 +1,176 @@
+package resp3_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+func TestConsume(t *testing.T) {
+	for _, c := range []struct {
+		in       string
+		consumed int
+		ty       resp3.Type
+		err      error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: "$5\r\nhel", err: resp3.ErrUnexpectedEOL},
+		{in: "*2\r\n:1\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "|1\r\n+ttl\r\n:10\r\n", err: resp3.ErrUnexpectedEOL},
+
+		{in: "+OK\r\n", consumed: 5, ty: resp3.TypeSimpleString},
+		{in: "+OK\r\n:1\r\n", consumed: 5, ty: resp3.TypeSimpleString},
+		{in: "$5\r\nhello\r\nrest", consumed: 11, ty: resp3.TypeBlobString},
+		{in: "$?\r\n;2\r\nhe\r\n;0\r\nrest", consumed: 16, ty: resp3.TypeBlobString},
+		{in: "*2\r\n:1\r\n*1\r\n_\r\nrest", consumed: 15, ty: resp3.TypeArray},
+		{in: "%?\r\n+a\r\n:1\r\n.\r\nrest", consumed: 15, ty: resp3.TypeMap},
+		{in: "|1\r\n+ttl\r\n:10\r\n:1\r\n:2\r\n", consumed: 19, ty: resp3.TypeNumber},
+		{in: "$-1\r\n:1\r\n", consumed: 5, ty: resp3.TypeNull},
+		{in: "*-1\r\n:1\r\n", consumed: 5, ty: resp3.TypeNull},
+		{in: "=7\r\ntxt:abc\r\n", consumed: 13, ty: resp3.TypeVerbatimString},
+		{in: "*?\r\n*?\r\n.\r\n.\r\n", consumed: 14, ty: resp3.TypeArray},
+
+		{in: "+OK\n", err: resp3.ErrUnexpectedEOL},
+		{in: "\n", err: resp3.ErrInvalidType},
+		{in: "$5\r\nhelloXX", err: resp3.ErrUnexpectedEOL},
+		{in: "$-2\r\n", err: resp3.ErrInvalidBlobLength},
+		{in: "$x\r\n", err: resp3.ErrInvalidNumber},
+		{in: "$1234567890123456789\r\n", err: resp3.ErrInvalidNumber},
+		{in: "$?\r\n;-1\r\n", err: resp3.ErrInvalidBlobChunkLength},
+		{in: "$?\r\n;-1\r\n", err: resp3.ErrInvalidBlobLength},
+		{in: "$?\r\n:1\r\n", err: resp3.ErrUnexpectedType},
+		{in: "$?\r\n;1\r\na\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "*-2\r\n", err: resp3.ErrInvalidAggregateTypeLength},
+		{in: "%-1\r\n", err: resp3.ErrInvalidAggregateTypeLength},
+		{in: "*?\r\n:1\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: strings.Repeat("*1\r\n", resp3.DefaultMaxReplyDepth+1) + ":1\r\n", err: resp3.ErrValueTooDeep},
+	} {
+		consumed, ty, err := resp3.Consume([]byte(c.in))
+		assertError(t, c.err, err)
+		if consumed != c.consumed {
+			t.Errorf("got %d consumed bytes for %q, expected %d", consumed, c.in, c.consumed)
+		}
+		if ty != c.ty {
+			t.Errorf("got type %q for %q, expected %q", ty, c.in, c.ty)
+		}
+	}
+
+	large := "$5000\r\n" + strings.Repeat("a", 5000) + "\r\n"
+	if consumed, _, err := resp3.Consume([]byte(large + large)); err != nil {
+		t.Errorf("got error %q", err)
+	} else if consumed != len(large) {
+		t.Errorf("got %d consumed bytes, expected %d", consumed, len(large))
+	}
+}
+
+func TestConsumeAllocs(t *testing.T) {
+	for _, in := range []string{
+		":1\r\n",
+		"|1\r\n+ttl\r\n:10\r\n*3\r\n$5\r\nhello\r\n%?\r\n+a\r\n$?\r\n;1\r\nb\r\n;0\r\n.\r\n$-1\r\n",
+	} {
+		b := []byte(in)
+		allocs := testing.AllocsPerRun(100, func() {
+			if consumed, _, err := resp3.Consume(b); err != nil || consumed != len(b) {
+				t.Fatalf("got (%d, %v), expected (%d, nil)", consumed, err, len(b))
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("got %.0f allocations for %q, expected 0", allocs, in)
+		}
+	}
+}
+
+func TestReaderWriteNextValueTo(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		out  string
+		err  error
+		rest string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: ".\r\n", err: resp3.ErrUnexpectedType},
+		{in: ";0\r\n", err: resp3.ErrUnexpectedType},
+
+		{in: "(123456789123456789123456789\r\n", out: "(123456789123456789123456789\r\n"},
+		{in: "#t\r\n", out: "#t\r\n"},
+		{in: ",1.5\r\n", out: ",1.5\r\n"},
+		{in: "_\r\n", out: "_\r\n"},
+		{in: "$-1\r\n", out: "$-1\r\n"},
+		{in: "*-1\r\n", out: "*-1\r\n"},
+		{in: ":-10\r\n", out: ":-10\r\n"},
+		{in: "+OK\r\n", out: "+OK\r\n"},
+		{in: "-ERR hello\r\n", out: "-ERR hello\r\n"},
+
+		{in: "$10\r\nhello\r\nyou\r\n", out: "$10\r\nhello\r\nyou\r\n"},
+		{in: "!3\r\nERR\r\n", out: "!3\r\nERR\r\n"},
+		{in: "=9\r\ntxt:hello\r\n", out: "=9\r\ntxt:hello\r\n"},
+		{in: "$?\r\n;5\r\nhello\r\n;1\r\n!\r\n;0\r\n", out: "$?\r\n;5\r\nhello\r\n;1\r\n!\r\n;0\r\n"},
+		{in: "$5\r\nhello", err: resp3.ErrUnexpectedEOL},
+		{in: "$?\r\n;5\r\nhello\r\n", err: resp3.ErrUnexpectedEOL},
+
+		{in: "*0\r\n", out: "*0\r\n"},
+		{in: "*3\r\n:1\r\n+a\r\n*1\r\n_\r\n", out: "*3\r\n:1\r\n+a\r\n*1\r\n_\r\n"},
+		{in: "*?\r\n:1\r\n:2\r\n.\r\n", out: "*?\r\n:1\r\n:2\r\n.\r\n"},
+		{in: "%2\r\n+b\r\n:1\r\n+a\r\n:2\r\n", out: "%2\r\n+b\r\n:1\r\n+a\r\n:2\r\n"},
+		{in: "~?\r\n:1\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "*2\r\n:1\r\n", err: resp3.ErrUnexpectedEOL},
+
+		{in: "|1\r\n+ttl\r\n:10\r\n:1\r\n:2\r\n", out: "|1\r\n+ttl\r\n:10\r\n:1\r\n", rest: ":2\r\n"},
+		{in: ">2\r\n+message\r\n$1\r\na\r\n+OK\r\n", out: ">2\r\n+message\r\n$1\r\na\r\n", rest: "+OK\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		var out strings.Builder
+		n, err := rr.WriteNextValueTo(&out)
+		assertError(t, c.err, err)
+		if got := out.String(); got != c.out {
+			t.Errorf("got %q, expected %q", got, c.out)
+		}
+		if n != int64(len(c.out)) {
+			t.Errorf("got %d written bytes, expected %d", n, len(c.out))
+		}
+		if c.err != nil {
+			continue
+		}
+		if got := rest(); got != c.rest {
+			t.Errorf("got %q left in input, expected %q", got, c.rest)
+		}
+	}
+
+	rr, _ := newTestReader("*1\n+OK\n")
+	rr.AllowBareLF = true
+	var out strings.Builder
+	if _, err := rr.WriteNextValueTo(&out); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := out.String(); got != "*1\r\n+OK\r\n" {
+		t.Errorf("got %q, expected %q", got, "*1\r\n+OK\r\n")
+	}
+}
+
+func TestReaderWriteNextValueToStats(t *testing.T) {
+	rr, _ := newTestReader("*3\r\n:1\r\n+abc\r\n$?\r\n;1\r\na\r\n;0\r\n")
+	rr.Stats = &resp3.Stats{}
+	rr.SingleReadSizeLimit = 3
+	if _, err := rr.WriteNextValueTo(ioutil.Discard); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for ty, expected := range map[resp3.Type]uint64{
+		resp3.TypeArray:        1,
+		resp3.TypeNumber:       1,
+		resp3.TypeSimpleString: 1,
+		resp3.TypeBlobString:   1,
+		resp3.TypeBlobChunk:    2,
+	} {
+		if got := rr.Stats.Count(ty); got != expected {
+			t.Errorf("got count %d for type %q, expected %d", got, ty, expected)
+		}
+	}
+	if rr.LastWasChunked() {
+		t.Error("got LastWasChunked true after blob chunk, expected false")
+	}
+}
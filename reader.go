@@ -2,11 +2,14 @@ package resp3
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"strconv"
+	"unicode/utf8"
 )
 
 // Reader wraps an io.Reader and provides methods for reading the RESP protocol.
@@ -18,16 +21,175 @@ type Reader struct {
 	// A negative < 0 value disables the limit.
 	SingleReadSizeLimit int
 
+	// AllowBareLF enables accepting a single \n as line ending in addition to \r\n.
+	//
+	// This can be used to read data from non-compliant peers that terminate lines using only \n. If AllowBareLF is
+	// false, a \n that is not preceded by \r is rejected with an error wrapping ErrUnexpectedEOL.
+	//
+	// This applies to the line ending following the body of blobs, blob chunks and verbatim strings too, so that
+	// streams that mix \r\n and \n, for example by only terminating blob bodies with \n, can be read.
+	AllowBareLF bool
+
+	// DisableRESP2Null disables treating arrays and blob strings with length -1 as null values.
+	//
+	// By default Peek returns TypeNull for these values and ReadNull accepts them for compatibility with RESP2. If
+	// DisableRESP2Null is true, Peek returns TypeArray or TypeBlobString instead, ReadNull only accepts RESP3 null
+	// values and reading the value using ReadArrayHeader or ReadBlobString returns an error because of the invalid
+	// length.
+	DisableRESP2Null bool
+
+	// StrictNumbers enables rejecting numbers that are not in canonical form.
+	//
+	// If StrictNumbers is true, numbers with leading zeros (for example "007" or "00") and negative zero ("-0") are
+	// rejected with an error wrapping ErrInvalidNumber. This also applies to the lengths of blobs and aggregates.
+	StrictNumbers bool
+
+	// AllowPlusSign enables accepting numbers with a single leading +, for example ":+1".
+	//
+	// By default only a leading - is accepted. Big numbers and doubles always accept a leading +. Like StrictNumbers,
+	// this also applies to the lengths of blobs and aggregates.
+	AllowPlusSign bool
+
+	// DoubleDecimalSeparator is the decimal separator expected in doubles, instead of '.'.
+	//
+	// This can be used to read doubles from non-compliant peers that format doubles using the conventions of a locale
+	// other than "C", for example ",1,5\r\n". The separator is replaced with '.' before parsing the value, so both
+	// ReadDouble and ReadDoubleBytes return values as if the peer had used '.'. If DoubleDecimalSeparator is 0, '.'
+	// is used.
+	DoubleDecimalSeparator byte
+
+	// AllowUnknownTypes enables discarding values with unknown types using Discard.
+	//
+	// If AllowUnknownTypes is true and the next value starts with an unknown type byte, Discard skips the line using
+	// SkipLine and returns TypeInvalid without error, instead of returning an error wrapping ErrInvalidType. This
+	// allows reading data that contains scalar types added in later versions of the protocol.
+	AllowUnknownTypes bool
+
+	// StrictSets enables rejecting duplicate set members in ReadSetStringSet with an error wrapping
+	// ErrDuplicateSetMember.
+	StrictSets bool
+
+	// StrictBlobErrors enables rejecting empty blob errors in ReadBlobError with ErrEmptyError.
+	//
+	// Empty blob errors are valid RESP, but are almost always caused by a server that sent an error without message.
+	// The empty blob error is consumed before returning the error.
+	StrictBlobErrors bool
+
+	// BooleanFromInteger enables reading numbers using ReadBoolean, treating all non-zero numbers as true.
+	//
+	// This is always enabled when Protocol is 2.
+	BooleanFromInteger bool
+
+	// Protocol specifies the version of the RESP protocol that is expected to be read.
+	//
+	// If Protocol is 2, methods for reading types that have no RESP2 equivalent also accept the RESP2 fallback used by
+	// Writer.Protocol:
+	//
+	//	ReadBigNumber and ReadDouble accept blob strings
+	//	ReadBoolean accepts numbers, treating all non-zero numbers as true
+	//	ReadMapHeader accepts array headers with an even number of elements, returning half the number of elements
+	//	ReadPushHeader and ReadSetHeader accept array headers
+	//
+	// For all other values, including 0, only RESP3 types are accepted.
+	Protocol uint8
+
+	// MaxReplyBytes limits the total size of all blob and simple values read by a single call to ReadValue, ReadReply
+	// or Discard with nested set to true, including all nested values. If the limit is exceeded, an error wrapping
+	// ErrReplyTooLarge is returned. Values read using other methods are not counted.
+	//
+	// If MaxReplyBytes is <= 0, there is no limit.
+	MaxReplyBytes int64
+
+	// MaxChunks limits the number of chunks of streamed blobs and the number of elements of streamed aggregates, with
+	// each key-value pair of streamed maps and attributes counting as one element. If the limit is exceeded, an error
+	// wrapping ErrTooManyChunks is returned.
+	//
+	// The limit is checked by all methods that read or discard whole streamed values, including ReadBlobChunks,
+	// ReadValue and Discard with nested set to true, but not when reading chunks or elements individually, for
+	// example using ReadBlobChunk or More. This bounds the amount of work for streamed values consisting of many small
+	// chunks, independent of MaxReplyBytes.
+	//
+	// If MaxChunks is <= 0, there is no limit.
+	MaxChunks int
+
+	// MaxReplyNodes limits the number of values that nested aggregates can declare when read using ReadValue,
+	// ReadReply or Discard with nested set to true.
+	//
+	// For each aggregate the product of its length and the lengths of all aggregates containing it is compared to the
+	// limit before reading any elements, with maps and attributes counting both keys and values. If the product exceeds
+	// the limit, an error wrapping ErrAggregateTooLong is returned. This bounds the amount of work for deeply nested
+	// aggregates, where each level multiplies the number of values. Streamed aggregates are not counted.
+	//
+	// If MaxReplyNodes is <= 0, there is no limit.
+	MaxReplyNodes int64
+
+	// MaxReplyDepth limits the nesting depth of aggregates read using ReadValue, ReadReply, ReadValueInto or Discard
+	// with nested set to true. Every aggregate counts, independent of its length and including streamed aggregates.
+	//
+	// If the limit is exceeded, an error wrapping ErrValueTooDeep is returned. This bounds the recursion for deeply
+	// nested aggregates, which MaxReplyNodes does not cover for aggregates with zero or one elements.
+	//
+	// If MaxReplyDepth is 0, DefaultMaxReplyDepth is used instead. If MaxReplyDepth is < 0, there is no limit.
+	MaxReplyDepth int
+
+	// ScratchSize is the size of a scratch buffer that is allocated once and reused for temporarily holding scalar
+	// values, like booleans, doubles and big numbers, while parsing them and for discarding simple values.
+	//
+	// If ScratchSize is <= 0, a small buffer is used for each call instead, which may need to be allocated for each
+	// read. Values larger than ScratchSize are still read, but may cause additional allocations.
+	ScratchSize int
+
+	// Stats, if not nil, is updated with the number of values read for each type.
+	//
+	// A value is counted once its type byte was consumed, so values that were only peeked at are not counted, while
+	// values that were read or discarded, including nested values and blob chunks, are counted. The same Stats can be
+	// shared by multiple Readers as long as they are not used concurrently.
+	Stats *Stats
+
 	br *bufio.Reader
 
 	// ownbr holds a *bufio.Reader that is reused when calling Reset. This is used in cases the io.Reader given to
 	// Reset is already a *bufio.Reader to avoid reusing the user given *bufio.Reader when calling Reset.
 	ownbr *bufio.Reader
+
+	// customTypes holds the functions registered using RegisterType, indexed by type byte.
+	customTypes map[byte]func(r *Reader) error
+
+	// bigNumberBuf is used by ReadBigNumber for intermediate values when parsing large numbers.
+	bigNumberBuf [2]big.Int
+
+	// scratch is the buffer configured using ScratchSize.
+	scratch []byte
+
+	// tokenBuf is reused by Next for the contents of returned tokens.
+	tokenBuf []byte
+
+	// lastChunked is true if the type of the last value consumed was followed by a ? (see LastWasChunked).
+	lastChunked bool
+
+	// replyBytes is the number of bytes read for the current top-level value, if replyDepth is > 0.
+	replyBytes int64
+
+	// replyDepth is the nesting depth of calls to ReadValue and Discard that count towards MaxReplyBytes.
+	replyDepth int
+
+	// replyNodes is the product of the lengths of all aggregates containing the current value, if replyDepth is > 0.
+	replyNodes int64
+
+	// aggregateDepth is the number of aggregates containing the current value, as tracked by enterAggregate.
+	aggregateDepth int
 }
 
 const (
 	// DefaultSingleReadSizeLimit defines the default read limit for values used when Reader.SingleReadSizeLimit is 0.
 	DefaultSingleReadSizeLimit = 1 << 25 // 32MiB
+
+	// DefaultMaxReplyDepth defines the default nesting depth limit used when Reader.MaxReplyDepth is 0.
+	DefaultMaxReplyDepth = 1000
+
+	// DefaultMaxValueCount defines the maximum number of values that can be read using Reader.ReadN,
+	// Reader.ReadReplies and Reader.ForEachReply when Reader.MaxReplyNodes is <= 0.
+	DefaultMaxValueCount = 1 << 24
 )
 
 // NewReader returns a *Reader that uses the given io.Reader for reads.
@@ -51,25 +213,119 @@ func wrapEOF(err error, msg string, args ...interface{}) error {
 	return fmt.Errorf("%w: expected %s, got EOF", ErrUnexpectedEOL, fmt.Sprintf(msg, args...))
 }
 
-func (rr *Reader) checkReadSizeLimit(n int) error {
-	l := rr.SingleReadSizeLimit
-	if l == 0 {
-		l = DefaultSingleReadSizeLimit
+// addReplyBytes adds n to the number of bytes read for the current top-level value and checks it against
+// MaxReplyBytes.
+func (rr *Reader) addReplyBytes(n int) error {
+	if rr.replyDepth == 0 || rr.MaxReplyBytes <= 0 {
+		return nil
+	}
+	rr.replyBytes += int64(n)
+	if rr.replyBytes > rr.MaxReplyBytes {
+		return fmt.Errorf("%w: reply exceeds limit of %d bytes", ErrReplyTooLarge, rr.MaxReplyBytes)
+	}
+	return nil
+}
+
+// beginReply marks the start of a value that counts towards MaxReplyBytes, resetting the number of read bytes if the
+// value is a top-level value. endReply must be called once the value was read.
+func (rr *Reader) beginReply() {
+	if rr.replyDepth == 0 {
+		rr.replyBytes = 0
+		rr.replyNodes = 1
+	}
+	rr.replyDepth++
+}
+
+func (rr *Reader) endReply() {
+	rr.replyDepth--
+}
+
+// enterAggregate increments the aggregate nesting depth and checks it against MaxReplyDepth. It also multiplies the
+// number of values of the current top-level value with the number of elements n of a nested aggregate and checks the
+// result against MaxReplyNodes. For streamed aggregates n must be -1.
+//
+// If no error is returned, leaveAggregate must be called with the returned value once all elements were read.
+func (rr *Reader) enterAggregate(t Type, n int64) (int64, error) {
+	prev := rr.replyNodes
+	if l := rr.maxReplyDepth(); l >= 0 && rr.aggregateDepth >= l {
+		return prev, fmt.Errorf("%w: %q exceeds nesting depth limit of %d", ErrValueTooDeep, t, l)
+	}
+	if rr.replyDepth > 0 && rr.MaxReplyNodes > 0 && n > 1 {
+		if prev > rr.MaxReplyNodes/n {
+			return prev, fmt.Errorf("%w: %q with %d elements exceeds limit of %d values", ErrAggregateTooLong, t, n,
+				rr.MaxReplyNodes)
+		}
+		rr.replyNodes = prev * n
+	}
+	rr.aggregateDepth++
+	return prev, nil
+}
+
+// maxReplyDepth returns MaxReplyDepth or DefaultMaxReplyDepth, if MaxReplyDepth is 0.
+func (rr *Reader) maxReplyDepth() int {
+	if rr.MaxReplyDepth == 0 {
+		return DefaultMaxReplyDepth
+	}
+	return rr.MaxReplyDepth
+}
+
+// leaveAggregate restores the state changed by the matching call to enterAggregate.
+func (rr *Reader) leaveAggregate(prev int64) {
+	rr.replyNodes = prev
+	rr.aggregateDepth--
+}
+
+// scratchBuf returns an empty slice for temporarily holding a scalar value. If ScratchSize is > 0, the slice uses
+// the reusable scratch buffer. Otherwise a new slice with capacity n is returned.
+func (rr *Reader) scratchBuf(n int) []byte {
+	if rr.ScratchSize <= 0 {
+		return make([]byte, 0, n)
+	}
+	if cap(rr.scratch) < rr.ScratchSize {
+		rr.scratch = make([]byte, 0, rr.ScratchSize)
 	}
-	if l > 0 && l < n {
+	return rr.scratch[:0]
+}
+
+// EffectiveSingleReadSizeLimit returns the limit for the size of single values that is currently in effect.
+//
+// This is SingleReadSizeLimit or DefaultSingleReadSizeLimit, if SingleReadSizeLimit is 0. If the limit is disabled,
+// -1 is returned.
+func (rr *Reader) EffectiveSingleReadSizeLimit() int {
+	switch l := rr.SingleReadSizeLimit; {
+	case l == 0:
+		return DefaultSingleReadSizeLimit
+	case l < 0:
+		return -1
+	default:
+		return l
+	}
+}
+
+func (rr *Reader) checkReadSizeLimit(n int) error {
+	if l := rr.EffectiveSingleReadSizeLimit(); l >= 0 && l < n {
 		return fmt.Errorf("%w: value of size %d exceeds configured limit", ErrSingleReadSizeLimitExceeded, n)
 	}
 	return nil
 }
 
-func (rr *Reader) consume(b []byte) bool {
-	if rr.match(b) {
-		_, _ = rr.br.Discard(len(b))
+func (rr *Reader) consumeLine(b []byte) bool {
+	if n := rr.matchLine(b); n > 0 {
+		_, _ = rr.br.Discard(n)
+		rr.countType(b[0])
+		rr.lastChunked = len(b) == 2 && b[1] == '?'
 		return true
 	}
 	return false
 }
 
+// countType increments the counter for the type byte b in Stats, if set.
+func (rr *Reader) countType(b byte) {
+	if rr.Stats != nil {
+		rr.Stats.counts[b]++
+	}
+}
+
 func (rr *Reader) expect(t Type) error {
 	g, err := rr.peek()
 	if err != nil {
@@ -79,20 +335,41 @@ func (rr *Reader) expect(t Type) error {
 		return fmt.Errorf("%w: expected %q, got %q", ErrUnexpectedType, t, g)
 	}
 	_, err = rr.br.Discard(1)
+	rr.countType(byte(t))
+	rr.lastChunked = false
 	return err
 }
 
 func (rr *Reader) match(b []byte) bool {
+	return rr.matchAt(0, b)
+}
+
+func (rr *Reader) matchAt(off int, b []byte) bool {
 	for i, c := range b {
 		// only read a byte at a time to avoid hangs when trying to read more bytes than are available
-		g, err := rr.br.Peek(i + 1)
-		if len(g) <= i || g[i] != c || err != nil {
+		g, err := rr.br.Peek(off + i + 1)
+		if len(g) <= off+i || g[off+i] != c || err != nil {
 			return false
 		}
 	}
 	return true
 }
 
+// matchLine checks if the next bytes are b followed by a line ending and returns the length of the line including
+// the line ending or 0 if the bytes did not match.
+func (rr *Reader) matchLine(b []byte) int {
+	if !rr.match(b) {
+		return 0
+	}
+	if rr.matchAt(len(b), []byte{'\r', '\n'}) {
+		return len(b) + len("\r\n")
+	}
+	if rr.AllowBareLF && rr.matchAt(len(b), []byte{'\n'}) {
+		return len(b) + len("\n")
+	}
+	return 0
+}
+
 func (rr *Reader) peek() (Type, error) {
 	b, err := rr.br.Peek(1)
 	if err != nil {
@@ -101,25 +378,90 @@ func (rr *Reader) peek() (Type, error) {
 	if t := types[b[0]]; t != TypeInvalid {
 		return t, nil
 	}
+	if _, ok := rr.customTypes[b[0]]; ok {
+		return Type(b[0]), nil
+	}
 	return TypeInvalid, fmt.Errorf("%w: %s", ErrInvalidType, b)
 }
 
+func (rr *Reader) resp2() bool {
+	return rr.Protocol == 2
+}
+
+// readEOL reads the line ending following the body of a blob.
+//
+// Only \r\n is accepted, unless AllowBareLF is true, in which case a single \n is accepted too. For all other input
+// the returned error wraps ErrUnexpectedEOL and includes the bytes found instead as hex escapes, for example
+// "expected \r\n, got \x0a\x2b". If the input ends after a single byte, the byte is included in the error too.
 func (rr *Reader) readEOL() error {
 	b, err := rr.br.Peek(len("\r\n"))
-	if err != nil {
+	if rr.AllowBareLF && len(b) > 0 && b[0] == '\n' {
+		_, err = rr.br.Discard(1)
+		return err
+	}
+	if len(b) == 2 && b[0] == '\r' && b[1] == '\n' {
+		_, err = rr.br.Discard(len(b))
+		return err
+	}
+	if err != nil && len(b) == 0 {
 		return wrapEOF(err, "\\r\\n")
 	}
-	if len(b) != 2 || b[0] != '\r' || b[1] != '\n' {
-		return fmt.Errorf("%w: expected \\r\\n, got %q", ErrUnexpectedEOL, string(b))
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		return fmt.Errorf("%w: expected \\r\\n, got %s followed by EOF", ErrUnexpectedEOL, hexEscape(b))
+	}
+	return fmt.Errorf("%w: expected \\r\\n, got %s", ErrUnexpectedEOL, hexEscape(b))
+}
+
+// readBlobEOL reads the line ending following the body of a blob with the declared length n.
+//
+// If the line ending is missing, the error returned by readEOL is annotated with the declared length, as a missing
+// line ending after a blob body usually means that the declared length does not match the length of the body.
+func (rr *Reader) readBlobEOL(n int) error {
+	err := rr.readEOL()
+	if err != nil && errors.Is(err, ErrUnexpectedEOL) {
+		return fmt.Errorf("%w after blob body of declared length %d, declared length may not match body", err, n)
 	}
-	_, err = rr.br.Discard(len(b))
 	return err
 }
 
+// hexEscape returns b with every byte escaped as \xNN.
+func hexEscape(b []byte) string {
+	const hex = "0123456789abcdef"
+	s := make([]byte, 0, len(b)*4)
+	for _, c := range b {
+		s = append(s, '\\', 'x', hex[c>>4], hex[c&0xF])
+	}
+	return string(s)
+}
+
 // Reset sets the underlying io.Reader tor and resets all internal state.
 //
 // If the given io.Reader is an *bufio.Reader it is used directly without additional buffering.
+//
+// If r is nil, all references to the previous io.Reader are released and all following reads return ErrClosed until
+// Reset is called again. This allows reusing Readers, for example using a sync.Pool, without keeping the underlying
+// connections alive:
+//
+//	var readerPool = sync.Pool{New: func() interface{} { return resp3.NewReader(nil) }}
+//
+//	func handle(conn net.Conn) {
+//		rr := readerPool.Get().(*resp3.Reader)
+//		rr.Reset(conn)
+//		defer func() {
+//			rr.Reset(nil)
+//			readerPool.Put(rr)
+//		}()
+//
+//		// ...
+//	}
 func (rr *Reader) Reset(r io.Reader) {
+	if r == nil {
+		r = closed{}
+	}
+
 	if br, ok := r.(*bufio.Reader); ok {
 		rr.br = br
 		return
@@ -134,44 +476,211 @@ func (rr *Reader) Reset(r io.Reader) {
 	rr.br = rr.ownbr
 }
 
+// NextIsPush returns true if the next value is a push, without consuming it.
+//
+// This can be used by clients to read push messages that arrive before the reply to a command:
+//
+//	for {
+//		push, err := rr.NextIsPush()
+//		if err != nil {
+//			return err
+//		}
+//		if !push {
+//			break
+//		}
+//		msg, err := rr.ReadPushMessage()
+//		// handle message
+//	}
+//
+// Note that push data can not be distinguished from arrays when reading RESP2, so NextIsPush always returns false in
+// this case.
+func (rr *Reader) NextIsPush() (bool, error) {
+	t, err := rr.Peek()
+	if err != nil {
+		return false, err
+	}
+	return t == TypePush, nil
+}
+
+// RegisterType registers a function for reading values of a custom type with the prefix byte b.
+//
+// After registering the type, Peek returns Type(b) for values of the type and Discard calls fn to read and discard
+// the value. fn must consume the whole value including the prefix byte, for example using SkipLine. This allows
+// reading types used by protocol extensions, for example by Redis modules.
+//
+// If fn is nil, a previous registration for b is removed. If b is the prefix of a known type, an error wrapping
+// ErrKnownType is returned and nothing is registered.
+func (rr *Reader) RegisterType(b byte, fn func(r *Reader) error) error {
+	if types[b] != TypeInvalid {
+		return fmt.Errorf("%w: %q", ErrKnownType, b)
+	}
+	if fn == nil {
+		delete(rr.customTypes, b)
+		return nil
+	}
+	if rr.customTypes == nil {
+		rr.customTypes = make(map[byte]func(r *Reader) error)
+	}
+	rr.customTypes[b] = fn
+	return nil
+}
+
 // Peek returns the Type of the next value.
 //
 // For backwards compatibility with RESP2, if the next value is either an array or
 // an blob string with length -1, TypeNull will be returned. ReadNull also handles
 // this case and will correctly parse the value, treating it as a normal null value.
+// This can be disabled by setting DisableRESP2Null.
 func (rr *Reader) Peek() (Type, error) {
 	t, err := rr.peek()
 	if err != nil {
 		return t, err
 	}
-	if t == TypeArray || t == TypeBlobString {
-		if rr.match([]byte{byte(t), '-', '1', '\r', '\n'}) {
+	if !rr.DisableRESP2Null && (t == TypeArray || t == TypeBlobString) {
+		if rr.matchLine([]byte{byte(t), '-', '1'}) > 0 {
 			return TypeNull, nil
 		}
 	}
 	return t, err
 }
 
-func (rr *Reader) readDouble() (float64, error) {
-	var buf [32]byte
-	b, err := rr.readLine(buf[:0])
+// PeekErrorCode returns the code of the next value, which must be a simple error, without consuming the value.
+//
+// The code is the first space-delimited token of the error, e.g. MOVED or ASK, or the whole error if the error
+// contains no space. This allows routing errors based on their code without reading the full error message.
+//
+// If the next type in the response is not simple error, ErrUnexpectedType is returned. If the code does not fit into
+// the internal buffer, bufio.ErrBufferFull is returned.
+func (rr *Reader) PeekErrorCode() (string, error) {
+	t, err := rr.peek()
+	if err != nil {
+		return "", err
+	}
+	if t != TypeSimpleError {
+		return "", fmt.Errorf("%w: expected %q, got %q", ErrUnexpectedType, TypeSimpleError, t)
+	}
+	for n := len("-") + 1; ; n++ {
+		b, err := rr.br.Peek(n)
+		if len(b) < n {
+			return "", wrapEOF(err, "\\r\\n")
+		}
+		if c := b[n-1]; c == ' ' || c == '\r' || c == '\n' {
+			return string(b[1 : n-1]), nil
+		}
+	}
+}
+
+// PeekBlobString reads a blob string and returns its content without copying it, if possible.
+//
+// If the whole blob string, including the trailing line ending, fits into the internal buffer, the returned slice
+// points directly into the buffer. Otherwise the blob string is copied into a newly allocated slice. Chunked blob
+// strings are always copied.
+//
+// The returned slice is only valid until the next read from the Reader and must not be modified.
+//
+// If the next type in the response is not blob string, ErrUnexpectedType is returned.
+func (rr *Reader) PeekBlobString() ([]byte, error) {
+	if rr.consumeLine([]byte{byte(TypeBlobString), '?'}) {
+		return rr.ReadBlobChunks(nil)
+	}
+	if err := rr.expect(TypeBlobString); err != nil {
+		return nil, err
+	}
+	n64, err := rr.readNumber()
+	if err != nil {
+		return nil, err
+	}
+	if n64 < 0 {
+		return nil, fmt.Errorf("%w: got length %d", ErrInvalidBlobLength, n64)
+	}
+	n := int(n64)
+	if err := rr.checkReadSizeLimit(n); err != nil {
+		return nil, err
+	}
+	if n+len("\r\n") <= rr.br.Size() {
+		if b, ok := rr.peekBlobBody(n); ok {
+			return b, nil
+		}
+	}
+	return rr.readBlobBody(nil, n)
+}
+
+// PeekN returns the next n bytes without consuming them, for example to differentiate between "*?" and "*5" when
+// implementing custom lookahead.
+//
+// The returned slice points into the internal buffer and is only valid until the next read from the Reader. n can be
+// at most the size of the buffer used by the Reader, which is 4096 bytes, unless the Reader was created for a
+// *bufio.Reader with a different size (see Reset). For larger n bufio.ErrBufferFull is returned.
+//
+// If fewer than n bytes are returned, an error explaining why is returned too. See bufio.Reader.Peek for details.
+func (rr *Reader) PeekN(n int) ([]byte, error) {
+	return rr.br.Peek(n)
+}
+
+// peekBlobBody returns the next n bytes from the buffer, consuming them and the following line ending. If the body is
+// not followed by a valid line ending, nothing is consumed and ok is false.
+func (rr *Reader) peekBlobBody(n int) (b []byte, ok bool) {
+	// only peek at the first byte of the line ending to avoid hangs when reading a bare \n at the end of the input
+	b, _ = rr.br.Peek(n + 1)
+	if len(b) != n+1 {
+		return nil, false
+	}
+	if rr.AllowBareLF && b[n] == '\n' {
+		_, _ = rr.br.Discard(n + 1)
+		return b[:n:n], true
+	}
+	if b, _ = rr.br.Peek(n + 2); len(b) != n+2 || b[n] != '\r' || b[n+1] != '\n' {
+		return nil, false
+	}
+	_, _ = rr.br.Discard(n + 2)
+	return b[:n:n], true
+}
+
+func (rr *Reader) readDouble(bitSize int) (float64, error) {
+	b, err := rr.readScalarLine(TypeDouble, rr.scratchBuf(32))
 	if err != nil {
 		return 0, err
 	}
 	if len(b) == 0 {
 		return 0, fmt.Errorf("%w: missing value", ErrUnexpectedEOL)
 	}
-	f, err := strconv.ParseFloat(string(b), 64)
+	sep := rr.replaceDecimalSeparator(b)
+	f, err := strconv.ParseFloat(string(b), bitSize)
 	if err != nil {
-		return 0, fmt.Errorf("%w: %s", ErrInvalidDouble, string(b))
+		return 0, rr.invalidDoubleError(b, sep)
 	}
 	return f, nil
 }
 
+// replaceDecimalSeparator replaces the first DoubleDecimalSeparator in b with '.' and returns its index, or -1 if
+// nothing was replaced.
+func (rr *Reader) replaceDecimalSeparator(b []byte) int {
+	sep := rr.DoubleDecimalSeparator
+	if sep == 0 || sep == '.' {
+		return -1
+	}
+	for i, c := range b {
+		if c == sep {
+			b[i] = '.'
+			return i
+		}
+	}
+	return -1
+}
+
+// invalidDoubleError returns an error wrapping ErrInvalidDouble for the value b, restoring the decimal separator at
+// index sep as replaced by replaceDecimalSeparator, if sep is >= 0.
+func (rr *Reader) invalidDoubleError(b []byte, sep int) error {
+	if sep >= 0 {
+		b[sep] = rr.DoubleDecimalSeparator
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidDouble, truncateErrorValue(b))
+}
+
 func (rr *Reader) readNumber() (int64, error) {
 	var i int
 	var n int64
-	var neg bool
+	var neg, signed bool
 
 loop:
 	for i = 0; ; i++ {
@@ -182,13 +691,18 @@ loop:
 
 		switch {
 		case b == '-' && i == 0:
-			neg = true
+			neg, signed = true, true
+		case b == '+' && i == 0 && rr.AllowPlusSign:
+			signed = true
 		case b >= '0' && b <= '9':
+			if rr.StrictNumbers && n == 0 && (i > 1 || (i == 1 && !signed)) {
+				return 0, fmt.Errorf("%w: leading zeros are not allowed", ErrInvalidNumber)
+			}
 			p := n
 			n *= 10
 			n += int64(b - '0')
 			if n < p {
-				return 0, fmt.Errorf("%w: at character %c (index %d)", ErrOverflow, b, i)
+				return 0, rr.overflowError(neg, p, b, i)
 			}
 		case b == '\r' || b == '\n':
 			_ = rr.br.UnreadByte()
@@ -202,9 +716,12 @@ loop:
 	if err := rr.readEOL(); err != nil {
 		return 0, err
 	}
-	if i < 1 || (i == 1 && neg) {
+	if i < 1 || (i == 1 && signed) {
 		return 0, fmt.Errorf("%w: expected number, got empty value", ErrUnexpectedEOL)
 	}
+	if rr.StrictNumbers && neg && n == 0 {
+		return 0, fmt.Errorf("%w: negative zero is not allowed", ErrInvalidNumber)
+	}
 
 	if neg {
 		n *= -1
@@ -212,8 +729,53 @@ loop:
 	return n, nil
 }
 
+// maxErrorValueLength is the maximum length of values included in error messages.
+const maxErrorValueLength = 64
+
+// overflowError returns an error wrapping ErrOverflow for a number that overflowed at character b (index i), after
+// reading the digits for the number p.
+//
+// The remaining digits of the number are consumed and included in the error message, up to maxErrorValueLength.
+func (rr *Reader) overflowError(neg bool, p int64, b byte, i int) error {
+	var buf [maxErrorValueLength]byte
+	num := buf[:0]
+	if neg {
+		num = append(num, '-')
+	}
+	num = strconv.AppendInt(num, p, 10)
+	num = append(num, b)
+	truncated := false
+	for {
+		c, err := rr.br.ReadByte()
+		if err != nil {
+			break
+		}
+		if c < '0' || c > '9' {
+			_ = rr.br.UnreadByte()
+			break
+		}
+		if len(num) < cap(num) {
+			num = append(num, c)
+		} else {
+			truncated = true
+		}
+	}
+	if truncated {
+		return fmt.Errorf("%w: %s... at character %c (index %d)", ErrOverflow, num, b, i)
+	}
+	return fmt.Errorf("%w: %s at character %c (index %d)", ErrOverflow, num, b, i)
+}
+
+// truncateErrorValue returns b as string, truncated to maxErrorValueLength characters.
+func truncateErrorValue(b []byte) string {
+	if len(b) > maxErrorValueLength {
+		return string(b[:maxErrorValueLength]) + "..."
+	}
+	return string(b)
+}
+
 func (rr *Reader) readChunkableBlob(t Type, dst []byte) ([]byte, bool, error) {
-	if rr.consume([]byte{byte(t), '?', '\r', '\n'}) {
+	if rr.consumeLine([]byte{byte(t), '?'}) {
 		return dst, true, nil
 	}
 	b, err := rr.readBlob(t, dst)
@@ -235,15 +797,45 @@ func (rr *Reader) readBlob(t Type, dst []byte) ([]byte, error) {
 	return b, err
 }
 
+// maxConsecutiveEmptyReads is the number of reads without data and error after which readFull gives up. This matches
+// the limit used by bufio.Reader.
+const maxConsecutiveEmptyReads = 100
+
+// readFull is like io.ReadFull, but returns ErrNoProgress if the underlying io.Reader repeatedly returns neither data
+// nor an error.
+//
+// This is needed because bufio.Reader.Read reads directly from the underlying io.Reader when the buffer is empty and
+// does not guard against empty reads itself, which would make io.ReadFull loop forever.
+func (rr *Reader) readFull(b []byte) (n int, err error) {
+	for empty := 0; n < len(b) && err == nil; {
+		var nn int
+		nn, err = rr.br.Read(b[n:])
+		if n += nn; nn > 0 {
+			empty = 0
+		} else if empty++; empty >= maxConsecutiveEmptyReads {
+			err = ErrNoProgress
+		}
+	}
+	if n == len(b) {
+		err = nil
+	} else if n > 0 && errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
 func (rr *Reader) readBlobBody(dst []byte, n int) ([]byte, error) {
 	if err := rr.checkReadSizeLimit(n); err != nil {
 		return nil, err
 	}
+	if err := rr.addReplyBytes(n); err != nil {
+		return nil, err
+	}
 	b := ensureSpace(dst, n)[:len(dst)+n]
-	if nn, err := io.ReadFull(rr.br, b[len(dst):]); err != nil {
+	if nn, err := rr.readFull(b[len(dst):]); err != nil {
 		return nil, wrapEOF(err, "%d more bytes", n-nn)
 	}
-	if err := rr.readEOL(); err != nil {
+	if err := rr.readBlobEOL(n); err != nil {
 		return nil, err
 	}
 	return b, nil
@@ -264,10 +856,47 @@ func (rr *Reader) readLine(dst []byte) ([]byte, error) {
 			break
 		}
 	}
-	if len(dst) < 2 || dst[len(dst)-2] != '\r' || dst[len(dst)-1] != '\n' {
-		return nil, ErrUnexpectedEOL
+	if len(dst)-slen >= 2 && dst[len(dst)-2] == '\r' && dst[len(dst)-1] == '\n' {
+		if err := rr.addReplyBytes(len(dst) - len("\r\n") - slen); err != nil {
+			return nil, err
+		}
+		return dst[:len(dst)-2], nil
+	}
+	if !rr.AllowBareLF || len(dst)-slen < 1 || dst[len(dst)-1] != '\n' {
+		end := dst[slen:]
+		if len(end) > 2 {
+			end = end[len(end)-2:]
+		}
+		return nil, fmt.Errorf("%w: expected \\r\\n, got %s", ErrUnexpectedEOL, hexEscape(end))
+	}
+	// the limit check above assumes a two byte line ending, so check again using the real length
+	if err := rr.checkReadSizeLimit(len(dst) - len("\n") - slen); err != nil {
+		return nil, err
+	}
+	if err := rr.addReplyBytes(len(dst) - len("\n") - slen); err != nil {
+		return nil, err
+	}
+	return dst[:len(dst)-1], nil
+}
+
+// readScalarLine reads the line of a scalar value of type t. If the Reader is configured to read RESP2, a blob string
+// is accepted instead and its content is returned.
+//
+// The blob is read into a separate buffer, as passing dst to readBlob would make it escape to the heap and cause
+// allocations even when reading RESP3.
+func (rr *Reader) readScalarLine(t Type, dst []byte) ([]byte, error) {
+	if rr.resp2() && rr.match([]byte{byte(TypeBlobString)}) {
+		return rr.readScalarBlob(dst)
+	}
+	return rr.readSimple(t, dst)
+}
+
+func (rr *Reader) readScalarBlob(dst []byte) ([]byte, error) {
+	b, err := rr.readBlob(TypeBlobString, nil)
+	if err != nil {
+		return nil, err
 	}
-	return dst[:len(dst)-2], nil
+	return append(dst, b...), nil
 }
 
 func (rr *Reader) readSimple(t Type, dst []byte) ([]byte, error) {
@@ -277,6 +906,38 @@ func (rr *Reader) readSimple(t Type, dst []byte) ([]byte, error) {
 	return rr.readLine(dst)
 }
 
+// readString reads either a blob string or a simple string, appending it to dst. Chunked blob strings are read
+// completely.
+func (rr *Reader) readString(dst []byte) ([]byte, error) {
+	t, err := rr.peek()
+	if err != nil {
+		return nil, wrapEOF(err, "value of type %q or %q", TypeBlobString, TypeSimpleString)
+	}
+	switch t {
+	case TypeBlobString:
+		b, chunked, err := rr.readChunkableBlob(t, dst)
+		if err == nil && chunked {
+			b, err = rr.ReadBlobChunks(b)
+		}
+		return b, err
+	case TypeSimpleString:
+		return rr.readSimple(t, dst)
+	default:
+		return nil, fmt.Errorf("%w: expected %q or %q, got %q", ErrUnexpectedType, TypeBlobString, TypeSimpleString, t)
+	}
+}
+
+// maxPrealloc is the maximum number of elements allocated up front when reading aggregates, so that a peer can not
+// force large allocations by sending a large aggregate length.
+const maxPrealloc = 1024
+
+func preallocSize(n int64) int {
+	if n > maxPrealloc {
+		return maxPrealloc
+	}
+	return int(n)
+}
+
 func ensureSpace(b []byte, n int) []byte {
 	if m := cap(b) - len(b); m < n {
 		newb := make([]byte, len(b), len(b)+n)
@@ -287,7 +948,7 @@ func ensureSpace(b []byte, n int) []byte {
 }
 
 func (rr *Reader) readAggregateHeader(t Type) (int64, bool, error) {
-	if rr.consume([]byte{byte(t), '?', '\r', '\n'}) {
+	if rr.consumeLine([]byte{byte(t), '?'}) {
 		return -1, true, nil
 	}
 	if err := rr.expect(t); err != nil {
@@ -300,6 +961,65 @@ func (rr *Reader) readAggregateHeader(t Type) (int64, bool, error) {
 	return n, false, err
 }
 
+// LastWasChunked returns true if the last value whose type was consumed was a chunked blob or a streamed aggregate.
+//
+// This allows forwarding values using generic methods like Discard with nested set to false, where the information
+// returned by ReadBlobString or the aggregate header methods is not available. If LastWasChunked returns true, the
+// chunks or elements of the value follow and must be read until the final zero-length chunk or end marker.
+//
+// Methods reading nested values, like ReadValue or Discard with nested set to true, consume the whole value including
+// all chunks and elements. In this case LastWasChunked refers to the last nested value read.
+func (rr *Reader) LastWasChunked() bool {
+	return rr.lastChunked
+}
+
+// More checks if there are more values in a streamed aggregate.
+//
+// If the next value is an end marker, the marker is consumed and More returns false. Otherwise nothing is consumed
+// and More returns true.
+//
+// This can be used to iterate over the values of a streamed aggregate:
+//
+//	for {
+//		more, err := rr.More()
+//		if err != nil {
+//			return err
+//		}
+//		if !more {
+//			break
+//		}
+//		// read next value
+//	}
+func (rr *Reader) More() (bool, error) {
+	t, err := rr.peek()
+	if err != nil {
+		return false, wrapEOF(err, "")
+	}
+	if t != TypeEnd {
+		return true, nil
+	}
+	return false, rr.ReadEnd()
+}
+
+// checkChunks returns an error wrapping ErrTooManyChunks if reading the chunk or element with index i of a streamed
+// value exceeds MaxChunks.
+func (rr *Reader) checkChunks(i int64) error {
+	if rr.MaxChunks > 0 && i >= int64(rr.MaxChunks) {
+		return fmt.Errorf("%w: streamed value exceeds limit of %d chunks", ErrTooManyChunks, rr.MaxChunks)
+	}
+	return nil
+}
+
+// moreChunks is like More, but additionally checks that reading the element with index i of a streamed aggregate
+// does not exceed MaxChunks.
+func (rr *Reader) moreChunks(i int64) (bool, error) {
+	more, err := rr.More()
+	if err != nil || !more {
+		return more, err
+	}
+	return true, rr.checkChunks(i)
+}
+
 // ReadArrayHeader reads an array header, returning the array length.
 //
 // If the array is chunked, n will be set to -1 and chunked will be set to true.
@@ -308,6 +1028,37 @@ func (rr *Reader) ReadArrayHeader() (n int64, chunked bool, err error) {
 	return rr.readAggregateHeader(TypeArray)
 }
 
+// ReadAttribute reads an attribute, including all nested values, and returns it as map.
+//
+// Keys can be either blob strings, including chunked blob strings, or simple strings. Values are read using ReadValue.
+//
+// If the next type in the response is not an attribute, ErrUnexpectedType is returned.
+func (rr *Reader) ReadAttribute() (map[string]interface{}, error) {
+	n, chunked, err := rr.ReadAttributeHeader()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{}, preallocSize(n))
+	var buf [64]byte
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil {
+				return nil, err
+			} else if !more {
+				break
+			}
+		}
+		k, err := rr.readString(buf[:0])
+		if err != nil {
+			return nil, err
+		}
+		if m[string(k)], err = rr.ReadValue(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
 // ReadAttributeHeader reads an attribute header, returning the attribute size.
 //
 // If the array is chunked, n will be set to -1 and chunked will be set to true.
@@ -316,36 +1067,152 @@ func (rr *Reader) ReadAttributeHeader() (n int64, chunked bool, err error) {
 	return rr.readAggregateHeader(TypeAttribute)
 }
 
-// ReadBigNumber reads a big number from into n.
+// ReadAttributeStringString reads an attribute with string keys and string values and stores the entries in dst,
+// returning the resulting map. If dst is nil, a new map is allocated.
 //
-// If the next type in the response is not a big number, ErrUnexpectedType is returned.
-func (rr *Reader) ReadBigNumber(n *big.Int) error {
-	if err := rr.expect(TypeBigNumber); err != nil {
-		return err
-	}
-	var buf [64]byte
-	b, err := rr.readLine(buf[:0])
+// Both keys and values can be either blob strings, including chunked blob strings, or simple strings.
+//
+// If the next type in the response is not an attribute, or if any key or value is not a string, ErrUnexpectedType
+// is returned.
+func (rr *Reader) ReadAttributeStringString(dst map[string]string) (map[string]string, error) {
+	n, chunked, err := rr.ReadAttributeHeader()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if dst == nil {
+		dst = make(map[string]string, preallocSize(n))
+	}
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil {
+				return nil, err
+			} else if !more {
+				break
+			}
+		}
+		k, v, err := rr.ReadMapEntryStrings()
+		if err != nil {
+			return nil, err
+		}
+		dst[k] = v
+	}
+	return dst, nil
+}
+
+// ReadBigNumber reads a big number from into n.
+//
+// If the next type in the response is not a big number, ErrUnexpectedType is returned.
+func (rr *Reader) ReadBigNumber(n *big.Int) error {
+	b, err := rr.readScalarLine(TypeBigNumber, rr.scratchBuf(64))
+	if err != nil {
+		return err
 	}
 	if len(b) == 0 {
 		return fmt.Errorf("%w: missing value", ErrUnexpectedEOL)
 	}
-	if _, ok := n.SetString(string(b), 10); !ok {
-		return fmt.Errorf("%w: %s", ErrInvalidBigNumber, string(b))
+	if !isBigNumber(b) {
+		return fmt.Errorf("%w: %s", ErrInvalidBigNumber, truncateErrorValue(b))
 	}
+	rr.setBigNumber(n, b)
 	return nil
 }
 
+// bigNumberChunkSize is the maximum number of decimal digits that always fit into an uint64.
+const bigNumberChunkSize = 19
+
+// bigNumberChunkMul is used to shift the already parsed digits of a big number by bigNumberChunkSize digits.
+var bigNumberChunkMul = new(big.Int).SetUint64(10000000000000000000)
+
+// setBigNumber sets n to the value of the decimal integer b, which must have been validated using isBigNumber.
+//
+// In contrast to big.Int.SetString this avoids converting b to a string. Numbers are parsed in chunks that fit into an
+// uint64, so that numbers with up to 19 digits are parsed without allocating if n is large enough.
+func (rr *Reader) setBigNumber(n *big.Int, b []byte) {
+	neg := b[0] == '-'
+	if b[0] == '-' || b[0] == '+' {
+		b = b[1:]
+	}
+	first := len(b) % bigNumberChunkSize
+	if first == 0 {
+		first = bigNumberChunkSize
+	}
+	n.SetUint64(parseDigits(b[:first]))
+	mul, chunk := &rr.bigNumberBuf[0], &rr.bigNumberBuf[1]
+	for b = b[first:]; len(b) > 0; b = b[bigNumberChunkSize:] {
+		mul.Mul(n, bigNumberChunkMul)
+		chunk.SetUint64(parseDigits(b[:bigNumberChunkSize]))
+		n.Add(mul, chunk)
+	}
+	if neg {
+		n.Neg(n)
+	}
+}
+
+func parseDigits(b []byte) uint64 {
+	var u uint64
+	for _, c := range b {
+		u = u*10 + uint64(c-'0')
+	}
+	return u
+}
+
+// ReadBigNumberBytes reads a big number and appends its decimal representation to dst, returning the resulting slice.
+//
+// The number is validated like in ReadBigNumber, but is not parsed into a big.Int. This can be used to store or
+// forward big numbers without the overhead of the math/big package.
+//
+// If the next type in the response is not a big number, ErrUnexpectedType is returned.
+func (rr *Reader) ReadBigNumberBytes(dst []byte) ([]byte, error) {
+	b, err := rr.readScalarLine(TypeBigNumber, dst)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == len(dst) {
+		return nil, fmt.Errorf("%w: missing value", ErrUnexpectedEOL)
+	}
+	if !isBigNumber(b[len(dst):]) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBigNumber, truncateErrorValue(b[len(dst):]))
+	}
+	return b, nil
+}
+
+// ReadBlobBody reads the body of a blob with length n and the trailing line ending, appending the body to dst and
+// returning the resulting slice.
+//
+// This can be used together with ReadBlobStringHeader to read blob strings in two steps, for example to decide on a
+// strategy based on the declared length before reading the body.
+//
+// If n is negative, an error wrapping ErrInvalidBlobLength is returned. If n exceeds SingleReadSizeLimit, an error
+// wrapping ErrSingleReadSizeLimitExceeded is returned and nothing is consumed.
+func (rr *Reader) ReadBlobBody(dst []byte, n int64) ([]byte, error) {
+	if n < 0 || int64(int(n)) != n {
+		return nil, fmt.Errorf("%w: got length %d", ErrInvalidBlobLength, n)
+	}
+	return rr.readBlobBody(dst, int(n))
+}
+
 // ReadBlobChunk reads a blob chunk into b, returning the resulting slice and a boolean indicating
 // whether this was the last chunk.
 //
+// Only a chunk of length 0 marks the end of the blob. If the chunk has a negative length, an error wrapping
+// ErrInvalidBlobChunkLength is returned.
+//
 // If the next type in the response is not blob chunk, ErrUnexpectedType is returned.
 func (rr *Reader) ReadBlobChunk(b []byte) (bb []byte, last bool, err error) {
-	if rr.consume([]byte{byte(TypeBlobChunk), '0', '\r', '\n'}) {
+	if rr.consumeLine([]byte{byte(TypeBlobChunk), '0'}) {
 		return b, true, nil
 	}
-	b, err = rr.readBlob(TypeBlobChunk, b)
+	if err := rr.expect(TypeBlobChunk); err != nil {
+		return nil, false, err
+	}
+	n, err := rr.readNumber()
+	if err != nil {
+		return nil, false, err
+	}
+	if n < 0 {
+		return nil, false, fmt.Errorf("%w: got length %d", ErrInvalidBlobChunkLength, n)
+	}
+	b, err = rr.readBlobBody(b, int(n))
 	return b, false, err
 }
 
@@ -354,22 +1221,44 @@ func (rr *Reader) ReadBlobChunk(b []byte) (bb []byte, last bool, err error) {
 //
 // If the next type in the response is not blob chunk, ErrUnexpectedType is returned.
 func (rr *Reader) ReadBlobChunks(b []byte) ([]byte, error) {
-	for {
+	return rr.readBlobChunks(b, nil)
+}
+
+// readBlobChunks is like ReadBlobChunks, but additionally calls fn, if not nil, with the content of each chunk,
+// including the final empty chunk.
+func (rr *Reader) readBlobChunks(b []byte, fn func(chunk []byte) error) ([]byte, error) {
+	for i := int64(0); ; i++ {
+		n := len(b)
 		var last bool
 		var err error
 		if b, last, err = rr.ReadBlobChunk(b); err != nil {
 			return nil, err
-		} else if last {
+		}
+		if fn != nil {
+			if err := fn(b[n:]); err != nil {
+				return nil, err
+			}
+		}
+		if last {
 			return b, nil
 		}
+		if err := rr.checkChunks(i); err != nil {
+			return nil, err
+		}
 	}
 }
 
 // ReadBlobError reads a blob error into b, returning the resulting slice.
 //
+// If StrictBlobErrors is true and the blob error is empty, ErrEmptyError is returned.
+//
 // If the next type in the response is not blob error, ErrUnexpectedType is returned.
 func (rr *Reader) ReadBlobError(b []byte) (bb []byte, chunked bool, err error) {
-	return rr.readChunkableBlob(TypeBlobError, b)
+	bb, chunked, err = rr.readChunkableBlob(TypeBlobError, b)
+	if err == nil && !chunked && rr.StrictBlobErrors && len(bb) == len(b) {
+		return nil, false, ErrEmptyError
+	}
+	return bb, chunked, err
 }
 
 // ReadBlobString reads a blob string into b, returning the resulting slice.
@@ -379,15 +1268,157 @@ func (rr *Reader) ReadBlobString(b []byte) (bb []byte, chunked bool, err error)
 	return rr.readChunkableBlob(TypeBlobString, b)
 }
 
+// ReadBlobStringCtx is like ReadBlobString, but checks ctx for cancellation while reading the blob string.
+//
+// The body of the blob string is read in parts of at most the size of the internal buffer and ctx is checked before
+// each part, so that reading large blob strings can be aborted. A single read from the underlying io.Reader can not be
+// interrupted by ctx. To abort blocking reads, use deadlines on the underlying connection instead.
+//
+// If ctx is done, ctx.Err() is returned. In this case the blob string may have been partially read, leaving the Reader
+// in an undefined state. The Reader should be discarded afterwards.
+//
+// If the next type in the response is not blob string, ErrUnexpectedType is returned.
+func (rr *Reader) ReadBlobStringCtx(ctx context.Context, dst []byte) (b []byte, chunked bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	if rr.consumeLine([]byte{byte(TypeBlobString), '?'}) {
+		return dst, true, nil
+	}
+	if err := rr.expect(TypeBlobString); err != nil {
+		return nil, false, err
+	}
+	n64, err := rr.readNumber()
+	if err != nil {
+		return nil, false, err
+	}
+	if n64 < 0 {
+		return nil, false, fmt.Errorf("%w: got length %d", ErrInvalidBlobLength, n64)
+	}
+	n := int(n64)
+	if err := rr.checkReadSizeLimit(n); err != nil {
+		return nil, false, err
+	}
+	b = ensureSpace(dst, n)[:len(dst)+n]
+	for off := len(dst); off < len(b); {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		end := off + rr.br.Size()
+		if end > len(b) {
+			end = len(b)
+		}
+		nn, err := rr.readFull(b[off:end])
+		if off += nn; err != nil {
+			return nil, false, wrapEOF(err, "%d more bytes", len(b)-off)
+		}
+	}
+	if err := rr.readBlobEOL(n); err != nil {
+		return nil, false, err
+	}
+	return b, false, nil
+}
+
+// ReadBlobStringHeader reads the type and length of a blob string, without reading the body.
+//
+// If the blob string is chunked, -1 and true are returned and the chunks can be read using ReadBlobChunk. Otherwise
+// the declared length is returned and the body, including the trailing line ending, must be read using ReadBlobBody
+// before reading the next value.
+//
+// If the next type in the response is not blob string, ErrUnexpectedType is returned.
+func (rr *Reader) ReadBlobStringHeader() (n int64, chunked bool, err error) {
+	if rr.consumeLine([]byte{byte(TypeBlobString), '?'}) {
+		return -1, true, nil
+	}
+	if err := rr.expect(TypeBlobString); err != nil {
+		return 0, false, err
+	}
+	if n, err = rr.readNumber(); err != nil {
+		return 0, false, err
+	}
+	if n < 0 {
+		return 0, false, fmt.Errorf("%w: got length %d", ErrInvalidBlobLength, n)
+	}
+	return n, false, nil
+}
+
+// ReadBlobStringN reads a blob string directly into dst, returning the length of the blob string.
+//
+// If the blob string is longer than len(dst), the blob string is skipped and an error wrapping io.ErrShortBuffer is
+// returned.
+//
+// Chunked blob strings are not supported. If the next value is a chunked blob string, nothing is consumed and an error
+// wrapping ErrUnexpectedType is returned.
+//
+// If the next type in the response is not blob string, ErrUnexpectedType is returned.
+func (rr *Reader) ReadBlobStringN(dst []byte) (int, error) {
+	if rr.matchLine([]byte{byte(TypeBlobString), '?'}) > 0 {
+		return 0, fmt.Errorf("%w: can not read chunked blob string into fixed size buffer", ErrUnexpectedType)
+	}
+	if err := rr.expect(TypeBlobString); err != nil {
+		return 0, err
+	}
+	n64, err := rr.readNumber()
+	if err != nil {
+		return 0, err
+	}
+	if n64 < 0 {
+		return 0, fmt.Errorf("%w: got length %d", ErrInvalidBlobLength, n64)
+	}
+	n := int(n64)
+	if err := rr.checkReadSizeLimit(n); err != nil {
+		return 0, err
+	}
+	if n > len(dst) {
+		if nn, err := rr.br.Discard(n); err != nil {
+			return 0, wrapEOF(err, "%d more bytes", n-nn)
+		}
+		if err := rr.readBlobEOL(n); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%w: blob string of length %d does not fit into buffer of size %d",
+			io.ErrShortBuffer, n, len(dst))
+	}
+	if nn, err := rr.readFull(dst[:n]); err != nil {
+		return 0, wrapEOF(err, "%d more bytes", n-nn)
+	}
+	if err := rr.readBlobEOL(n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
 // ReadBoolean reads a boolean.
 //
+// If BooleanFromInteger is set or Protocol is 2, numbers are also accepted and all non-zero numbers are returned as
+// true.
+//
 // If the next type in the response is not boolean, ErrUnexpectedType is returned.
 func (rr *Reader) ReadBoolean() (bool, error) {
+	if (rr.BooleanFromInteger || rr.resp2()) && rr.match([]byte{byte(TypeNumber)}) {
+		n, err := rr.ReadNumber()
+		return n != 0, err
+	}
+	// fast path for the common case of a complete, valid boolean in the buffer. Peeking a single byte fills the buffer
+	// if it is empty, but only data that is already buffered afterwards is checked, to avoid blocking on a bare \n at
+	// the end of the input.
+	if _, err := rr.br.Peek(1); err == nil && rr.br.Buffered() >= len("#t\r\n") {
+		b, _ := rr.br.Peek(len("#t\r\n"))
+		if b[0] == byte(TypeBoolean) && (b[1] == 't' || b[1] == 'f') && b[2] == '\r' && b[3] == '\n' {
+			if err := rr.addReplyBytes(1); err != nil {
+				return false, err
+			}
+			v := b[1] == 't'
+			_, _ = rr.br.Discard(len(b))
+			rr.countType(byte(TypeBoolean))
+			rr.lastChunked = false
+			return v, nil
+		}
+	}
 	if err := rr.expect(TypeBoolean); err != nil {
 		return false, err
 	}
-	var buf [1]byte
-	b, err := rr.readLine(buf[:0])
+	b, err := rr.readLine(rr.scratchBuf(1))
 	if err != nil {
 		return false, err
 	}
@@ -402,12 +1433,37 @@ func (rr *Reader) ReadBoolean() (bool, error) {
 
 // ReadDouble reads a double.
 //
+// Negative zero ("-0" or "-0.0") is returned as negative zero, so the sign can be checked using math.Signbit.
+//
+// If the value can not be parsed, an error wrapping ErrInvalidDouble and containing the value is returned. Values
+// using a decimal separator other than '.', for example "1,5", are rejected unless DoubleDecimalSeparator is set.
+//
 // If the next type in the response is not double, ErrUnexpectedType is returned.
 func (rr *Reader) ReadDouble() (float64, error) {
-	if err := rr.expect(TypeDouble); err != nil {
-		return 0, err
+	return rr.readDouble(64)
+}
+
+// ReadDoubleBytes reads a double and appends its original textual representation to dst, returning the resulting
+// slice.
+//
+// The value is validated like in ReadDouble, but is returned exactly as read, for example "1.0" is not changed to
+// "1". This can be used to forward doubles without losing their original representation. The only exception is the
+// decimal separator, which is replaced with '.' if DoubleDecimalSeparator is set.
+//
+// If the next type in the response is not double, ErrUnexpectedType is returned.
+func (rr *Reader) ReadDoubleBytes(dst []byte) ([]byte, error) {
+	b, err := rr.readScalarLine(TypeDouble, dst)
+	if err != nil {
+		return nil, err
 	}
-	return rr.readDouble()
+	if len(b) == len(dst) {
+		return nil, fmt.Errorf("%w: missing value", ErrUnexpectedEOL)
+	}
+	sep := rr.replaceDecimalSeparator(b[len(dst):])
+	if _, err := strconv.ParseFloat(string(b[len(dst):]), 64); err != nil {
+		return nil, rr.invalidDoubleError(b[len(dst):], sep)
+	}
+	return b, nil
 }
 
 // ReadEnd reads a stream end marker.
@@ -420,19 +1476,316 @@ func (rr *Reader) ReadEnd() error {
 	return rr.readEOL()
 }
 
+// ReadErrorReply reads either a simple error or a blob error and returns it as *RedisError.
+//
+// If the next value is a chunked blob error, all chunks are read and combined into a single error.
+//
+// If the next type in the response is neither simple error nor blob error, ErrUnexpectedType is returned.
+func (rr *Reader) ReadErrorReply() (*RedisError, error) {
+	t, err := rr.peek()
+	if err != nil {
+		return nil, wrapEOF(err, "value of type %q or %q", TypeSimpleError, TypeBlobError)
+	}
+
+	var buf [64]byte
+	var b []byte
+
+	switch t {
+	case TypeSimpleError:
+		b, err = rr.ReadSimpleError(buf[:0])
+	case TypeBlobError:
+		var chunked bool
+		if b, chunked, err = rr.ReadBlobError(buf[:0]); err == nil && chunked {
+			b, err = rr.ReadBlobChunks(b)
+		}
+	default:
+		return nil, fmt.Errorf("%w: expected %q or %q, got %q", ErrUnexpectedType, TypeSimpleError, TypeBlobError, t)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return newRedisError(b), nil
+}
+
+// ReadFloat32 reads a double and returns it rounded to the nearest float32.
+//
+// The value is parsed directly with float32 precision, avoiding double rounding when converting a float64. Infinity
+// and NaN are returned as their float32 equivalents. If the value is outside the range of float32, an error wrapping
+// ErrInvalidDouble is returned.
+//
+// If the next type in the response is not double, ErrUnexpectedType is returned.
+func (rr *Reader) ReadFloat32() (float32, error) {
+	f, err := rr.readDouble(32)
+	return float32(f), err
+}
+
+// ReadInt reads a number as int.
+//
+// If the number does not fit into an int, it is still consumed and an error wrapping ErrOverflow is returned.
+//
+// If the next type in the response is not number, ErrUnexpectedType is returned.
+func (rr *Reader) ReadInt() (int, error) {
+	n, err := rr.ReadNumber()
+	if err != nil {
+		return 0, err
+	}
+	if int64(int(n)) != n {
+		return 0, fmt.Errorf("%w: %d does not fit into int", ErrOverflow, n)
+	}
+	return int(n), nil
+}
+
+// ReadInt32 reads a number as int32.
+//
+// If the number does not fit into an int32, it is still consumed and an error wrapping ErrOverflow is returned.
+//
+// If the next type in the response is not number, ErrUnexpectedType is returned.
+func (rr *Reader) ReadInt32() (int32, error) {
+	n, err := rr.ReadNumber()
+	if err != nil {
+		return 0, err
+	}
+	if n < math.MinInt32 || n > math.MaxInt32 {
+		return 0, fmt.Errorf("%w: %d does not fit into int32", ErrOverflow, n)
+	}
+	return int32(n), nil
+}
+
+// ReadInteger reads a number, also known as integer, as int64.
+//
+// If the next type in the response is not number, ErrUnexpectedType is returned.
+func (rr *Reader) ReadInteger() (int64, error) {
+	if err := rr.expect(TypeNumber); err != nil {
+		return 0, err
+	}
+	return rr.readNumber()
+}
+
+// ReadIntegerRange reads a number and checks that it is in the range [min, max].
+//
+// If the number is outside the range, it is still consumed and an error wrapping ErrOutOfRange is returned.
+//
+// If the next type in the response is not number, ErrUnexpectedType is returned.
+func (rr *Reader) ReadIntegerRange(min, max int64) (int64, error) {
+	n, err := rr.ReadNumber()
+	if err != nil {
+		return 0, err
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("%w: %d is not in range [%d, %d]", ErrOutOfRange, n, min, max)
+	}
+	return n, nil
+}
+
+// ReadLeadingAttribute reads the attribute preceding the next value, if any.
+//
+// If the next value is an attribute, it is read completely and its entries are returned together with true. Keys and
+// values can be any scalar type and are converted to strings using their textual representation, for example the
+// number 10 is returned as "10". Nulls are returned as empty strings. If a key or value is not a scalar type, an error
+// wrapping ErrTypeMismatch is returned.
+//
+// If the next value is not an attribute, ReadLeadingAttribute returns nil and false without consuming anything.
+func (rr *Reader) ReadLeadingAttribute() (map[string]string, bool, error) {
+	t, err := rr.Peek()
+	if err != nil {
+		return nil, false, err
+	}
+	if t != TypeAttribute {
+		return nil, false, nil
+	}
+	n, chunked, err := rr.ReadAttributeHeader()
+	if err != nil {
+		return nil, false, err
+	}
+	m := make(map[string]string, preallocSize(n))
+	var buf [64]byte
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil {
+				return nil, false, err
+			} else if !more {
+				break
+			}
+		}
+		k, _, err := rr.readScalarText(buf[:0])
+		if err != nil {
+			return nil, false, err
+		}
+		ks := string(k)
+		v, _, err := rr.readScalarText(buf[:0])
+		if err != nil {
+			return nil, false, err
+		}
+		m[ks] = string(v)
+	}
+	return m, true, nil
+}
+
+// ReadMapEntry reads the key of a map entry into b, returning the resulting slice. The value of the entry must be
+// read by the caller.
+//
+// The key can be either a blob string, including chunked blob strings, or a simple string.
+//
+// If the next type in the response is neither blob string nor simple string, ErrUnexpectedType is returned.
+func (rr *Reader) ReadMapEntry(b []byte) ([]byte, error) {
+	return rr.readString(b)
+}
+
+// ReadMapEntryStrings reads a map entry with a string key and a string value.
+//
+// Both key and value can be either a blob string, including chunked blob strings, or a simple string.
+//
+// If the next type in the response is neither blob string nor simple string, ErrUnexpectedType is returned.
+func (rr *Reader) ReadMapEntryStrings() (k, v string, err error) {
+	var buf [64]byte
+	b, err := rr.readString(buf[:0])
+	if err != nil {
+		return "", "", err
+	}
+	k = string(b)
+	if b, err = rr.readString(buf[:0]); err != nil {
+		return "", "", err
+	}
+	return k, string(b), nil
+}
+
+// ReadMapFields reads a map with string keys and for each key calls the function registered for the key in fields,
+// which must read the value of the entry. Values of keys without a registered function are discarded.
+//
+// Keys can be either blob strings, including chunked blob strings, or simple strings. Like with ReadMapHeader, if
+// Protocol is 2 arrays with an even number of elements are read as maps.
+//
+// ReadMapFields stops at the first error returned by a function in fields and returns it. Functions can keep track
+// of whether they were called to detect missing fields.
+//
+// If the next type in the response is not a map, or if any key is not a string, ErrUnexpectedType is returned.
+func (rr *Reader) ReadMapFields(fields map[string]func(r *Reader) error) error {
+	n, chunked, err := rr.ReadMapHeader()
+	if err != nil {
+		return err
+	}
+	var buf [64]byte
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil {
+				return err
+			} else if !more {
+				break
+			}
+		}
+		k, err := rr.readString(buf[:0])
+		if err != nil {
+			return err
+		}
+		fn := fields[string(k)]
+		if fn == nil {
+			if _, err := rr.Discard(true); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(rr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ReadMapHeader reads a map header, returning the map size.
 //
 // If the array is chunked, n will be set to -1 and chunked will be set to true.
 // If the next type in the response is not a map, ErrUnexpectedType is returned.
 func (rr *Reader) ReadMapHeader() (n int64, chunked bool, err error) {
+	if rr.resp2() && rr.match([]byte{byte(TypeArray)}) {
+		n, chunked, err = rr.readAggregateHeader(TypeArray)
+		if err != nil || chunked {
+			return n, chunked, err
+		}
+		if n%2 != 0 {
+			return 0, false, fmt.Errorf("%w: array with odd length %d can not be read as map",
+				ErrInvalidAggregateTypeLength, n)
+		}
+		return n / 2, false, nil
+	}
 	return rr.readAggregateHeader(TypeMap)
 }
 
+// ReadMapStringAny reads a map with string keys and stores the entries in dst, returning the resulting map. If dst is
+// nil, a new map is allocated.
+//
+// Keys can be either blob strings, including chunked blob strings, or simple strings. Values can be of any type,
+// including aggregates, and are read using ReadValue. See ReadValue for the Go types used for each RESP type.
+//
+// If the next type in the response is not a map, or if any key is not a string, ErrUnexpectedType is returned.
+func (rr *Reader) ReadMapStringAny(dst map[string]interface{}) (map[string]interface{}, error) {
+	n, chunked, err := rr.ReadMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	if dst == nil {
+		dst = make(map[string]interface{}, preallocSize(n))
+	}
+	var buf [64]byte
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil {
+				return nil, err
+			} else if !more {
+				break
+			}
+		}
+		k, err := rr.readString(buf[:0])
+		if err != nil {
+			return nil, err
+		}
+		ks := string(k)
+		if dst[ks], err = rr.ReadValue(); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// checkValueCount returns an error if n is negative or exceeds MaxReplyNodes, or DefaultMaxValueCount if
+// MaxReplyNodes is not set.
+func (rr *Reader) checkValueCount(n int) error {
+	if n < 0 {
+		return fmt.Errorf("%w: got negative count %d", ErrOutOfRange, n)
+	}
+	limit := rr.MaxReplyNodes
+	if limit <= 0 {
+		limit = DefaultMaxValueCount
+	}
+	if int64(n) > limit {
+		return fmt.Errorf("%w: %d values exceed limit of %d values", ErrAggregateTooLong, n, limit)
+	}
+	return nil
+}
+
+// ReadN calls fn n times, passing the index of the value and the Reader, so that fn can read the next value. This
+// can be used to read a sequence of n values, where the caller controls how each value is decoded.
+//
+// ReadN stops at the first error returned by fn and returns it. If n is negative, an error wrapping ErrOutOfRange is
+// returned. If n exceeds MaxReplyNodes, or DefaultMaxValueCount if MaxReplyNodes is <= 0, an error wrapping
+// ErrAggregateTooLong is returned. In both cases fn is not called.
+func (rr *Reader) ReadN(n int, fn func(i int, r *Reader) error) error {
+	if err := rr.checkValueCount(n); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := fn(i, rr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ReadNull reads a stream end marker.
 //
 // For backwards compatibility with RESP2, if the next value is either an array or
 // an blob string with length -1, ReadNull will treat the value as a normal null
-// value.
+// value, unless DisableRESP2Null is set.
 //
 // If the next type in the response is not null, ErrUnexpectedType is returned.
 func (rr *Reader) ReadNull() error {
@@ -440,33 +1793,136 @@ func (rr *Reader) ReadNull() error {
 	if err != nil {
 		return wrapEOF(err, "value of type %q", TypeNull)
 	}
-	if ty == TypeArray || ty == TypeBlobString {
-		if rr.consume([]byte{byte(ty), '-', '1', '\r', '\n'}) {
+	if !rr.DisableRESP2Null && (ty == TypeArray || ty == TypeBlobString) {
+		if rr.consumeLine([]byte{byte(ty), '-', '1'}) {
 			return nil
 		}
 	}
-	if err := rr.expect(TypeNull); err != nil {
-		return err
-	}
-	return rr.readEOL()
-}
+	if err := rr.expect(TypeNull); err != nil {
+		return err
+	}
+	return rr.readEOL()
+}
+
+// ReadNumber reads a number. It is equivalent to ReadInteger and kept for compatibility.
+func (rr *Reader) ReadNumber() (int64, error) {
+	return rr.ReadInteger()
+}
+
+// ReadNumeric reads either a number or a double and returns its value as float64, together with a boolean indicating
+// whether the value was a number.
+//
+// This can be used for replies that can be either a number or a double, without having to check the type first.
+// Numbers with an absolute value greater than 2^53 may not be exactly representable as float64 and are rounded.
+//
+// If Protocol is 2, blob strings are also accepted and read like in ReadDouble.
+//
+// If the next type in the response is neither number nor double, ErrUnexpectedType is returned.
+func (rr *Reader) ReadNumeric() (value float64, isInteger bool, err error) {
+	t, err := rr.peek()
+	if err != nil {
+		return 0, false, wrapEOF(err, "value of type %q or %q", TypeNumber, TypeDouble)
+	}
+	switch {
+	case t == TypeNumber:
+		n, err := rr.ReadNumber()
+		return float64(n), err == nil, err
+	case t == TypeDouble, t == TypeBlobString && rr.resp2():
+		f, err := rr.ReadDouble()
+		return f, false, err
+	default:
+		return 0, false, fmt.Errorf("%w: expected %q or %q, got %q", ErrUnexpectedType, TypeNumber, TypeDouble, t)
+	}
+}
+
+// ReadPushHeader reads a push header, returning the push size.
+//
+// If the array is chunked, n will be set to -1 and chunked will be set to true.
+// If the next type in the response is not a push, ErrUnexpectedType is returned.
+func (rr *Reader) ReadPushHeader() (n int64, chunked bool, err error) {
+	if rr.resp2() && rr.match([]byte{byte(TypeArray)}) {
+		return rr.readAggregateHeader(TypeArray)
+	}
+	return rr.readAggregateHeader(TypePush)
+}
+
+// ReadPushMessage reads a pub/sub push message.
+//
+// The fields of the returned PushMessage are set depending on the kind of the message:
+//
+//	message, smessage                                  Channel and Payload
+//	pmessage                                           Pattern, Channel and Payload
+//	subscribe, unsubscribe, ssubscribe, sunsubscribe   Channel and Count
+//	psubscribe, punsubscribe                           Pattern and Count
+//
+// If the message has an unknown kind or does not have the expected number of elements, the remaining elements are
+// discarded and an error wrapping ErrInvalidPushMessage is returned.
+//
+// If the next type in the response is not a push, ErrUnexpectedType is returned.
+func (rr *Reader) ReadPushMessage() (*PushMessage, error) {
+	n, chunked, err := rr.ReadPushHeader()
+	if err != nil {
+		return nil, err
+	}
+	if chunked {
+		return nil, fmt.Errorf("%w: streamed push messages are not supported", ErrInvalidPushMessage)
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("%w: missing kind", ErrInvalidPushMessage)
+	}
+
+	var buf [64]byte
+	kind, err := rr.readString(buf[:0])
+	if err != nil {
+		return nil, err
+	}
+	m := &PushMessage{Kind: string(kind)}
+
+	var fields []*string
+	var count, payload bool
+	switch m.Kind {
+	case "message", "smessage":
+		fields, payload = []*string{&m.Channel}, true
+	case "pmessage":
+		fields, payload = []*string{&m.Pattern, &m.Channel}, true
+	case "subscribe", "unsubscribe", "ssubscribe", "sunsubscribe":
+		fields, count = []*string{&m.Channel}, true
+	case "psubscribe", "punsubscribe":
+		fields, count = []*string{&m.Pattern}, true
+	default:
+		if err := rr.discardN(n - 1); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidPushMessage, truncateErrorValue(kind))
+	}
+
+	if expected := int64(1 + len(fields) + 1); n != expected {
+		if err := rr.discardN(n - 1); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %q with %d elements, expected %d", ErrInvalidPushMessage, m.Kind, n, expected)
+	}
+
+	for _, f := range fields {
+		b, err := rr.readString(buf[:0])
+		if err != nil {
+			return nil, err
+		}
+		*f = string(b)
+	}
 
-// ReadNumber reads a number.
-//
-// If the next type in the response is not number, ErrUnexpectedType is returned.
-func (rr *Reader) ReadNumber() (int64, error) {
-	if err := rr.expect(TypeNumber); err != nil {
-		return 0, err
+	switch {
+	case payload:
+		if m.Payload, err = rr.readString([]byte{}); err != nil {
+			return nil, err
+		}
+	case count:
+		if m.Count, err = rr.ReadNumber(); err != nil {
+			return nil, err
+		}
 	}
-	return rr.readNumber()
-}
 
-// ReadPushHeader reads a push header, returning the push size.
-//
-// If the array is chunked, n will be set to -1 and chunked will be set to true.
-// If the next type in the response is not a push, ErrUnexpectedType is returned.
-func (rr *Reader) ReadPushHeader() (n int64, chunked bool, err error) {
-	return rr.readAggregateHeader(TypePush)
+	return m, nil
 }
 
 // ReadSetHeader reads a set header, returning the set size.
@@ -474,9 +1930,57 @@ func (rr *Reader) ReadPushHeader() (n int64, chunked bool, err error) {
 // If the array is chunked, n will be set to -1 and chunked will be set to true.
 // If the next type in the response is not a set, ErrUnexpectedType is returned.
 func (rr *Reader) ReadSetHeader() (n int64, chunked bool, err error) {
+	if rr.resp2() && rr.match([]byte{byte(TypeArray)}) {
+		return rr.readAggregateHeader(TypeArray)
+	}
 	return rr.readAggregateHeader(TypeSet)
 }
 
+// ReadSetStringSet reads a set of strings and adds its members to dst, returning the resulting map. If dst is nil, a
+// new map is allocated.
+//
+// Members can be either blob strings, including chunked blob strings, or simple strings. If StrictSets is true and the
+// set contains the same member more than once, an error wrapping ErrDuplicateSetMember is returned. Members already
+// contained in dst before the call are not considered duplicates.
+//
+// If the next type in the response is not a set, or if any member is not a string, ErrUnexpectedType is returned.
+func (rr *Reader) ReadSetStringSet(dst map[string]struct{}) (map[string]struct{}, error) {
+	n, chunked, err := rr.ReadSetHeader()
+	if err != nil {
+		return nil, err
+	}
+	m := dst
+	if m == nil || (rr.StrictSets && len(m) > 0) {
+		m = make(map[string]struct{}, preallocSize(n))
+	}
+	var buf [64]byte
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil {
+				return nil, err
+			} else if !more {
+				break
+			}
+		}
+		b, err := rr.readString(buf[:0])
+		if err != nil {
+			return nil, err
+		}
+		l := len(m)
+		m[string(b)] = struct{}{}
+		if rr.StrictSets && len(m) == l {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateSetMember, truncateErrorValue(b))
+		}
+	}
+	if dst == nil {
+		return m, nil
+	}
+	for k := range m {
+		dst[k] = struct{}{}
+	}
+	return dst, nil
+}
+
 // ReadSimpleError reads a simple error into b, returning the resulting slice.
 //
 // If the next type in the response is not simple error, ErrUnexpectedType is returned.
@@ -491,6 +1995,32 @@ func (rr *Reader) ReadSimpleString(b []byte) ([]byte, error) {
 	return rr.readSimple(TypeSimpleString, b)
 }
 
+// ReadStreamedArray reads an array and calls fn once for each element. fn must read exactly one element from r.
+//
+// For streamed arrays, fn is called until the end marker is reached. The end marker is consumed by
+// ReadStreamedArray. Arrays with a fixed length are also accepted, in which case fn is called once for each element.
+//
+// If fn returns an error, ReadStreamedArray stops and returns the error. The remaining elements are not consumed.
+//
+// If the next type in the response is not an array, ErrUnexpectedType is returned.
+func (rr *Reader) ReadStreamedArray(fn func(r *Reader) error) error {
+	n, chunked, err := rr.ReadArrayHeader()
+	if err != nil {
+		return err
+	}
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil || !more {
+				return err
+			}
+		}
+		if err := fn(rr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ReadVerbatimString reads a verbatim string into b, returning the resulting slice
 //
 // If the next type in the response is not simple string, ErrUnexpectedType is returned.
@@ -500,41 +2030,240 @@ func (rr *Reader) ReadVerbatimString(b []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	// the prefix must be followed by a colon, but the text may be empty (e.g. "txt:")
 	if bs := b[oldLen:]; len(bs) < verbatimPrefixLength+1 || bs[verbatimPrefixLength] != ':' {
-		p := bs
-		if n := verbatimPrefixLength*verbatimPrefixLength + 1; len(p) >= n {
-			p = p[:n]
-		}
-		return nil, fmt.Errorf("%w: %q", ErrInvalidVerbatimString, string(p))
+		return nil, fmt.Errorf("%w: %q", ErrInvalidVerbatimString, verbatimPreview(bs))
 	}
 	return b, nil
 }
 
-func (rr *Reader) discardAggregate(t Type, nested bool) error {
-	n, chunked, err := rr.readAggregateHeader(t)
-	if !nested || err != nil {
-		return err
+// verbatimPreviewLength is the maximum number of bytes of an invalid verbatim string included in error messages.
+const verbatimPreviewLength = 16
+
+// verbatimPreview returns b as string, truncated to at most verbatimPreviewLength bytes. If b is truncated, the cut is
+// moved back to the start of a UTF-8 sequence, so that no multi-byte character is split.
+func verbatimPreview(b []byte) string {
+	if len(b) <= verbatimPreviewLength {
+		return string(b)
 	}
-	if chunked {
-		return rr.discardAggregateChunks()
+	n := verbatimPreviewLength
+	for i := 1; i < utf8.UTFMax && !utf8.RuneStart(b[n]); i++ {
+		n--
 	}
-	if t == TypeAttribute || t == TypeMap {
-		n *= 2
+	return string(b[:n]) + "..."
+}
+
+// ReadVerbatimStringFormat reads a verbatim string and returns its format and content.
+//
+// If one or more allowed formats are given and the format of the verbatim string is not one of them, the verbatim
+// string is consumed and an error wrapping ErrUnknownVerbatimFormat is returned.
+//
+// If the next type in the response is not verbatim string, ErrUnexpectedType is returned.
+func (rr *Reader) ReadVerbatimStringFormat(allowed ...string) (VerbatimString, error) {
+	var buf [64]byte
+	b, err := rr.ReadVerbatimString(buf[:0])
+	if err != nil {
+		return VerbatimString{}, err
+	}
+	format := b[:verbatimPrefixLength]
+	if len(allowed) > 0 {
+		found := false
+		for _, f := range allowed {
+			if f == string(format) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return VerbatimString{}, fmt.Errorf("%w: %q", ErrUnknownVerbatimFormat, format)
+		}
+	}
+	return VerbatimString{Format: string(format), Text: string(b[verbatimPrefixLength+1:])}, nil
+}
+
+// Resync tries to find the start of the next value after the Reader got out of sync with the stream, for example
+// after an error in the middle of a reply.
+//
+// Resync discards lines until it finds a line that starts with a known type byte, including types registered using
+// RegisterType. This line is not consumed, so that the next value can be read as usual. If the Reader is already
+// positioned at a known type byte, nothing is discarded. If the input ends at the end of a discarded line, io.EOF is
+// returned.
+//
+// Unlike SkipLine, Resync ends a discarded line at every \n, even if AllowBareLF is false, so that a value following
+// a line with a bare \n is not lost.
+//
+// Resync is a recovery heuristic and not a guarantee, as RESP is not self-synchronizing. The line found may for
+// example be part of a blob or an element of an aggregate whose header was discarded, instead of the start of a new
+// top-level value. Callers should be prepared for further errors and close the connection if they occur.
+func (rr *Reader) Resync() error {
+	for {
+		_, err := rr.peek()
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrInvalidType) {
+			return err
+		}
+		for {
+			_, err := rr.br.ReadSlice('\n')
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, bufio.ErrBufferFull) {
+				return wrapEOF(err, "\\n")
+			}
+		}
 	}
-	return rr.discardN(n)
 }
 
-func (rr *Reader) discardAggregateChunks() error {
+// SkipLine reads and discards all bytes up to and including the next \r\n, regardless of the content of the line.
+//
+// This can be used to skip values with types unknown to the Reader. If AllowBareLF is true, SkipLine also stops
+// after a single \n.
+func (rr *Reader) SkipLine() error {
+	var last byte
 	for {
-		t, err := rr.Discard(true)
-		if t == TypeEnd || err != nil {
+		line, err := rr.br.ReadSlice('\n')
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			return wrapEOF(err, "\\r\\n")
+		}
+		if n := len(line); line[n-1] == '\n' {
+			if rr.AllowBareLF || (n >= 2 && line[n-2] == '\r') || (n == 1 && last == '\r') {
+				return nil
+			}
+		}
+		last = line[len(line)-1]
+	}
+}
+
+// DiscardToDepth discards the next value like Discard with nested set to true, but does not read aggregates nested
+// deeper than maxDepth, with the top-level value having a depth of 0.
+//
+// Values are discarded in order until the first aggregate with a depth greater than maxDepth is found. This aggregate
+// is left in the stream unread and its type is returned. Note that in this case all values following the aggregate
+// that belong to the aggregates containing it are left in the stream too and must be read by the caller. If there is
+// no such aggregate, the whole value is discarded and TypeInvalid is returned.
+//
+// For example, given the array [1, [2, [3]], 4] and a maxDepth of 1, DiscardToDepth discards the outer array header,
+// 1 and the header of [2, [3]] as well as 2 and returns TypeArray, leaving [3] and 4 in the stream.
+//
+// Attributes are handled like maps. Chunked blobs are always discarded completely.
+func (rr *Reader) DiscardToDepth(maxDepth int) (Type, error) {
+	rr.beginReply()
+	defer rr.endReply()
+	return rr.discardToDepth(0, maxDepth)
+}
+
+func (rr *Reader) discardToDepth(depth, maxDepth int) (Type, error) {
+	t, err := rr.Peek()
+	if err != nil {
+		return TypeInvalid, wrapEOF(err, "")
+	}
+	switch t {
+	case TypeArray, TypeAttribute, TypeMap, TypePush, TypeSet:
+	default:
+		_, err := rr.Discard(true)
+		return TypeInvalid, err
+	}
+	if depth > maxDepth {
+		return t, nil
+	}
+	n, chunked, err := rr.readAggregateHeader(t)
+	if err != nil {
+		return TypeInvalid, err
+	}
+	nodes := int64(-1)
+	if !chunked {
+		if t == TypeAttribute || t == TypeMap {
+			if n > math.MaxInt64/2 {
+				return TypeInvalid, fmt.Errorf("%w: %q with %d entries is too long", ErrInvalidAggregateTypeLength,
+					t, n)
+			}
+			n *= 2
+		}
+		nodes = n
+	}
+	prev, err := rr.enterAggregate(t, nodes)
+	if err != nil {
+		return TypeInvalid, err
+	}
+	defer rr.leaveAggregate(prev)
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil {
+				return TypeInvalid, err
+			} else if !more {
+				break
+			}
+		}
+		if left, err := rr.discardToDepth(depth+1, maxDepth); err != nil || left != TypeInvalid {
+			return left, err
+		}
+	}
+	return TypeInvalid, nil
+}
+
+func (rr *Reader) discardAggregate(t Type, nested bool) error {
+	if !nested {
+		_, _, err := rr.readAggregateHeader(t)
+		return err
+	}
+	return rr.walkAggregate(t, nil, func() error {
+		_, err := rr.Discard(true)
+		return err
+	})
+}
+
+// walkAggregate reads the header of the next aggregate, which must be of type t, and calls elem once for each nested
+// value, with keys and values of maps and attributes counting as separate values. elem must read exactly one value.
+//
+// If header is not nil, it is called after reading the header with the number of entries of the aggregate, or -1 if
+// the aggregate is streamed. The end marker of streamed aggregates is consumed by walkAggregate.
+//
+// This is the traversal used by Discard and Fdump and applies MaxChunks, MaxReplyDepth and MaxReplyNodes.
+func (rr *Reader) walkAggregate(t Type, header func(n int64) error, elem func() error) error {
+	n, chunked, err := rr.readAggregateHeader(t)
+	if err != nil {
+		return err
+	}
+	if header != nil {
+		if err := header(n); err != nil {
 			return err
 		}
 	}
+	perEntry := int64(1)
+	if t == TypeAttribute || t == TypeMap {
+		perEntry = 2
+	}
+	nodes := int64(-1)
+	if !chunked {
+		if n > math.MaxInt64/perEntry {
+			return fmt.Errorf("%w: %q with %d entries is too long", ErrInvalidAggregateTypeLength, t, n)
+		}
+		nodes = n * perEntry
+	}
+	prev, err := rr.enterAggregate(t, nodes)
+	if err != nil {
+		return err
+	}
+	defer rr.leaveAggregate(prev)
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil || !more {
+				return err
+			}
+		}
+		for j := int64(0); j < perEntry; j++ {
+			if err := elem(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func (rr *Reader) discardBlob(t Type, nested bool) error {
-	if rr.consume([]byte{byte(t), '?', '\r', '\n'}) {
+	if rr.consumeLine([]byte{byte(t), '?'}) {
 		if !nested {
 			return nil
 		}
@@ -565,17 +2294,31 @@ func (rr *Reader) discardN(n int64) error {
 }
 
 func (rr *Reader) discardSimple(t Type) error {
-	var buf [64]byte
-	_, err := rr.readSimple(t, buf[:0])
+	_, err := rr.readSimple(t, rr.scratchBuf(64))
 	return err
 }
 
 // Discard reads and discards the next value, returning its type.
 //
 // If nested is true and the next value is either an aggregate type or a chunked blob, the following values belonging
-// to the aggregate or blob will be discarded too.
+// to the aggregate or blob will be discarded too. In this case the discarded values count towards MaxReplyBytes.
+//
+// Values of types registered using RegisterType are discarded by calling the registered function. If
+// AllowUnknownTypes is true, values with unknown types are discarded using SkipLine and TypeInvalid is returned.
 func (rr *Reader) Discard(nested bool) (Type, error) {
+	if nested {
+		rr.beginReply()
+		defer rr.endReply()
+	}
+
 	t, err := rr.Peek()
+	if err != nil && rr.AllowUnknownTypes && errors.Is(err, ErrInvalidType) {
+		if b, _ := rr.br.Peek(1); len(b) > 0 {
+			rr.countType(b[0])
+		}
+		rr.lastChunked = false
+		return TypeInvalid, rr.SkipLine()
+	}
 	if err != nil {
 		return TypeInvalid, err
 	}
@@ -608,6 +2351,10 @@ func (rr *Reader) Discard(nested bool) (Type, error) {
 		err = rr.ReadNull()
 	case TypeVerbatimString:
 		_, err = rr.ReadVerbatimString(nil)
+	default:
+		rr.countType(byte(t))
+		rr.lastChunked = false
+		err = rr.customTypes[byte(t)](rr)
 	}
 
 	if err != nil {
@@ -615,3 +2362,240 @@ func (rr *Reader) Discard(nested bool) (Type, error) {
 	}
 	return t, nil
 }
+
+func (rr *Reader) readAggregateValues(t Type) ([]interface{}, error) {
+	n, chunked, err := rr.readAggregateHeader(t)
+	if err != nil {
+		return nil, err
+	}
+	nodes := n
+	if chunked {
+		nodes = -1
+	}
+	prev, err := rr.enterAggregate(t, nodes)
+	if err != nil {
+		return nil, err
+	}
+	defer rr.leaveAggregate(prev)
+	if chunked {
+		vs := []interface{}{}
+		for i := int64(0); ; i++ {
+			if more, err := rr.moreChunks(i); err != nil || !more {
+				return vs, err
+			}
+			v, err := rr.ReadValue()
+			if err != nil {
+				return nil, err
+			}
+			vs = append(vs, v)
+		}
+	}
+	vs := make([]interface{}, 0, preallocSize(n))
+	for i := int64(0); i < n; i++ {
+		v, err := rr.ReadValue()
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+func (rr *Reader) readMapValue() (map[interface{}]interface{}, error) {
+	n, chunked, err := rr.readAggregateHeader(TypeMap)
+	if err != nil {
+		return nil, err
+	}
+	nodes := int64(-1)
+	if !chunked {
+		nodes = math.MaxInt64
+		if n <= math.MaxInt64/2 {
+			nodes = n * 2
+		}
+	}
+	prev, err := rr.enterAggregate(TypeMap, nodes)
+	if err != nil {
+		return nil, err
+	}
+	defer rr.leaveAggregate(prev)
+	m := make(map[interface{}]interface{}, preallocSize(n))
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil {
+				return nil, err
+			} else if !more {
+				break
+			}
+		}
+		k, err := rr.ReadValue()
+		if err != nil {
+			return nil, err
+		}
+		switch k.(type) {
+		case []interface{}, map[interface{}]interface{}:
+			return nil, fmt.Errorf("%w: aggregate types can not be used as map keys", ErrUnexpectedType)
+		}
+		if m[k], err = rr.ReadValue(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (rr *Reader) readStringValue(t Type) (string, error) {
+	var buf [64]byte
+	b, chunked, err := rr.readChunkableBlob(t, buf[:0])
+	if err == nil && chunked {
+		b, err = rr.ReadBlobChunks(b)
+	}
+	return string(b), err
+}
+
+// ReadValue reads the next value, including all nested values, and returns it as one of the following Go types:
+//
+//	TypeArray, TypePush, TypeSet        []interface{}
+//	TypeBigNumber                       *big.Int
+//	TypeBlobError, TypeSimpleError      *RedisError
+//	TypeBlobString, TypeSimpleString    string
+//	TypeBoolean                         bool
+//	TypeDouble                          float64
+//	TypeMap                             map[interface{}]interface{}
+//	TypeNull                            nil
+//	TypeNumber                          int64
+//	TypeVerbatimString                  string (without the format prefix)
+//
+// Chunked blobs are combined into a single string and streamed aggregates are read until the end marker.
+//
+// Attributes preceding a value are discarded.
+//
+// If the next type is a blob chunk or an end marker, ErrUnexpectedType is returned.
+//
+// If MaxReplyBytes is set and the size of all blob and simple values in the value exceeds the limit, an error wrapping
+// ErrReplyTooLarge is returned.
+func (rr *Reader) ReadValue() (interface{}, error) {
+	rr.beginReply()
+	defer rr.endReply()
+
+	t, err := rr.Peek()
+	// attributes are discarded in a loop instead of recursively, so that a long sequence of attributes can not
+	// exhaust the stack
+	for err == nil && t == TypeAttribute {
+		if _, err := rr.Discard(true); err != nil {
+			return nil, err
+		}
+		t, err = rr.Peek()
+	}
+	if err != nil {
+		return nil, wrapEOF(err, "")
+	}
+
+	var v interface{}
+
+	switch t {
+	case TypeArray, TypePush, TypeSet:
+		v, err = rr.readAggregateValues(t)
+	case TypeBigNumber:
+		n := new(big.Int)
+		v, err = n, rr.ReadBigNumber(n)
+	case TypeBlobError, TypeSimpleError:
+		v, err = rr.ReadErrorReply()
+	case TypeBlobString:
+		v, err = rr.readStringValue(t)
+	case TypeBoolean:
+		v, err = rr.ReadBoolean()
+	case TypeDouble:
+		v, err = rr.ReadDouble()
+	case TypeMap:
+		v, err = rr.readMapValue()
+	case TypeNull:
+		err = rr.ReadNull()
+	case TypeNumber:
+		v, err = rr.ReadNumber()
+	case TypeSimpleString:
+		var buf [64]byte
+		var b []byte
+		b, err = rr.ReadSimpleString(buf[:0])
+		v = string(b)
+	case TypeVerbatimString:
+		var buf [64]byte
+		var b []byte
+		if b, err = rr.ReadVerbatimString(buf[:0]); err == nil {
+			v = string(b[verbatimPrefixLength+1:])
+		}
+	default:
+		err = fmt.Errorf("%w: got %q", ErrUnexpectedType, t)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ReadReply reads the next value like ReadValue, but returns errors sent by the server as error.
+//
+// If the next value is a simple error or a blob error, a *RedisError is returned as error. Errors nested inside
+// aggregates are returned as values, like ReadValue does.
+func (rr *Reader) ReadReply() (interface{}, error) {
+	v, err := rr.ReadValue()
+	if err != nil {
+		return nil, err
+	}
+	if re, ok := v.(*RedisError); ok {
+		return nil, re
+	}
+	return v, nil
+}
+
+// ReadReplies reads n replies using ReadReply, for example the replies to n pipelined commands, and returns them as
+// slice.
+//
+// Errors sent by the server do not stop reading, so that all n replies are consumed even if some commands failed. The
+// value of a failed reply is nil and the first *RedisError is returned together with the slice. Any other error stops
+// reading and is returned with a nil slice.
+//
+// If n is negative, an error wrapping ErrOutOfRange is returned. If n exceeds MaxReplyNodes, or DefaultMaxValueCount
+// if MaxReplyNodes is <= 0, an error wrapping ErrAggregateTooLong is returned. In both cases nothing is read.
+func (rr *Reader) ReadReplies(n int) ([]interface{}, error) {
+	if err := rr.checkValueCount(n); err != nil {
+		return nil, err
+	}
+	vs := make([]interface{}, 0, preallocSize(int64(n)))
+	err := rr.ForEachReply(n, func(_ int, v interface{}, _ error) {
+		vs = append(vs, v)
+	})
+	var re *RedisError
+	if err != nil && !errors.As(err, &re) {
+		return nil, err
+	}
+	return vs, err
+}
+
+// ForEachReply reads n replies using ReadReply, for example the replies to n pipelined commands, and calls fn with the
+// index and the result of ReadReply for each reply.
+//
+// Errors sent by the server are passed as *RedisError to fn and do not stop reading, so that all n replies are
+// consumed even if some commands failed. The first *RedisError is returned after all replies were read. Any other
+// error is passed to fn, after which ForEachReply stops and returns the error.
+//
+// If n is negative, an error wrapping ErrOutOfRange is returned. If n exceeds MaxReplyNodes, or DefaultMaxValueCount
+// if MaxReplyNodes is <= 0, an error wrapping ErrAggregateTooLong is returned. In both cases nothing is read and fn is
+// not called.
+func (rr *Reader) ForEachReply(n int, fn func(i int, v interface{}, err error)) error {
+	if err := rr.checkValueCount(n); err != nil {
+		return err
+	}
+	var firstErr error
+	for i := 0; i < n; i++ {
+		v, err := rr.ReadReply()
+		fn(i, v, err)
+		var re *RedisError
+		if err != nil && !errors.As(err, &re) {
+			return err
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -3,11 +3,14 @@ package resp3_test
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"io/ioutil"
 	"math"
 	"math/big"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -35,6 +38,15 @@ func TestReaderReset(t *testing.T) {
 	rr.Reset(strings.NewReader(".\r\n"))
 	assertError(t, nil, rr.ReadEnd())
 	assertError(t, resp3.ErrUnexpectedEOL, rr.ReadEnd())
+	rr.Reset(nil)
+	assertError(t, resp3.ErrClosed, rr.ReadEnd())
+	assertError(t, resp3.ErrClosed, rr.ReadEnd())
+	rr.Reset(strings.NewReader(".\r\n"))
+	assertError(t, nil, rr.ReadEnd())
+
+	rr = resp3.NewReader(nil)
+	_, err := rr.Peek()
+	assertError(t, resp3.ErrClosed, err)
 }
 
 func TestReaderPeek(t *testing.T) {
@@ -104,25 +116,84 @@ func BenchmarkReaderPeek(b *testing.B) {
 	b.Run("Valid", benchmarkPeek("_\r\n"))
 }
 
+func TestReaderPeekBlobString(t *testing.T) {
+	runBlobReadTest(t, resp3.TypeBlobString, func(rr *resp3.Reader, b []byte) ([]byte, bool, error) {
+		s, err := rr.PeekBlobString()
+		if err != nil {
+			return nil, false, err
+		}
+		return append(b, s...), false, nil
+	})
+
+	for _, c := range []struct {
+		in     string
+		bareLF bool
+		s      string
+		err    error
+		rest   string
+	}{
+		{in: "$5\r\nhello\r\n:1\r\n", s: "hello", rest: ":1\r\n"},
+		{in: "$5\r\nhello\n:1\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "$5\r\nhello\n:1\r\n", bareLF: true, s: "hello", rest: ":1\r\n"},
+		{in: "$5\r\nhello\n", bareLF: true, s: "hello"},
+		{in: "$?\r\n;5\r\nhello\r\n;6\r\n world\r\n;0\r\n:1\r\n", s: "hello world", rest: ":1\r\n"},
+		{in: "$?\r\n;5\r\nhello\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "$5000\r\n" + strings.Repeat("a", 5000) + "\r\n:1\r\n", s: strings.Repeat("a", 5000), rest: ":1\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		rr.AllowBareLF = c.bareLF
+		s, err := rr.PeekBlobString()
+		assertError(t, c.err, err)
+		if string(s) != c.s {
+			t.Errorf("got %q, expected %q", s, c.s)
+		}
+		if c.err == nil {
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
+		}
+	}
+}
+
 func TestReaderRead(t *testing.T) {
 	t.Run("Array", testReadArray)
 	t.Run("Attribute", testReadAttribute)
+	t.Run("AttributeMap", testReadAttributeMap)
+	t.Run("AttributeStringString", testReadAttributeStringString)
 	t.Run("BigNumber", testReadBigNumber)
+	t.Run("BigNumberBytes", testReadBigNumberBytes)
 	t.Run("Boolean", testReadBoolean)
 	t.Run("Double", testReadDouble)
+	t.Run("DoubleBytes", testReadDoubleBytes)
+	t.Run("BlobBody", testReadBlobBody)
 	t.Run("BlobChunk", testReadBlobChunk)
 	t.Run("BlobChunks", testReadBlobChunks)
 	t.Run("BlobError", testReadBlobError)
 	t.Run("BlobString", testReadBlobString)
+	t.Run("BlobStringCtx", testReadBlobStringCtx)
+	t.Run("BlobStringHeader", testReadBlobStringHeader)
+	t.Run("BlobStringN", testReadBlobStringN)
 	t.Run("End", testReadEnd)
+	t.Run("ErrorReply", testReadErrorReply)
+	t.Run("Float32", testReadFloat32)
+	t.Run("Int", testReadInt)
+	t.Run("Int32", testReadInt32)
+	t.Run("IntegerRange", testReadIntegerRange)
 	t.Run("Map", testReadMap)
+	t.Run("MapEntry", testReadMapEntry)
+	t.Run("MapEntryStrings", testReadMapEntryStrings)
+	t.Run("MapStringAny", testReadMapStringAny)
 	t.Run("Null", testReadNull)
 	t.Run("Number", testReadNumber)
+	t.Run("Numeric", testReadNumeric)
 	t.Run("Push", testReadPush)
 	t.Run("Set", testReadSet)
+	t.Run("SetStringSet", testReadSetStringSet)
 	t.Run("SimpleError", testReadSimpleError)
 	t.Run("SimpleString", testReadSimpleString)
+	t.Run("StreamedArray", testReadStreamedArray)
 	t.Run("VerbatimString", testReadVerbatimString)
+	t.Run("VerbatimStringFormat", testReadVerbatimStringFormat)
 }
 
 func newTestReader(s string) (rr *resp3.Reader, reset func(string)) {
@@ -135,6 +206,15 @@ func newTestReader(s string) (rr *resp3.Reader, reset func(string)) {
 	}
 }
 
+func newTestReaderWithRest(s string) (rr *resp3.Reader, rest func() string) {
+	br := bufio.NewReader(strings.NewReader(s))
+	rr = resp3.NewReader(br)
+	return rr, func() string {
+		b, _ := ioutil.ReadAll(br)
+		return string(b)
+	}
+}
+
 func newTypePrefixFunc(ty resp3.Type) func(string) string {
 	return func(s string) string {
 		return string(ty) + s
@@ -187,6 +267,10 @@ func runAggregateReadTest(t *testing.T, ty resp3.Type, readHeader func(*resp3.Re
 
 func runBlobReadTest(t *testing.T, ty resp3.Type, readBlob func(*resp3.Reader, []byte) ([]byte, bool, error)) {
 	p := newTypePrefixFunc(ty)
+	errInvalidLength := resp3.ErrInvalidBlobLength
+	if ty == resp3.TypeBlobChunk {
+		errInvalidLength = resp3.ErrInvalidBlobChunkLength
+	}
 	for _, c := range []struct {
 		in    string
 		limit int
@@ -205,8 +289,8 @@ func runBlobReadTest(t *testing.T, ty resp3.Type, readBlob func(*resp3.Reader, [
 		{in: p("\r"), err: resp3.ErrUnexpectedEOL},
 		{in: p("\r\n"), err: resp3.ErrUnexpectedEOL},
 
-		{in: p("-2\r\n"), err: resp3.ErrInvalidBlobLength},
-		{in: p("-1\r\n"), err: resp3.ErrInvalidBlobLength},
+		{in: p("-2\r\n"), err: errInvalidLength},
+		{in: p("-1\r\n"), err: errInvalidLength},
 
 		{in: p("\r\nhello\r\n"), err: resp3.ErrUnexpectedEOL},
 
@@ -405,6 +489,67 @@ func testReadAttribute(t *testing.T) {
 	runAggregateReadTest(t, resp3.TypeAttribute, (*resp3.Reader).ReadAttributeHeader)
 }
 
+func testReadAttributeMap(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		m    map[string]interface{}
+		err  error
+		rest string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "%0\r\n", err: resp3.ErrUnexpectedType},
+		{in: "|0\r\n", m: map[string]interface{}{}},
+		{
+			in: "|2\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.5\r\n$3\r\nttl\r\n:10\r\n+OK\r\n",
+			m: map[string]interface{}{
+				"key-popularity": map[interface{}]interface{}{"a": 0.5},
+				"ttl":            int64(10),
+			},
+			rest: "+OK\r\n",
+		},
+		{in: "|?\r\n+a\r\n:1\r\n.\r\n", m: map[string]interface{}{"a": int64(1)}},
+		{in: "|?\r\n+a\r\n:1\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "|1\r\n:1\r\n:1\r\n", err: resp3.ErrUnexpectedType},
+		{in: "|1\r\n+a\r\n", err: resp3.ErrUnexpectedEOL},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		m, err := rr.ReadAttribute()
+		assertError(t, c.err, err)
+		if !reflect.DeepEqual(m, c.m) {
+			t.Errorf("got %#v, expected %#v", m, c.m)
+		}
+		if c.err == nil {
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
+		}
+	}
+}
+
+func testReadAttributeStringString(t *testing.T) {
+	for _, c := range []struct {
+		in  string
+		dst map[string]string
+		m   map[string]string
+		err error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "%0\r\n", err: resp3.ErrUnexpectedType},
+		{in: "|0\r\n", m: map[string]string{}},
+		{in: "|1\r\n+a\r\n$1\r\nb\r\n", m: map[string]string{"a": "b"}},
+		{in: "|1\r\n+a\r\n$1\r\nb\r\n", dst: map[string]string{"c": "d"}, m: map[string]string{"a": "b", "c": "d"}},
+		{in: "|?\r\n+a\r\n+b\r\n.\r\n", m: map[string]string{"a": "b"}},
+		{in: "|1\r\n+a\r\n:1\r\n", err: resp3.ErrUnexpectedType},
+	} {
+		rr, _ := newTestReader(c.in)
+		m, err := rr.ReadAttributeStringString(c.dst)
+		assertError(t, c.err, err)
+		if !reflect.DeepEqual(m, c.m) {
+			t.Errorf("got %#v, expected %#v", m, c.m)
+		}
+	}
+}
+
 func testReadBigNumber(t *testing.T) {
 	newBigInt := func(s string) *big.Int {
 		n, _ := new(big.Int).SetString(s, 10)
@@ -440,6 +585,11 @@ func testReadBigNumber(t *testing.T) {
 		{in: p("+123456789123456789123456789123456789\r\n"),
 			n: newBigInt("123456789123456789123456789123456789")},
 		{in: p("+1\r\n"), n: big.NewInt(1)},
+		{in: p("-0\r\n"), n: big.NewInt(0)},
+		{in: p("9999999999999999999\r\n"), n: newBigInt("9999999999999999999")},
+		{in: p("-10000000000000000000\r\n"), n: newBigInt("-10000000000000000000")},
+		{in: p("12345678901234567890123456789012345678\r\n"), n: newBigInt("12345678901234567890123456789012345678")},
+		{in: p("-00000000000000000000000000000000000001\r\n"), n: big.NewInt(-1)},
 
 		{in: p("A\r\n"), err: resp3.ErrInvalidBigNumber},
 		{in: p("1a\r\n"), err: resp3.ErrInvalidBigNumber},
@@ -489,6 +639,10 @@ func testReadBoolean(t *testing.T) {
 
 		{in: p("f\r\n")},
 		{in: p("t\r\n"), b: true},
+		{in: p("f\r\n+OK\r\n")},
+		{in: p("t\r\n+OK\r\n"), b: true},
+		{in: p("tt\r\n"), err: resp3.ErrInvalidBoolean},
+		{in: p("t\r\r\n"), err: resp3.ErrInvalidBoolean},
 
 		{in: p("#\r\n"), err: resp3.ErrInvalidBoolean},
 		{in: p("A\r\n"), err: resp3.ErrInvalidBoolean},
@@ -505,6 +659,40 @@ func testReadBoolean(t *testing.T) {
 	}
 }
 
+func testReadBigNumberBytes(t *testing.T) {
+	p := newTypePrefixFunc(resp3.TypeBigNumber)
+	for _, c := range []struct {
+		in  string
+		dst string
+		b   string
+		err error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: string(resp3.TypeArray), err: resp3.ErrUnexpectedType},
+		{in: p("\r\n"), err: resp3.ErrUnexpectedEOL},
+		{in: p("1"), err: resp3.ErrUnexpectedEOL},
+
+		{in: p("0\r\n"), b: "0"},
+		{in: p("-10\r\n"), b: "-10"},
+		{in: p("+1\r\n"), b: "+1"},
+		{in: p("123456789123456789123456789123456789\r\n"), b: "123456789123456789123456789123456789"},
+		{in: p("42\r\n"), dst: "n=", b: "n=42"},
+
+		{in: p("A\r\n"), err: resp3.ErrInvalidBigNumber},
+		{in: p("1.0\r\n"), err: resp3.ErrInvalidBigNumber},
+		{in: p("-\r\n"), err: resp3.ErrInvalidBigNumber},
+		{in: p("+\r\n"), dst: "n=", err: resp3.ErrInvalidBigNumber},
+		{in: p("1_000\r\n"), err: resp3.ErrInvalidBigNumber},
+	} {
+		rr, _ := newTestReader(c.in)
+		b, err := rr.ReadBigNumberBytes([]byte(c.dst))
+		assertError(t, c.err, err)
+		if string(b) != c.b {
+			t.Errorf("got %q, expected %q", b, c.b)
+		}
+	}
+}
+
 func testReadDouble(t *testing.T) {
 	p := newTypePrefixFunc(resp3.TypeDouble)
 	for _, c := range []struct {
@@ -536,7 +724,8 @@ func testReadDouble(t *testing.T) {
 		{in: p("-1\r\n"), f: -1},
 		{in: p("-0.01\r\n"), f: -0.01},
 		{in: p("-0.1\r\n"), f: -0.1},
-		{in: p("-0.0\r\n")},
+		{in: p("-0.0\r\n"), f: math.Copysign(0, -1)},
+		{in: p("-0\r\n"), f: math.Copysign(0, -1)},
 		{in: p("0\r\n")},
 		{in: p("0.0\r\n")},
 		{in: p("0.01\r\n"), f: 0.01},
@@ -552,6 +741,7 @@ func testReadDouble(t *testing.T) {
 		{in: p("inf\r\n"), f: math.Inf(1)},
 		{in: p("+inf\r\n"), f: math.Inf(1)}, // not specified, but handled by ParseFloat
 		{in: p("-inf\r\n"), f: math.Inf(-1)},
+		{in: p("nan\r\n"), f: math.NaN()},
 
 		{in: p("A\r\n"), err: resp3.ErrInvalidDouble},
 		{in: p("1a\r\n"), err: resp3.ErrInvalidDouble},
@@ -562,12 +752,190 @@ func testReadDouble(t *testing.T) {
 		rr, _ := newTestReader(c.in)
 		f, err := rr.ReadDouble()
 		assertError(t, c.err, err)
-		if f != c.f {
+		if math.IsNaN(c.f) {
+			if !math.IsNaN(f) {
+				t.Errorf("got %f, expected NaN", f)
+			}
+		} else if f != c.f || math.Signbit(f) != math.Signbit(c.f) {
+			t.Errorf("got %f, expected %f", f, c.f)
+		}
+	}
+}
+
+func testReadDoubleBytes(t *testing.T) {
+	p := newTypePrefixFunc(resp3.TypeDouble)
+	for _, c := range []struct {
+		in  string
+		dst string
+		b   string
+		err error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: string(resp3.TypeArray), err: resp3.ErrUnexpectedType},
+		{in: p("\r\n"), err: resp3.ErrUnexpectedEOL},
+		{in: p("1"), err: resp3.ErrUnexpectedEOL},
+
+		{in: p("1\r\n"), b: "1"},
+		{in: p("1.0\r\n"), b: "1.0"},
+		{in: p("1.500\r\n"), b: "1.500"},
+		{in: p("-0.0\r\n"), b: "-0.0"},
+		{in: p("1e10\r\n"), b: "1e10"},
+		{in: p("inf\r\n"), b: "inf"},
+		{in: p("-inf\r\n"), b: "-inf"},
+		{in: p("nan\r\n"), b: "nan"},
+		{in: p("2.50\r\n"), dst: "f=", b: "f=2.50"},
+
+		{in: p("A\r\n"), err: resp3.ErrInvalidDouble},
+		{in: p("1a\r\n"), err: resp3.ErrInvalidDouble},
+		{in: p("1e400\r\n"), err: resp3.ErrInvalidDouble},
+	} {
+		rr, _ := newTestReader(c.in)
+		b, err := rr.ReadDoubleBytes([]byte(c.dst))
+		assertError(t, c.err, err)
+		if string(b) != c.b {
+			t.Errorf("got %q, expected %q", b, c.b)
+		}
+	}
+}
+
+func testReadFloat32(t *testing.T) {
+	p := newTypePrefixFunc(resp3.TypeDouble)
+	for _, c := range []struct {
+		in  string
+		f   float32
+		err error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: string(resp3.TypeArray), err: resp3.ErrUnexpectedType},
+		{in: p("1"), err: resp3.ErrUnexpectedEOL},
+
+		{in: p("-1.5\r\n"), f: -1.5},
+		{in: p("0\r\n")},
+		{in: p("1.2\r\n"), f: 1.2},
+		{in: p("16777217\r\n"), f: 16777216},
+
+		// rounding to float64 first and then to float32 would result in 1
+		{in: p("1.0000000596046448\r\n"), f: 1.0000001},
+
+		{in: p("inf\r\n"), f: float32(math.Inf(1))},
+		{in: p("-inf\r\n"), f: float32(math.Inf(-1))},
+		{in: p("nan\r\n"), f: float32(math.NaN())},
+
+		{in: p("1e39\r\n"), err: resp3.ErrInvalidDouble},
+		{in: p("A\r\n"), err: resp3.ErrInvalidDouble},
+	} {
+		rr, _ := newTestReader(c.in)
+		f, err := rr.ReadFloat32()
+		assertError(t, c.err, err)
+		if math.IsNaN(float64(c.f)) {
+			if !math.IsNaN(float64(f)) {
+				t.Errorf("got %f, expected NaN", f)
+			}
+		} else if f != c.f {
 			t.Errorf("got %f, expected %f", f, c.f)
 		}
 	}
 }
 
+func testReadInt(t *testing.T) {
+	p := newTypePrefixFunc(resp3.TypeNumber)
+	for _, c := range []struct {
+		in  string
+		n   int
+		err error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: string(resp3.TypeArray), err: resp3.ErrUnexpectedType},
+		{in: p("A\r\n"), err: resp3.ErrInvalidNumber},
+		{in: p("9223372036854775808\r\n"), err: resp3.ErrOverflow},
+
+		{in: p("0\r\n")},
+		{in: p("-10\r\n"), n: -10},
+		{in: p("2147483647\r\n"), n: math.MaxInt32},
+		{in: p("-2147483648\r\n"), n: math.MinInt32},
+	} {
+		rr, _ := newTestReader(c.in)
+		n, err := rr.ReadInt()
+		assertError(t, c.err, err)
+		if n != c.n {
+			t.Errorf("got %d, expected %d", n, c.n)
+		}
+	}
+
+	if strconv.IntSize == 32 {
+		rr, rest := newTestReaderWithRest(":2147483648\r\n")
+		_, err := rr.ReadInt()
+		assertError(t, resp3.ErrOverflow, err)
+		if rest() != "" {
+			t.Errorf("expected value to be consumed")
+		}
+	}
+}
+
+func testReadInt32(t *testing.T) {
+	p := newTypePrefixFunc(resp3.TypeNumber)
+	for _, c := range []struct {
+		in  string
+		n   int32
+		err error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: string(resp3.TypeArray), err: resp3.ErrUnexpectedType},
+		{in: p("A\r\n"), err: resp3.ErrInvalidNumber},
+
+		{in: p("0\r\n")},
+		{in: p("-10\r\n"), n: -10},
+		{in: p("2147483647\r\n"), n: math.MaxInt32},
+		{in: p("-2147483648\r\n"), n: math.MinInt32},
+
+		{in: p("2147483648\r\n"), err: resp3.ErrOverflow},
+		{in: p("-2147483649\r\n"), err: resp3.ErrOverflow},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		n, err := rr.ReadInt32()
+		assertError(t, c.err, err)
+		if n != c.n {
+			t.Errorf("got %d, expected %d", n, c.n)
+		}
+		if errors.Is(err, resp3.ErrOverflow) && rest() != "" {
+			t.Errorf("expected value to be consumed")
+		}
+	}
+}
+
+func testReadIntegerRange(t *testing.T) {
+	p := newTypePrefixFunc(resp3.TypeNumber)
+	for _, c := range []struct {
+		in       string
+		min, max int64
+		n        int64
+		err      error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: string(resp3.TypeArray), err: resp3.ErrUnexpectedType},
+		{in: p("A\r\n"), min: 1, max: 65535, err: resp3.ErrInvalidNumber},
+
+		{in: p("1\r\n"), min: 1, max: 65535, n: 1},
+		{in: p("6379\r\n"), min: 1, max: 65535, n: 6379},
+		{in: p("65535\r\n"), min: 1, max: 65535, n: 65535},
+		{in: p("-5\r\n"), min: -5, max: -5, n: -5},
+
+		{in: p("0\r\n"), min: 1, max: 65535, err: resp3.ErrOutOfRange},
+		{in: p("65536\r\n"), min: 1, max: 65535, err: resp3.ErrOutOfRange},
+		{in: p("-1\r\n"), min: 0, max: math.MaxInt64, err: resp3.ErrOutOfRange},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		n, err := rr.ReadIntegerRange(c.min, c.max)
+		assertError(t, c.err, err)
+		if n != c.n {
+			t.Errorf("got %d, expected %d", n, c.n)
+		}
+		if errors.Is(err, resp3.ErrOutOfRange) && rest() != "" {
+			t.Errorf("expected value to be consumed")
+		}
+	}
+}
+
 func testReadBlobChunk(t *testing.T) {
 	runBlobReadTest(t, resp3.TypeBlobChunk, (*resp3.Reader).ReadBlobChunk)
 
@@ -583,6 +951,24 @@ func testReadBlobChunk(t *testing.T) {
 			t.Errorf("got last=%v, expected last=%v", last, true)
 		}
 	}
+	{
+		rr, _ := newTestReader(p("-1\r\n"))
+		_, last, err := rr.ReadBlobChunk(nil)
+		assertError(t, resp3.ErrInvalidBlobChunkLength, err)
+		assertError(t, resp3.ErrInvalidBlobLength, err)
+		if last {
+			t.Errorf("got last=%v, expected last=%v", last, false)
+		}
+		if err != nil && !strings.Contains(err.Error(), "blob chunk length must be >= 0") {
+			t.Errorf("got error %q, expected chunk specific error", err)
+		}
+	}
+	{
+		rr, _ := newTestReader("$?\r\n;-1\r\n")
+		_, err := rr.ReadValue()
+		assertError(t, resp3.ErrInvalidBlobChunkLength, err)
+		assertError(t, resp3.ErrInvalidBlobLength, err)
+	}
 }
 
 func testReadBlobChunks(t *testing.T) {
@@ -604,8 +990,8 @@ func testReadBlobChunks(t *testing.T) {
 		{in: p("\r"), err: resp3.ErrUnexpectedEOL},
 		{in: p("\r\n"), err: resp3.ErrUnexpectedEOL},
 
-		{in: p("-2\r\n"), err: resp3.ErrInvalidBlobLength},
-		{in: p("-1\r\n"), err: resp3.ErrInvalidBlobLength},
+		{in: p("-2\r\n"), err: resp3.ErrInvalidBlobChunkLength},
+		{in: p("-1\r\n"), err: resp3.ErrInvalidBlobChunkLength},
 
 		{in: p("\r\nhello\r\n"), err: resp3.ErrUnexpectedEOL},
 
@@ -646,83 +1032,475 @@ func testReadBlobString(t *testing.T) {
 	runStreamableBlobReadTest(t, resp3.TypeBlobString, (*resp3.Reader).ReadBlobString)
 }
 
-func testReadEnd(t *testing.T) {
-	runEmptyReadTest(t, resp3.TypeEnd, (*resp3.Reader).ReadEnd)
-}
+func testReadBlobStringCtx(t *testing.T) {
+	runStreamableBlobReadTest(t, resp3.TypeBlobString, func(rr *resp3.Reader, b []byte) ([]byte, bool, error) {
+		return rr.ReadBlobStringCtx(context.Background(), b)
+	})
 
-func testReadMap(t *testing.T) {
-	runAggregateReadTest(t, resp3.TypeMap, (*resp3.Reader).ReadMapHeader)
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		rr, rest := newTestReaderWithRest("$5\r\nhello\r\n")
+		_, _, err := rr.ReadBlobStringCtx(ctx, nil)
+		assertError(t, context.Canceled, err)
+		if got := rest(); got != "$5\r\nhello\r\n" {
+			t.Errorf("got %q left in input, expected input to be unchanged", got)
+		}
+	})
+
+	t.Run("CanceledWhileReading", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		const size = 64 * 1024
+		in := "$" + strconv.Itoa(size) + "\r\n" + strings.Repeat("a", size) + "\r\n"
+		r := &cancelReader{r: strings.NewReader(in), cancel: cancel, after: 2}
+
+		rr := resp3.NewReader(r)
+		_, _, err := rr.ReadBlobStringCtx(ctx, nil)
+		assertError(t, context.Canceled, err)
+		if r.reads >= size/4096 {
+			t.Errorf("got %d reads, expected reading to stop after cancellation", r.reads)
+		}
+	})
 }
 
-func testReadNull(t *testing.T) {
-	runEmptyReadTest(t, resp3.TypeNull, (*resp3.Reader).ReadNull)
+// cancelReader calls cancel after the given number of reads.
+type cancelReader struct {
+	r      io.Reader
+	cancel func()
+	after  int
+	reads  int
+}
 
-	// RESP2 backward compatibility
-	for _, in := range []string{
-		string(resp3.TypeArray) + "-1\r\n",
-		string(resp3.TypeBlobString) + "-1\r\n",
-	} {
-		rr, _ := newTestReader(in)
-		assertError(t, nil, rr.ReadNull())
+func (c *cancelReader) Read(b []byte) (int, error) {
+	if c.reads++; c.reads == c.after {
+		c.cancel()
 	}
+	return c.r.Read(b)
 }
 
-func testReadNumber(t *testing.T) {
-	p := newTypePrefixFunc(resp3.TypeNumber)
+func testReadBlobBody(t *testing.T) {
 	for _, c := range []struct {
-		in  string
-		n   int64
-		err error
+		in    string
+		n     int64
+		limit int
+		s     string
+		err   error
+		rest  string
 	}{
-		{err: resp3.ErrUnexpectedEOL},
-
-		{in: "A", err: resp3.ErrInvalidType},
-		{in: string(resp3.TypeArray), err: resp3.ErrUnexpectedType},
-		{in: string(resp3.TypeInvalid), err: resp3.ErrInvalidType},
-
-		{in: p(""), err: resp3.ErrUnexpectedEOL},
-		{in: p("\n"), err: resp3.ErrUnexpectedEOL},
-		{in: p("\n\r"), err: resp3.ErrUnexpectedEOL},
-		{in: p("\r"), err: resp3.ErrUnexpectedEOL},
-		{in: p("\r\n"), err: resp3.ErrUnexpectedEOL},
-
-		{in: p("-10\r\n"), n: -10},
-		{in: p("-1\r\n"), n: -1},
-		{in: p("0\r\n")},
-		{in: p("1\r\n"), n: 1},
-		{in: p("10\r\n"), n: 10},
-
-		{in: p("A\r\n"), err: resp3.ErrInvalidNumber},
-		{in: p("1a\r\n"), err: resp3.ErrInvalidNumber},
-		{in: p("1.\r\n"), err: resp3.ErrInvalidNumber},
-		{in: p("1.0\r\n"), err: resp3.ErrInvalidNumber},
-		{in: p("1.01\r\n"), err: resp3.ErrInvalidNumber},
-		{in: p("#\r\n"), err: resp3.ErrInvalidNumber},
-		{in: p("-\r\n"), err: resp3.ErrUnexpectedEOL},
-		{in: p("+\r\n"), err: resp3.ErrInvalidNumber},
-		{in: p("+1\r\n"), err: resp3.ErrInvalidNumber},
-
-		// Numbers are parsed as int64
-		{in: p("-184467440737095516151\r\n"), err: resp3.ErrOverflow},
-		{in: p("184467440737095516151\r\n"), err: resp3.ErrOverflow},
+		{n: 1, err: resp3.ErrUnexpectedEOL},
+		{in: "hello\r\n", n: -1, err: resp3.ErrInvalidBlobLength, rest: "hello\r\n"},
+		{in: "hello\r\n", n: 5, limit: 4, err: resp3.ErrSingleReadSizeLimitExceeded, rest: "hello\r\n"},
+		{in: "hel", n: 5, err: resp3.ErrUnexpectedEOL},
+		{in: "hello", n: 5, err: resp3.ErrUnexpectedEOL},
+		{in: "hello\n", n: 5, err: resp3.ErrUnexpectedEOL},
+		{in: "\r\n", n: 0, s: ""},
+		{in: "hello\r\n:1\r\n", n: 5, s: "hello", rest: ":1\r\n"},
+		{in: "hel\r\no\r\n", n: 6, s: "hel\r\no"},
 	} {
-		rr, _ := newTestReader(c.in)
-		n, err := rr.ReadNumber()
+		rr, rest := newTestReaderWithRest(c.in)
+		rr.SingleReadSizeLimit = c.limit
+		s, err := rr.ReadBlobBody([]byte("x"), c.n)
 		assertError(t, c.err, err)
-		if n != c.n {
-			t.Errorf("got %d, expected %d", n, c.n)
+		if c.err == nil && string(s) != "x"+c.s {
+			t.Errorf("got %q, expected %q", s, "x"+c.s)
+		}
+		if c.err == nil || c.rest != "" {
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
 		}
 	}
-}
 
-func testReadPush(t *testing.T) {
-	runAggregateReadTest(t, resp3.TypePush, (*resp3.Reader).ReadPushHeader)
+	rr, _ := newTestReader("$5\r\nhello\r\n")
+	n, _, err := rr.ReadBlobStringHeader()
+	assertError(t, nil, err)
+	s, err := rr.ReadBlobBody(nil, n)
+	assertError(t, nil, err)
+	assertBytes(t, "hello", s)
 }
 
-func testReadSet(t *testing.T) {
+func testReadBlobStringHeader(t *testing.T) {
+	for _, c := range []struct {
+		in      string
+		n       int64
+		chunked bool
+		err     error
+		rest    string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "+OK\r\n", err: resp3.ErrUnexpectedType},
+		{in: "!5\r\nhello\r\n", err: resp3.ErrUnexpectedType},
+		{in: "$-1\r\n", err: resp3.ErrInvalidBlobLength},
+		{in: "$a\r\n", err: resp3.ErrInvalidNumber},
+		{in: "$5", err: resp3.ErrUnexpectedEOL},
+		{in: "$0\r\n\r\n", n: 0, rest: "\r\n"},
+		{in: "$5\r\nhello\r\n:1\r\n", n: 5, rest: "hello\r\n:1\r\n"},
+		{in: "$?\r\n;5\r\nhello\r\n;0\r\n", n: -1, chunked: true, rest: ";5\r\nhello\r\n;0\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		n, chunked, err := rr.ReadBlobStringHeader()
+		assertError(t, c.err, err)
+		if n != c.n || chunked != c.chunked {
+			t.Errorf("got (%d, %t), expected (%d, %t)", n, chunked, c.n, c.chunked)
+		}
+		if c.err == nil {
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
+		}
+	}
+}
+
+func testReadBlobStringN(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		size int
+		s    string
+		err  error
+		rest string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "+OK\r\n", size: 8, err: resp3.ErrUnexpectedType},
+		{in: "$-1\r\n", size: 8, err: resp3.ErrInvalidBlobLength},
+		{in: "$0\r\n\r\n", size: 8, s: ""},
+		{in: "$5\r\nhello\r\n:1\r\n", size: 5, s: "hello", rest: ":1\r\n"},
+		{in: "$5\r\nhello\r\n:1\r\n", size: 8, s: "hello", rest: ":1\r\n"},
+		{in: "$5\r\nhello\r\n:1\r\n", size: 4, err: io.ErrShortBuffer, rest: ":1\r\n"},
+		{in: "$5\r\nhel", size: 8, err: resp3.ErrUnexpectedEOL},
+		{in: "$5\r\nhello", size: 8, err: resp3.ErrUnexpectedEOL},
+		{in: "$5\r\nhello\r\n", size: 8, s: "hello"},
+		{in: "$?\r\n;0\r\n", size: 8, err: resp3.ErrUnexpectedType, rest: "$?\r\n;0\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		dst := make([]byte, c.size)
+		n, err := rr.ReadBlobStringN(dst)
+		assertError(t, c.err, err)
+		if got := string(dst[:n]); got != c.s {
+			t.Errorf("got %q, expected %q", got, c.s)
+		}
+		if c.err == nil || c.rest != "" {
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
+		}
+	}
+}
+
+func testReadEnd(t *testing.T) {
+	runEmptyReadTest(t, resp3.TypeEnd, (*resp3.Reader).ReadEnd)
+}
+
+func testReadErrorReply(t *testing.T) {
+	for _, c := range []struct {
+		in      string
+		s       string
+		code    string
+		message string
+		err     error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: string(resp3.TypeSimpleString), err: resp3.ErrUnexpectedType},
+
+		{in: "-ERR", err: resp3.ErrUnexpectedEOL},
+		{in: "!5\r\nERR", err: resp3.ErrUnexpectedEOL},
+
+		{in: "-\r\n"},
+		{in: "-ERR\r\n", s: "ERR", code: "ERR"},
+		{in: "-ERR \r\n", s: "ERR ", code: "ERR"},
+		{in: "-ERR unknown command\r\n", s: "ERR unknown command", code: "ERR", message: "unknown command"},
+		{
+			in:      "-WRONGTYPE Operation against a key\r\n",
+			s:       "WRONGTYPE Operation against a key",
+			code:    "WRONGTYPE",
+			message: "Operation against a key",
+		},
+
+		{in: "!0\r\n\r\n"},
+		{in: "!3\r\nERR\r\n", s: "ERR", code: "ERR"},
+		{in: "!16\r\nSYNTAX inv\r\nalid\r\n", s: "SYNTAX inv\r\nalid", code: "SYNTAX", message: "inv\r\nalid"},
+		{in: "!?\r\n;4\r\nERR \r\n;5\r\nhello\r\n;0\r\n", s: "ERR hello", code: "ERR", message: "hello"},
+	} {
+		rr, _ := newTestReader(c.in)
+		re, err := rr.ReadErrorReply()
+		assertError(t, c.err, err)
+		if c.err != nil {
+			if re != nil {
+				t.Errorf("got %v, expected nil", re)
+			}
+			continue
+		}
+		if got := re.Error(); got != c.s {
+			t.Errorf("got error %q, expected %q", got, c.s)
+		}
+		if got := re.Code(); got != c.code {
+			t.Errorf("got code %q, expected %q", got, c.code)
+		}
+		if got := re.Message(); got != c.message {
+			t.Errorf("got message %q, expected %q", got, c.message)
+		}
+	}
+}
+
+func testReadMap(t *testing.T) {
+	runAggregateReadTest(t, resp3.TypeMap, (*resp3.Reader).ReadMapHeader)
+}
+
+func testReadMapEntry(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		s    string
+		err  error
+		rest string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: ":1\r\n", err: resp3.ErrUnexpectedType},
+
+		{in: "+key\r\n", s: "key"},
+		{in: "$3\r\nkey\r\n", s: "key"},
+		{in: "$?\r\n;1\r\nk\r\n;2\r\ney\r\n;0\r\n", s: "key"},
+		{in: "$3\r\nkey", err: resp3.ErrUnexpectedEOL},
+
+		{in: "+key\r\n:1\r\n", s: "key", rest: ":1\r\n"},
+	} {
+		withBuf := func(base []byte) {
+			rr, rest := newTestReaderWithRest(c.in)
+			buf, err := rr.ReadMapEntry(base)
+			assertReadResultEqual(t, append(base, c.s...), buf, c.err, err)
+			if c.err == nil {
+				if got := rest(); got != c.rest {
+					t.Errorf("got %q left in input, expected %q", got, c.rest)
+				}
+			}
+		}
+		withBuf(nil)
+		withBuf([]byte("existing data"))
+	}
+}
+
+func testReadMapEntryStrings(t *testing.T) {
+	for _, c := range []struct {
+		in  string
+		k   string
+		v   string
+		err error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+
+		{in: "+key\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "+key\r\n:1\r\n", err: resp3.ErrUnexpectedType},
+		{in: ":1\r\n+value\r\n", err: resp3.ErrUnexpectedType},
+
+		{in: "+key\r\n+value\r\n", k: "key", v: "value"},
+		{in: "$3\r\nkey\r\n$5\r\nvalue\r\n", k: "key", v: "value"},
+		{in: "+key\r\n$?\r\n;2\r\nva\r\n;3\r\nlue\r\n;0\r\n", k: "key", v: "value"},
+	} {
+		rr, _ := newTestReader(c.in)
+		k, v, err := rr.ReadMapEntryStrings()
+		assertError(t, c.err, err)
+		if k != c.k || v != c.v {
+			t.Errorf("got (%q, %q), expected (%q, %q)", k, v, c.k, c.v)
+		}
+	}
+
+	rr, _ := newTestReader("%2\r\n+a\r\n+1\r\n+b\r\n+2\r\n")
+	n, _, err := rr.ReadMapHeader()
+	assertError(t, nil, err)
+	var got []string
+	for ; n > 0; n-- {
+		k, v, err := rr.ReadMapEntryStrings()
+		assertError(t, nil, err)
+		got = append(got, k+"="+v)
+	}
+	if s := strings.Join(got, ","); s != "a=1,b=2" {
+		t.Errorf("got %q, expected %q", s, "a=1,b=2")
+	}
+}
+
+func testReadMapStringAny(t *testing.T) {
+	type m = map[string]interface{}
+
+	for _, c := range []struct {
+		in  string
+		dst m
+		m   m
+		err error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "*0\r\n", err: resp3.ErrUnexpectedType},
+		{in: "%0\r\n", m: m{}},
+		{
+			in: "%4\r\n+id\r\n:3\r\n$4\r\nname\r\n$3\r\nfoo\r\n+flags\r\n~1\r\n+N\r\n+db\r\n_\r\n",
+			m:  m{"id": int64(3), "name": "foo", "flags": []interface{}{"N"}, "db": nil},
+		},
+		{in: "%1\r\n+a\r\n%1\r\n+b\r\n#t\r\n", m: m{"a": map[interface{}]interface{}{"b": true}}},
+		{in: "%1\r\n+a\r\n:1\r\n", dst: m{"b": 2}, m: m{"a": int64(1), "b": 2}},
+		{in: "%?\r\n+a\r\n,1.5\r\n.\r\n", m: m{"a": 1.5}},
+		{in: "%1\r\n:1\r\n:1\r\n", err: resp3.ErrUnexpectedType},
+		{in: "%1\r\n+a\r\n", err: resp3.ErrUnexpectedEOL},
+	} {
+		rr, _ := newTestReader(c.in)
+		got, err := rr.ReadMapStringAny(c.dst)
+		assertError(t, c.err, err)
+		if !reflect.DeepEqual(got, c.m) {
+			t.Errorf("got %#v, expected %#v", got, c.m)
+		}
+	}
+}
+
+func testReadNull(t *testing.T) {
+	runEmptyReadTest(t, resp3.TypeNull, (*resp3.Reader).ReadNull)
+
+	// RESP2 backward compatibility
+	for _, in := range []string{
+		string(resp3.TypeArray) + "-1\r\n",
+		string(resp3.TypeBlobString) + "-1\r\n",
+	} {
+		rr, _ := newTestReader(in)
+		assertError(t, nil, rr.ReadNull())
+	}
+}
+
+func testReadNumeric(t *testing.T) {
+	for _, c := range []struct {
+		in        string
+		protocol  uint8
+		f         float64
+		isInteger bool
+		err       error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: "+1\r\n", err: resp3.ErrUnexpectedType},
+		{in: "$3\r\n1.5\r\n", err: resp3.ErrUnexpectedType},
+
+		{in: ":10\r\n", f: 10, isInteger: true},
+		{in: ":-1\r\n", f: -1, isInteger: true},
+		{in: ":0\r\n", isInteger: true},
+		{in: ":A\r\n", err: resp3.ErrInvalidNumber},
+
+		{in: ",10\r\n", f: 10},
+		{in: ",1.5\r\n", f: 1.5},
+		{in: ",-inf\r\n", f: math.Inf(-1)},
+		{in: ",A\r\n", err: resp3.ErrInvalidDouble},
+
+		{in: "$3\r\n1.5\r\n", protocol: 2, f: 1.5},
+		{in: ":10\r\n", protocol: 2, f: 10, isInteger: true},
+	} {
+		rr, _ := newTestReader(c.in)
+		rr.Protocol = c.protocol
+		f, isInteger, err := rr.ReadNumeric()
+		assertError(t, c.err, err)
+		if f != c.f || isInteger != c.isInteger {
+			t.Errorf("got (%v, %t) for %q, expected (%v, %t)", f, isInteger, c.in, c.f, c.isInteger)
+		}
+	}
+}
+
+func testReadNumber(t *testing.T) {
+	p := newTypePrefixFunc(resp3.TypeNumber)
+	for _, c := range []struct {
+		in  string
+		n   int64
+		err error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: string(resp3.TypeArray), err: resp3.ErrUnexpectedType},
+		{in: string(resp3.TypeInvalid), err: resp3.ErrInvalidType},
+
+		{in: p(""), err: resp3.ErrUnexpectedEOL},
+		{in: p("\n"), err: resp3.ErrUnexpectedEOL},
+		{in: p("\n\r"), err: resp3.ErrUnexpectedEOL},
+		{in: p("\r"), err: resp3.ErrUnexpectedEOL},
+		{in: p("\r\n"), err: resp3.ErrUnexpectedEOL},
+
+		{in: p("-10\r\n"), n: -10},
+		{in: p("-1\r\n"), n: -1},
+		{in: p("0\r\n")},
+		{in: p("1\r\n"), n: 1},
+		{in: p("10\r\n"), n: 10},
+
+		{in: p("A\r\n"), err: resp3.ErrInvalidNumber},
+		{in: p("1a\r\n"), err: resp3.ErrInvalidNumber},
+		{in: p("1.\r\n"), err: resp3.ErrInvalidNumber},
+		{in: p("1.0\r\n"), err: resp3.ErrInvalidNumber},
+		{in: p("1.01\r\n"), err: resp3.ErrInvalidNumber},
+		{in: p("#\r\n"), err: resp3.ErrInvalidNumber},
+		{in: p("-\r\n"), err: resp3.ErrUnexpectedEOL},
+		{in: p("+\r\n"), err: resp3.ErrInvalidNumber},
+		{in: p("+1\r\n"), err: resp3.ErrInvalidNumber},
+
+		// Numbers are parsed as int64
+		{in: p("-184467440737095516151\r\n"), err: resp3.ErrOverflow},
+		{in: p("184467440737095516151\r\n"), err: resp3.ErrOverflow},
+	} {
+		for _, read := range []func(*resp3.Reader) (int64, error){
+			(*resp3.Reader).ReadInteger,
+			(*resp3.Reader).ReadNumber,
+		} {
+			rr, _ := newTestReader(c.in)
+			n, err := read(rr)
+			assertError(t, c.err, err)
+			if n != c.n {
+				t.Errorf("got %d, expected %d", n, c.n)
+			}
+		}
+	}
+}
+
+func testReadPush(t *testing.T) {
+	runAggregateReadTest(t, resp3.TypePush, (*resp3.Reader).ReadPushHeader)
+}
+
+func testReadSet(t *testing.T) {
 	runAggregateReadTest(t, resp3.TypeSet, (*resp3.Reader).ReadSetHeader)
 }
 
+func testReadSetStringSet(t *testing.T) {
+	type set = map[string]struct{}
+
+	for _, c := range []struct {
+		in     string
+		strict bool
+		dst    set
+		m      set
+		err    error
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "*0\r\n", err: resp3.ErrUnexpectedType},
+		{in: "~0\r\n", m: set{}},
+		{in: "~2\r\n+a\r\n$1\r\nb\r\n", m: set{"a": {}, "b": {}}},
+		{in: "~1\r\n+a\r\n", dst: set{"c": {}}, m: set{"a": {}, "c": {}}},
+		{in: "~?\r\n+a\r\n$?\r\n;1\r\nb\r\n;0\r\n.\r\n", m: set{"a": {}, "b": {}}},
+		{in: "~2\r\n+a\r\n+a\r\n", m: set{"a": {}}},
+		{in: "~2\r\n+a\r\n+a\r\n", strict: true, err: resp3.ErrDuplicateSetMember},
+		{in: "~1\r\n+a\r\n", strict: true, dst: set{"a": {}}, m: set{"a": {}}},
+		{in: "~1\r\n:1\r\n", err: resp3.ErrUnexpectedType},
+		{in: "~1\r\n$5\r\nhello\r\n", strict: true, dst: set{}, m: set{"hello": {}}},
+	} {
+		rr, _ := newTestReader(c.in)
+		rr.StrictSets = c.strict
+		m, err := rr.ReadSetStringSet(c.dst)
+		assertError(t, c.err, err)
+		if !reflect.DeepEqual(m, c.m) {
+			t.Errorf("got %#v, expected %#v", m, c.m)
+		}
+	}
+
+	rr, _ := newTestReader("~1\r\n$5\r\nhello\r\n")
+	rr.SingleReadSizeLimit = 4
+	_, err := rr.ReadSetStringSet(nil)
+	assertError(t, resp3.ErrSingleReadSizeLimitExceeded, err)
+}
+
 func testReadSimpleError(t *testing.T) {
 	runSimpleReadTest(t, resp3.TypeSimpleError, (*resp3.Reader).ReadSimpleError)
 }
@@ -731,6 +1509,50 @@ func testReadSimpleString(t *testing.T) {
 	runSimpleReadTest(t, resp3.TypeSimpleString, (*resp3.Reader).ReadSimpleString)
 }
 
+func testReadStreamedArray(t *testing.T) {
+	errStop := errors.New("stop")
+
+	for _, c := range []struct {
+		in   string
+		stop int64
+		ns   []int64
+		err  error
+		rest string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "%0\r\n", err: resp3.ErrUnexpectedType},
+		{in: "*?\r\n.\r\n"},
+		{in: "*?\r\n:1\r\n:2\r\n.\r\n:3\r\n", ns: []int64{1, 2}, rest: ":3\r\n"},
+		{in: "*?\r\n:1\r\n:2\r\n", ns: []int64{1, 2}, err: resp3.ErrUnexpectedEOL},
+		{in: "*?\r\n:1\r\n+OK\r\n.\r\n", ns: []int64{1}, err: resp3.ErrUnexpectedType},
+		{in: "*?\r\n:1\r\n:2\r\n.\r\n", stop: 2, ns: []int64{1, 2}, err: errStop},
+		{in: "*0\r\n"},
+		{in: "*2\r\n:1\r\n:2\r\n:3\r\n", ns: []int64{1, 2}, rest: ":3\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		var ns []int64
+		err := rr.ReadStreamedArray(func(r *resp3.Reader) error {
+			n, err := r.ReadNumber()
+			if err != nil {
+				return err
+			}
+			if ns = append(ns, n); n == c.stop {
+				return errStop
+			}
+			return nil
+		})
+		assertError(t, c.err, err)
+		if !reflect.DeepEqual(ns, c.ns) {
+			t.Errorf("got %v, expected %v", ns, c.ns)
+		}
+		if c.err == nil {
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
+		}
+	}
+}
+
 func testReadVerbatimString(t *testing.T) {
 	p := newTypePrefixFunc(resp3.TypeVerbatimString)
 	for _, c := range []struct {
@@ -753,6 +1575,7 @@ func testReadVerbatimString(t *testing.T) {
 
 		{in: p("\r\nfoo:\r\n"), err: resp3.ErrUnexpectedEOL},
 
+		{in: p("0\r\n\r\n"), err: resp3.ErrInvalidVerbatimString},
 		{in: p("3\r\nbar\r\n"), err: resp3.ErrInvalidVerbatimString},
 		{in: p("4\r\n:bar\r\n"), err: resp3.ErrInvalidVerbatimString},
 		{in: p("5\r\nf:bar\r\n"), err: resp3.ErrInvalidVerbatimString},
@@ -818,30 +1641,361 @@ func testReadVerbatimString(t *testing.T) {
 	}
 }
 
-func TestReaderReadCrashers(t *testing.T) {
-	files, err := filepath.Glob(filepath.Join("testdata", "crashers", "*.quoted"))
-	if err != nil {
-		t.Fatalf("failed to get crashers: %s", err)
+func testReadVerbatimStringFormat(t *testing.T) {
+	for _, c := range []struct {
+		in      string
+		allowed []string
+		vs      resp3.VerbatimString
+		err     error
+		rest    string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "$3\r\ntxt\r\n", err: resp3.ErrUnexpectedType},
+		{in: "=3\r\ntxt\r\n", err: resp3.ErrInvalidVerbatimString},
+		{in: "=9\r\ntxt:hello\r\n", vs: resp3.VerbatimString{Format: "txt", Text: "hello"}},
+		{in: "=4\r\nmkd:\r\n", vs: resp3.VerbatimString{Format: "mkd"}},
+		{
+			in:      "=9\r\ntxt:hello\r\n",
+			allowed: []string{"mkd", "txt"},
+			vs:      resp3.VerbatimString{Format: "txt", Text: "hello"},
+		},
+		{
+			in:      "=9\r\nfoo:hello\r\n:1\r\n",
+			allowed: []string{"mkd", "txt"},
+			err:     resp3.ErrUnknownVerbatimFormat,
+			rest:    ":1\r\n",
+		},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		vs, err := rr.ReadVerbatimStringFormat(c.allowed...)
+		assertError(t, c.err, err)
+		if vs != c.vs {
+			t.Errorf("got %#v, expected %#v", vs, c.vs)
+		}
+		if c.err == nil || c.rest != "" {
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
+		}
 	}
+}
 
-	for _, file := range files {
-		name := filepath.Base(file)
-		name = name[:len(name)-len(filepath.Ext(file))]
+func TestReaderAllowBareLF(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   string
+		read func(*resp3.Reader) (string, error)
+		s    string
 
-		t.Run(name, func(t *testing.T) {
-			b, err := ioutil.ReadFile(file)
-			if err != nil {
-				t.Fatalf("failed to read %s: %s", file, err)
-			}
-			in, err := strconv.Unquote(string(bytes.TrimSpace(b)))
-			if err != nil {
-				t.Fatalf("invalid input: %s", string(b))
-			}
-			for _, f := range fuzz.ReaderFuncs {
-				t.Run(f.Name, func(t *testing.T) {
-					rr := resp3.NewReader(strings.NewReader(in))
-					_ = f.Func(rr)
-				})
+		// strictErr is the error returned when AllowBareLF is false. Defaults to ErrUnexpectedEOL.
+		strictErr error
+	}{
+		{
+			name: "Array",
+			in:   "*2\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				n, _, err := rr.ReadArrayHeader()
+				return strconv.FormatInt(n, 10), err
+			},
+			s: "2",
+		},
+		{
+			name: "ArrayStreamed",
+			in:   "*?\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				_, chunked, err := rr.ReadArrayHeader()
+				return strconv.FormatBool(chunked), err
+			},
+			s:         "true",
+			strictErr: resp3.ErrInvalidAggregateTypeLength,
+		},
+		{
+			name: "BigNumber",
+			in:   "(123456789123456789123456789\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				var n big.Int
+				err := rr.ReadBigNumber(&n)
+				return n.String(), err
+			},
+			s: "123456789123456789123456789",
+		},
+		{
+			name: "Boolean",
+			in:   "#t\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				b, err := rr.ReadBoolean()
+				return strconv.FormatBool(b), err
+			},
+			s: "true",
+		},
+		{
+			name: "Double",
+			in:   ",1.5\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				f, err := rr.ReadDouble()
+				return strconv.FormatFloat(f, 'f', -1, 64), err
+			},
+			s: "1.5",
+		},
+		{
+			name: "BlobChunks",
+			in:   ";5\nhello\n;0\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				b, err := rr.ReadBlobChunks(nil)
+				return string(b), err
+			},
+			s: "hello",
+		},
+		{
+			name: "BlobError",
+			in:   "!5\nhello\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				b, _, err := rr.ReadBlobError(nil)
+				return string(b), err
+			},
+			s: "hello",
+		},
+		{
+			name: "BlobString",
+			in:   "$5\nhello\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				b, _, err := rr.ReadBlobString(nil)
+				return string(b), err
+			},
+			s: "hello",
+		},
+		{
+			name: "BlobStringStreamed",
+			in:   "$?\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				_, chunked, err := rr.ReadBlobString(nil)
+				return strconv.FormatBool(chunked), err
+			},
+			s:         "true",
+			strictErr: resp3.ErrInvalidNumber,
+		},
+		{
+			name: "End",
+			in:   ".\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				return "", rr.ReadEnd()
+			},
+		},
+		{
+			name: "Null",
+			in:   "_\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				return "", rr.ReadNull()
+			},
+		},
+		{
+			name: "NullRESP2",
+			in:   "$-1\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				return "", rr.ReadNull()
+			},
+			strictErr: resp3.ErrUnexpectedType,
+		},
+		{
+			name: "Number",
+			in:   ":-10\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				n, err := rr.ReadNumber()
+				return strconv.FormatInt(n, 10), err
+			},
+			s: "-10",
+		},
+		{
+			name: "SimpleError",
+			in:   "-ERR\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				b, err := rr.ReadSimpleError(nil)
+				return string(b), err
+			},
+			s: "ERR",
+		},
+		{
+			name: "SimpleString",
+			in:   "+OK\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				b, err := rr.ReadSimpleString(nil)
+				return string(b), err
+			},
+			s: "OK",
+		},
+		{
+			name: "VerbatimString",
+			in:   "=7\nfoo:bar\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				b, err := rr.ReadVerbatimString(nil)
+				return string(b), err
+			},
+			s: "foo:bar",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			strictErr := c.strictErr
+			if strictErr == nil {
+				strictErr = resp3.ErrUnexpectedEOL
+			}
+
+			rr, _ := newTestReader(c.in)
+			_, err := c.read(rr)
+			assertError(t, strictErr, err)
+
+			rr, _ = newTestReader(c.in)
+			rr.AllowBareLF = true
+			s, err := c.read(rr)
+			assertError(t, nil, err)
+			if s != c.s {
+				t.Errorf("got %q, expected %q", s, c.s)
+			}
+
+			crlf := strings.Replace(c.in, "\n", "\r\n", -1)
+			rr, _ = newTestReader(crlf)
+			rr.AllowBareLF = true
+			s, err = c.read(rr)
+			assertError(t, nil, err)
+			if s != c.s {
+				t.Errorf("got %q, expected %q", s, c.s)
+			}
+		})
+	}
+
+	t.Run("BlobTrailer", func(t *testing.T) {
+		for _, c := range []struct {
+			name string
+			in   string
+			read func(*resp3.Reader) (string, error)
+		}{
+			{
+				name: "BlobChunk",
+				in:   ";5\r\nhello\n",
+				read: func(rr *resp3.Reader) (string, error) {
+					b, _, err := rr.ReadBlobChunk(nil)
+					return string(b), err
+				},
+			},
+			{
+				name: "BlobError",
+				in:   "!5\r\nhello\n",
+				read: func(rr *resp3.Reader) (string, error) {
+					b, _, err := rr.ReadBlobError(nil)
+					return string(b), err
+				},
+			},
+			{
+				name: "BlobString",
+				in:   "$5\r\nhello\n",
+				read: func(rr *resp3.Reader) (string, error) {
+					b, _, err := rr.ReadBlobString(nil)
+					return string(b), err
+				},
+			},
+			{
+				name: "BlobStringCtx",
+				in:   "$5\r\nhello\n",
+				read: func(rr *resp3.Reader) (string, error) {
+					b, _, err := rr.ReadBlobStringCtx(context.Background(), nil)
+					return string(b), err
+				},
+			},
+			{
+				name: "BlobStringN",
+				in:   "$5\r\nhello\n",
+				read: func(rr *resp3.Reader) (string, error) {
+					var b [5]byte
+					n, err := rr.ReadBlobStringN(b[:])
+					return string(b[:n]), err
+				},
+			},
+			{
+				name: "Discard",
+				in:   "$5\r\nhello\n",
+				read: func(rr *resp3.Reader) (string, error) {
+					_, err := rr.Discard(false)
+					return "hello", err
+				},
+			},
+			{
+				name: "PeekBlobString",
+				in:   "$5\r\nhello\n",
+				read: func(rr *resp3.Reader) (string, error) {
+					b, err := rr.PeekBlobString()
+					return string(b), err
+				},
+			},
+			{
+				name: "VerbatimString",
+				in:   "=9\r\ntxt:hello\n",
+				read: func(rr *resp3.Reader) (string, error) {
+					b, err := rr.ReadVerbatimString(nil)
+					return strings.TrimPrefix(string(b), "txt:"), err
+				},
+			},
+		} {
+			t.Run(c.name, func(t *testing.T) {
+				rr, _ := newTestReader(c.in + ":1\r\n")
+				_, err := c.read(rr)
+				assertError(t, resp3.ErrUnexpectedEOL, err)
+
+				rr, rest := newTestReaderWithRest(c.in + ":1\r\n")
+				rr.AllowBareLF = true
+				s, err := c.read(rr)
+				assertError(t, nil, err)
+				if s != "hello" {
+					t.Errorf("got %q, expected %q", s, "hello")
+				}
+				if got := rest(); got != ":1\r\n" {
+					t.Errorf("got %q left in input, expected %q", got, ":1\r\n")
+				}
+			})
+		}
+	})
+
+	t.Run("Peek", func(t *testing.T) {
+		rr, _ := newTestReader("*-1\n")
+		rr.AllowBareLF = true
+		ty, err := rr.Peek()
+		assertError(t, nil, err)
+		if ty != resp3.TypeNull {
+			t.Errorf("got %s, expected %s", ty, resp3.TypeNull)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		rr, _ := newTestReader("+hello\n")
+		rr.AllowBareLF = true
+		rr.SingleReadSizeLimit = 4
+		_, err := rr.ReadSimpleString(nil)
+		assertError(t, resp3.ErrSingleReadSizeLimitExceeded, err)
+	})
+}
+
+func TestReaderReadCrashers(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "crashers", "*.quoted"))
+	if err != nil {
+		t.Fatalf("failed to get crashers: %s", err)
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		name = name[:len(name)-len(filepath.Ext(file))]
+
+		t.Run(name, func(t *testing.T) {
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatalf("failed to read %s: %s", file, err)
+			}
+			in, err := strconv.Unquote(string(bytes.TrimSpace(b)))
+			if err != nil {
+				t.Fatalf("invalid input: %s", string(b))
+			}
+			for _, f := range fuzz.ReaderFuncs {
+				t.Run(f.Name, func(t *testing.T) {
+					rr := resp3.NewReader(strings.NewReader(in))
+					_ = f.Func(rr)
+				})
 			}
 		})
 	}
@@ -926,30 +2080,73 @@ func makeReadSimpleBenchmark(ty resp3.Type, readSimple func(*resp3.Reader, []byt
 }
 
 func benchmarkReadBigNumber(b *testing.B) {
-	in := string(resp3.TypeBigNumber) + "123456789123456789123456789123456789\r\n"
-	rr, reset := newTestReader(in)
-	n := new(big.Int)
-	for i := 0; i < b.N; i++ {
-		reset(in)
-		_ = rr.ReadBigNumber(n)
+	for _, c := range []struct {
+		name    string
+		in      string
+		scratch int
+	}{
+		{"Small", "-1234567891234567891", 0},
+		{"Large", "123456789123456789123456789123456789", 0},
+		{"VeryLarge", strings.Repeat("1234567890", 20), 0},
+		{"VeryLargeWithScratch", strings.Repeat("1234567890", 20), 256},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			in := string(resp3.TypeBigNumber) + c.in + "\r\n"
+			rr, reset := newTestReader(in)
+			rr.ScratchSize = c.scratch
+			n := new(big.Int)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				reset(in)
+				_ = rr.ReadBigNumber(n)
+			}
+		})
 	}
 }
 
 func benchmarkReadBoolean(b *testing.B) {
-	in := string(resp3.TypeBoolean) + "t\r\n"
-	rr, reset := newTestReader(in)
-	for i := 0; i < b.N; i++ {
+	b.Run("Single", func(b *testing.B) {
+		in := string(resp3.TypeBoolean) + "t\r\n"
+		rr, reset := newTestReader(in)
+		for i := 0; i < b.N; i++ {
+			reset(in)
+			_, _ = rr.ReadBoolean()
+		}
+	})
+
+	b.Run("Buffered", func(b *testing.B) {
+		in := strings.Repeat("#t\r\n#f\r\n", 32)
+		rr, reset := newTestReader(in)
 		reset(in)
-		_, _ = rr.ReadBoolean()
-	}
+		for i := 0; i < b.N; i++ {
+			if i%64 == 0 {
+				reset(in)
+			}
+			_, _ = rr.ReadBoolean()
+		}
+	})
 }
 
 func benchmarkReadDouble(b *testing.B) {
-	in := string(resp3.TypeDouble) + "1234.5678\r\n"
-	rr, reset := newTestReader(in)
-	for i := 0; i < b.N; i++ {
-		reset(in)
-		_, _ = rr.ReadDouble()
+	for _, c := range []struct {
+		name    string
+		in      string
+		scratch int
+	}{
+		{"Short", "1234.5678", 0},
+		{"Long", "1234.567890123456789012345678901234567890", 0},
+		{"LongWithScratch", "1234.567890123456789012345678901234567890", 64},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			in := string(resp3.TypeDouble) + c.in + "\r\n"
+			rr, reset := newTestReader(in)
+			rr.ScratchSize = c.scratch
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				reset(in)
+				_, _ = rr.ReadDouble()
+			}
+		})
 	}
 }
 
@@ -1123,6 +2320,11 @@ func TestReaderDiscard(t *testing.T) {
 					in:  "|-1\r\n",
 					err: resp3.ErrInvalidAggregateTypeLength,
 				},
+				{
+					in:     "|4611686018427387904\r\n+OK\r\n",
+					nested: true,
+					err:    resp3.ErrInvalidAggregateTypeLength,
+				},
 				{
 					in: "|0\r\n",
 					ty: resp3.TypeAttribute,
@@ -1269,7 +2471,7 @@ func TestReaderDiscard(t *testing.T) {
 				},
 				{
 					in:  ";-1\r\n",
-					err: resp3.ErrInvalidBlobLength,
+					err: resp3.ErrInvalidBlobChunkLength,
 				},
 				{
 					in:   ";5\r\nhello\r\n+OK\r\n",
@@ -1554,6 +2756,11 @@ func TestReaderDiscard(t *testing.T) {
 					in:  "%-1\r\n",
 					err: resp3.ErrInvalidAggregateTypeLength,
 				},
+				{
+					in:     "%4611686018427387904\r\n+OK\r\n",
+					nested: true,
+					err:    resp3.ErrInvalidAggregateTypeLength,
+				},
 				{
 					in: "%0\r\n",
 					ty: resp3.TypeMap,
@@ -1911,3 +3118,1468 @@ func TestReaderDiscard(t *testing.T) {
 		})
 	}
 }
+
+func TestReaderMore(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		more bool
+		err  error
+		rest string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: ".\r\n", rest: ""},
+		{in: ".\r\n:1\r\n", rest: ":1\r\n"},
+		{in: ".\r", err: resp3.ErrUnexpectedEOL},
+		{in: ".a\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: ":1\r\n", more: true, rest: ":1\r\n"},
+		{in: "+OK\r\n", more: true, rest: "+OK\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		more, err := rr.More()
+		assertError(t, c.err, err)
+		if more != c.more {
+			t.Errorf("got %t for %q, expected %t", more, c.in, c.more)
+		}
+		if c.err == nil {
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
+		}
+	}
+}
+
+func TestReaderReadValue(t *testing.T) {
+	newBigInt := func(s string) *big.Int {
+		n, _ := new(big.Int).SetString(s, 10)
+		return n
+	}
+	for _, c := range []struct {
+		in   string
+		v    interface{}
+		err  error
+		rest string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: ".\r\n", err: resp3.ErrUnexpectedType},
+		{in: ";5\r\nhello\r\n", err: resp3.ErrUnexpectedType},
+
+		{in: "*0\r\n", v: []interface{}{}},
+		{in: "*2\r\n+OK\r\n:1\r\n", v: []interface{}{"OK", int64(1)}},
+		{in: "*2\r\n+OK\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "*9223372036854775807\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "%4611686018427387904\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "*?\r\n+OK\r\n:1\r\n.\r\n", v: []interface{}{"OK", int64(1)}},
+		{in: "*?\r\n+OK\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: "*1\r\n*1\r\n_\r\n", v: []interface{}{[]interface{}{nil}}},
+		{in: "*-1\r\n"},
+		{in: ">2\r\n+message\r\n$5\r\nhello\r\n", v: []interface{}{"message", "hello"}},
+		{in: "~2\r\n#t\r\n#f\r\n", v: []interface{}{true, false}},
+
+		{in: "|1\r\n+key\r\n+value\r\n:1\r\n", v: int64(1)},
+		{in: "|1\r\n+key\r\n+value\r\n", err: resp3.ErrUnexpectedEOL},
+
+		{in: "(123456789123456789123456789\r\n", v: newBigInt("123456789123456789123456789")},
+		{in: "#t\r\n", v: true},
+		{in: ",1.5\r\n", v: 1.5},
+		{in: ",a\r\n", err: resp3.ErrInvalidDouble},
+		{in: ":-10\r\n", v: int64(-10)},
+		{in: "_\r\n"},
+		{in: "$-1\r\n"},
+
+		{in: "$5\r\nhello\r\n", v: "hello"},
+		{in: "$?\r\n;5\r\nhello\r\n;6\r\n world\r\n;0\r\n", v: "hello world"},
+		{in: "+hello\r\n", v: "hello"},
+		{in: "=9\r\ntxt:hello\r\n", v: "hello"},
+
+		{in: "%0\r\n", v: map[interface{}]interface{}{}},
+		{
+			in: "%2\r\n+a\r\n:1\r\n:2\r\n*1\r\n+b\r\n",
+			v:  map[interface{}]interface{}{"a": int64(1), int64(2): []interface{}{"b"}},
+		},
+		{
+			in: "%?\r\n+a\r\n:1\r\n+b\r\n:2\r\n.\r\n",
+			v:  map[interface{}]interface{}{"a": int64(1), "b": int64(2)},
+		},
+		{in: "%1\r\n*0\r\n:1\r\n", err: resp3.ErrUnexpectedType},
+
+		{in: "+OK\r\n:1\r\n", v: "OK", rest: ":1\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		v, err := rr.ReadValue()
+		assertError(t, c.err, err)
+		if !reflect.DeepEqual(v, c.v) {
+			t.Errorf("got %#v, expected %#v", v, c.v)
+		}
+		if c.err == nil {
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
+		}
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		rr, _ := newTestReader("*2\r\n-ERR hello\r\n!5\r\nERR 2\r\n")
+		v, err := rr.ReadValue()
+		assertError(t, nil, err)
+		vs, _ := v.([]interface{})
+		if len(vs) != 2 {
+			t.Fatalf("got %#v, expected 2 values", v)
+		}
+		for i, expected := range []string{"ERR hello", "ERR 2"} {
+			if re, ok := vs[i].(*resp3.RedisError); !ok || re.Error() != expected {
+				t.Errorf("got %#v, expected error %q", vs[i], expected)
+			}
+		}
+	})
+}
+
+func TestReaderReadReply(t *testing.T) {
+	for _, c := range []struct {
+		in  string
+		v   interface{}
+		err string
+	}{
+		{in: "+OK\r\n", v: "OK"},
+		{in: "*1\r\n-ERR nested\r\n", v: []interface{}{nil}},
+		{in: "-ERR hello world\r\n", err: "ERR hello world"},
+		{in: "!11\r\nERR blob\r\nx\r\n", err: "ERR blob\r\nx"},
+		{in: "|1\r\n+ttl\r\n:10\r\n-ERR attributed\r\n", err: "ERR attributed"},
+	} {
+		rr, _ := newTestReader(c.in)
+		v, err := rr.ReadReply()
+		if c.err == "" {
+			assertError(t, nil, err)
+			if vs, ok := v.([]interface{}); ok && len(vs) == 1 {
+				if _, ok := vs[0].(*resp3.RedisError); !ok {
+					t.Errorf("got %#v, expected nested *RedisError", vs[0])
+				}
+			} else if !reflect.DeepEqual(v, c.v) {
+				t.Errorf("got %#v, expected %#v", v, c.v)
+			}
+			continue
+		}
+		var re *resp3.RedisError
+		if !errors.As(err, &re) {
+			t.Fatalf("got error %v, expected *RedisError", err)
+		}
+		if re.Error() != c.err {
+			t.Errorf("got error %q, expected %q", re.Error(), c.err)
+		}
+		if v != nil {
+			t.Errorf("got %#v, expected nil", v)
+		}
+	}
+
+	rr, _ := newTestReader("")
+	_, err := rr.ReadReply()
+	assertError(t, resp3.ErrUnexpectedEOL, err)
+}
+
+func TestReaderDisableRESP2Null(t *testing.T) {
+	for _, in := range []string{"*-1\r\n", "$-1\r\n"} {
+		rr, _ := newTestReader(in)
+		rr.DisableRESP2Null = true
+		ty, err := rr.Peek()
+		assertError(t, nil, err)
+		if expected := resp3.Type(in[0]); ty != expected {
+			t.Errorf("got type %q for %q, expected %q", ty, in, expected)
+		}
+		assertError(t, resp3.ErrUnexpectedType, rr.ReadNull())
+	}
+
+	rr, _ := newTestReader("*-1\r\n")
+	rr.DisableRESP2Null = true
+	_, _, err := rr.ReadArrayHeader()
+	assertError(t, resp3.ErrInvalidAggregateTypeLength, err)
+
+	rr, _ = newTestReader("$-1\r\n")
+	rr.DisableRESP2Null = true
+	_, _, err = rr.ReadBlobString(nil)
+	assertError(t, resp3.ErrInvalidBlobLength, err)
+
+	rr, _ = newTestReader("*-1\r\n")
+	rr.DisableRESP2Null = true
+	_, err = rr.ReadValue()
+	assertError(t, resp3.ErrInvalidAggregateTypeLength, err)
+
+	rr, _ = newTestReader("_\r\n")
+	rr.DisableRESP2Null = true
+	assertError(t, nil, rr.ReadNull())
+}
+
+func TestReaderStrictNumbers(t *testing.T) {
+	for _, c := range []struct {
+		in      string
+		n       int64
+		err     error
+		lenient int64
+	}{
+		{in: ":0\r\n", n: 0},
+		{in: ":1\r\n", n: 1, lenient: 1},
+		{in: ":10\r\n", n: 10, lenient: 10},
+		{in: ":-10\r\n", n: -10, lenient: -10},
+		{in: ":007\r\n", err: resp3.ErrInvalidNumber, lenient: 7},
+		{in: ":00\r\n", err: resp3.ErrInvalidNumber},
+		{in: ":-0\r\n", err: resp3.ErrInvalidNumber},
+		{in: ":-01\r\n", err: resp3.ErrInvalidNumber, lenient: -1},
+		{in: ":+1\r\n", err: resp3.ErrInvalidNumber},
+	} {
+		rr, _ := newTestReader(c.in)
+		rr.StrictNumbers = true
+		n, err := rr.ReadNumber()
+		assertError(t, c.err, err)
+		if n != c.n {
+			t.Errorf("got %d for %q, expected %d", n, c.in, c.n)
+		}
+
+		if c.in == ":+1\r\n" {
+			continue
+		}
+
+		rr, _ = newTestReader(c.in)
+		if n, err := rr.ReadNumber(); err != nil || n != c.lenient {
+			t.Errorf("got (%d, %v) for %q in lenient mode, expected (%d, nil)", n, err, c.in, c.lenient)
+		}
+	}
+
+	rr, _ := newTestReader("$05\r\nhello\r\n")
+	rr.StrictNumbers = true
+	_, _, err := rr.ReadBlobString(nil)
+	assertError(t, resp3.ErrInvalidNumber, err)
+}
+
+func TestReaderAllowPlusSign(t *testing.T) {
+	for _, c := range []struct {
+		in     string
+		strict bool
+		n      int64
+		err    error
+	}{
+		{in: ":+1\r\n", n: 1},
+		{in: ":+0\r\n", n: 0},
+		{in: ":+10\r\n", n: 10},
+		{in: ":+10\r\n", strict: true, n: 10},
+		{in: ":+0\r\n", strict: true, n: 0},
+		{in: ":+01\r\n", strict: true, err: resp3.ErrInvalidNumber},
+		{in: ":-1\r\n", n: -1},
+		{in: ":+\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: ":++1\r\n", err: resp3.ErrInvalidNumber},
+		{in: ":1+\r\n", err: resp3.ErrInvalidNumber},
+		{in: ":+-1\r\n", err: resp3.ErrInvalidNumber},
+		{in: ":+9223372036854775808\r\n", err: resp3.ErrOverflow},
+	} {
+		rr, _ := newTestReader(c.in)
+		rr.AllowPlusSign = true
+		rr.StrictNumbers = c.strict
+		n, err := rr.ReadNumber()
+		assertError(t, c.err, err)
+		if n != c.n {
+			t.Errorf("got %d for %q, expected %d", n, c.in, c.n)
+		}
+	}
+}
+
+func TestReaderOverflowError(t *testing.T) {
+	long := "1" + strings.Repeat("0", 100)
+	for _, c := range []struct {
+		in  string
+		msg string
+	}{
+		{in: ":184467440737095516151\r\n", msg: "number overflowed: 184467440737095516151 at character"},
+		{in: ":-184467440737095516151\r\n", msg: "number overflowed: -184467440737095516151 at character"},
+		{in: ":" + long + "\r\n", msg: "number overflowed: " + long[:64] + "... at character"},
+		{in: "(" + long + "a\r\n", msg: "invalid big number: " + long[:64] + "..."},
+	} {
+		rr, _ := newTestReader(c.in)
+		var err error
+		if c.in[0] == '(' {
+			err = rr.ReadBigNumber(new(big.Int))
+		} else {
+			_, err = rr.ReadNumber()
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), c.msg) {
+			t.Errorf("got error %v, expected error starting with %q", err, c.msg)
+		}
+	}
+}
+
+func TestReaderBooleanFromInteger(t *testing.T) {
+	for _, c := range []struct {
+		in  string
+		b   bool
+		err error
+	}{
+		{in: ":0\r\n", b: false},
+		{in: ":1\r\n", b: true},
+		{in: ":-2\r\n", b: true},
+		{in: ":a\r\n", err: resp3.ErrInvalidNumber},
+		{in: "#t\r\n", b: true},
+		{in: "#f\r\n", b: false},
+		{in: "+OK\r\n", err: resp3.ErrUnexpectedType},
+	} {
+		rr, _ := newTestReader(c.in)
+		rr.BooleanFromInteger = true
+		b, err := rr.ReadBoolean()
+		assertError(t, c.err, err)
+		if b != c.b {
+			t.Errorf("got %t for %q, expected %t", b, c.in, c.b)
+		}
+	}
+
+	rr, _ := newTestReader(":1\r\n")
+	_, err := rr.ReadBoolean()
+	assertError(t, resp3.ErrUnexpectedType, err)
+}
+
+func TestReaderReadReplies(t *testing.T) {
+	rr, rest := newTestReaderWithRest("+OK\r\n-ERR failed\r\n:1\r\n-ERR other\r\n:2\r\n")
+	vs, err := rr.ReadReplies(4)
+	if re, ok := err.(*resp3.RedisError); !ok || re.Error() != "ERR failed" {
+		t.Errorf("got error %#v, expected error %q", err, "ERR failed")
+	}
+	if expected := []interface{}{"OK", nil, int64(1), nil}; !reflect.DeepEqual(vs, expected) {
+		t.Errorf("got %#v, expected %#v", vs, expected)
+	}
+	if got := rest(); got != ":2\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, ":2\r\n")
+	}
+
+	rr, _ = newTestReader("+OK\r\n")
+	vs, err = rr.ReadReplies(2)
+	assertError(t, resp3.ErrUnexpectedEOL, err)
+	if vs != nil {
+		t.Errorf("got %#v, expected nil", vs)
+	}
+
+	rr, rest = newTestReaderWithRest("+OK\r\n")
+	_, err = rr.ReadReplies(-1)
+	assertError(t, resp3.ErrOutOfRange, err)
+	rr.MaxReplyNodes = 2
+	_, err = rr.ReadReplies(3)
+	assertError(t, resp3.ErrAggregateTooLong, err)
+	if got := rest(); got != "+OK\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, "+OK\r\n")
+	}
+
+	rr, _ = newTestReader("")
+	_, err = rr.ReadReplies(resp3.DefaultMaxValueCount)
+	assertError(t, resp3.ErrUnexpectedEOL, err)
+	_, err = rr.ReadReplies(resp3.DefaultMaxValueCount + 1)
+	assertError(t, resp3.ErrAggregateTooLong, err)
+}
+
+func TestReaderForEachReply(t *testing.T) {
+	type reply struct {
+		v   interface{}
+		err string
+	}
+
+	rr, _ := newTestReader("+OK\r\n-ERR failed\r\n:1\r\nA")
+	var replies []reply
+	err := rr.ForEachReply(4, func(i int, v interface{}, err error) {
+		if i != len(replies) {
+			t.Errorf("got index %d, expected %d", i, len(replies))
+		}
+		r := reply{v: v}
+		if err != nil {
+			r.err = err.Error()
+		}
+		replies = append(replies, r)
+	})
+	assertError(t, resp3.ErrInvalidType, err)
+
+	expected := []reply{{v: "OK"}, {err: "ERR failed"}, {v: int64(1)}, {err: err.Error()}}
+	if !reflect.DeepEqual(replies, expected) {
+		t.Errorf("got %#v, expected %#v", replies, expected)
+	}
+
+	rr, rest := newTestReaderWithRest("-ERR first\r\n-ERR second\r\n:1\r\n+OK\r\n")
+	var calls int
+	err = rr.ForEachReply(3, func(int, interface{}, error) { calls++ })
+	if re, ok := err.(*resp3.RedisError); !ok || re.Error() != "ERR first" {
+		t.Errorf("got error %#v, expected error %q", err, "ERR first")
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, expected 3", calls)
+	}
+	if got := rest(); got != "+OK\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, "+OK\r\n")
+	}
+
+	rr.MaxReplyNodes = 1
+	assertError(t, resp3.ErrAggregateTooLong, rr.ForEachReply(2, func(int, interface{}, error) { calls++ }))
+	assertError(t, resp3.ErrOutOfRange, rr.ForEachReply(-1, func(int, interface{}, error) { calls++ }))
+	if calls != 3 {
+		t.Errorf("got %d calls, expected 3", calls)
+	}
+}
+
+func TestReaderMaxReplyBytes(t *testing.T) {
+	const in = "*3\r\n$3\r\nfoo\r\n+bar\r\n:123456\r\n"
+
+	for _, c := range []struct {
+		name string
+		fn   func(rr *resp3.Reader) error
+	}{
+		{"ReadValue", func(rr *resp3.Reader) error { _, err := rr.ReadValue(); return err }},
+		{"ReadReply", func(rr *resp3.Reader) error { _, err := rr.ReadReply(); return err }},
+		{"Discard", func(rr *resp3.Reader) error { _, err := rr.Discard(true); return err }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rr, _ := newTestReader(in + in + in)
+
+			rr.MaxReplyBytes = 6
+			assertError(t, nil, c.fn(rr))
+			assertError(t, nil, c.fn(rr))
+
+			rr.MaxReplyBytes = 5
+			assertError(t, resp3.ErrReplyTooLarge, c.fn(rr))
+		})
+	}
+
+	rr, _ := newTestReader("$3\r\nfoo\r\n$3\r\nbar\r\n")
+	rr.MaxReplyBytes = 4
+	if _, _, err := rr.ReadBlobString(nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := rr.ReadValue(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestReaderMaxReplyNodes(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		fn   func(rr *resp3.Reader) error
+	}{
+		{"ReadValue", func(rr *resp3.Reader) error { _, err := rr.ReadValue(); return err }},
+		{"Discard", func(rr *resp3.Reader) error { _, err := rr.Discard(true); return err }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rr, _ := newTestReader("*2\r\n%2\r\n:1\r\n:2\r\n:3\r\n:4\r\n*2\r\n_\r\n_\r\n")
+			rr.MaxReplyNodes = 8
+			assertError(t, nil, c.fn(rr))
+
+			rr, _ = newTestReader("*2\r\n%2\r\n:1\r\n:2\r\n:3\r\n:4\r\n*2\r\n_\r\n_\r\n")
+			rr.MaxReplyNodes = 7
+			assertError(t, resp3.ErrAggregateTooLong, c.fn(rr))
+
+			rr, _ = newTestReader(strings.Repeat("*1000\r\n", 10))
+			rr.MaxReplyNodes = 1 << 20
+			assertError(t, resp3.ErrAggregateTooLong, c.fn(rr))
+		})
+	}
+}
+
+func TestReaderMaxReplyDepth(t *testing.T) {
+	nested := func(header string, depth int) string {
+		return strings.Repeat(header, depth) + ":1\r\n" + strings.Repeat(".\r\n", strings.Count(header, "?")*depth)
+	}
+
+	for _, c := range []struct {
+		name string
+		fn   func(rr *resp3.Reader) error
+	}{
+		{"ReadValue", func(rr *resp3.Reader) error { _, err := rr.ReadValue(); return err }},
+		{"ReadValueInto", func(rr *resp3.Reader) error { var v resp3.Value; return rr.ReadValueInto(&v) }},
+		{"Discard", func(rr *resp3.Reader) error { _, err := rr.Discard(true); return err }},
+		{"DiscardToDepth", func(rr *resp3.Reader) error { _, err := rr.DiscardToDepth(math.MaxInt32); return err }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			for _, header := range []string{"*1\r\n", "*?\r\n"} {
+				rr, _ := newTestReader(nested(header, resp3.DefaultMaxReplyDepth))
+				assertError(t, nil, c.fn(rr))
+
+				rr, _ = newTestReader(nested(header, resp3.DefaultMaxReplyDepth+1))
+				assertError(t, resp3.ErrValueTooDeep, c.fn(rr))
+
+				rr, _ = newTestReader(nested(header, 3))
+				rr.MaxReplyDepth = 2
+				assertError(t, resp3.ErrValueTooDeep, c.fn(rr))
+
+				rr, _ = newTestReader(nested(header, resp3.DefaultMaxReplyDepth+1))
+				rr.MaxReplyDepth = -1
+				assertError(t, nil, c.fn(rr))
+			}
+		})
+	}
+
+	t.Run("Attributes", func(t *testing.T) {
+		in := strings.Repeat("|0\r\n", 10*resp3.DefaultMaxReplyDepth) + ":1\r\n"
+
+		rr, _ := newTestReader(in + in)
+		v, err := rr.ReadValue()
+		assertError(t, nil, err)
+		if v != int64(1) {
+			t.Errorf("got %#v, expected 1", v)
+		}
+
+		var vv resp3.Value
+		assertError(t, nil, rr.ReadValueInto(&vv))
+		if vv.Type != resp3.TypeNumber || vv.Int != 1 {
+			t.Errorf("got %#v, expected number 1", vv)
+		}
+	})
+}
+
+func TestReaderSkipLine(t *testing.T) {
+	rr, rest := newTestReaderWithRest("@foo\nbar\r\n:1\r\n")
+	assertError(t, nil, rr.SkipLine())
+	if got := rest(); got != ":1\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, ":1\r\n")
+	}
+
+	rr, rest = newTestReaderWithRest("@foo\nbar\r\n")
+	rr.AllowBareLF = true
+	assertError(t, nil, rr.SkipLine())
+	if got := rest(); got != "bar\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, "bar\r\n")
+	}
+
+	rr, rest = newTestReaderWithRest("@" + strings.Repeat("a", 8192) + "\r\n:1\r\n")
+	assertError(t, nil, rr.SkipLine())
+	if got := rest(); got != ":1\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, ":1\r\n")
+	}
+
+	rr, _ = newTestReader("@foo")
+	assertError(t, resp3.ErrUnexpectedEOL, rr.SkipLine())
+}
+
+func TestReaderAllowUnknownTypes(t *testing.T) {
+	const in = "*2\r\n@foo\r\n:1\r\n"
+
+	rr, _ := newTestReader(in)
+	_, err := rr.Discard(true)
+	assertError(t, resp3.ErrInvalidType, err)
+
+	rr, rest := newTestReaderWithRest(in + "@bar\r\n:2\r\n")
+	rr.AllowUnknownTypes = true
+	if ty, err := rr.Discard(true); err != nil || ty != resp3.TypeArray {
+		t.Errorf("got (%q, %v), expected (%q, nil)", ty, err, resp3.TypeArray)
+	}
+	if ty, err := rr.Discard(true); err != nil || ty != resp3.TypeInvalid {
+		t.Errorf("got (%q, %v), expected (%q, nil)", ty, err, resp3.TypeInvalid)
+	}
+	if got := rest(); got != ":2\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, ":2\r\n")
+	}
+}
+
+func TestReaderRegisterType(t *testing.T) {
+	rr, rest := newTestReaderWithRest("*2\r\n@foo\r\n:1\r\n@bar\r\n:2\r\n")
+
+	var calls int
+	assertError(t, nil, rr.RegisterType('@', func(r *resp3.Reader) error {
+		calls++
+		return r.SkipLine()
+	}))
+
+	if ty, err := rr.Discard(true); err != nil || ty != resp3.TypeArray {
+		t.Errorf("got (%q, %v), expected (%q, nil)", ty, err, resp3.TypeArray)
+	}
+	if ty, err := rr.Peek(); err != nil || ty != resp3.Type('@') {
+		t.Errorf("got (%q, %v), expected (%q, nil)", ty, err, '@')
+	}
+	if ty, err := rr.Discard(false); err != nil || ty != resp3.Type('@') {
+		t.Errorf("got (%q, %v), expected (%q, nil)", ty, err, '@')
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, expected 2", calls)
+	}
+	if got := rest(); got != ":2\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, ":2\r\n")
+	}
+
+	rr, _ = newTestReader("@foo\r\n")
+	assertError(t, nil, rr.RegisterType('@', func(r *resp3.Reader) error { return r.SkipLine() }))
+	assertError(t, nil, rr.RegisterType('@', nil))
+	_, err := rr.Peek()
+	assertError(t, resp3.ErrInvalidType, err)
+
+	rr, _ = newTestReader("*0\r\n")
+	err = rr.RegisterType(byte(resp3.TypeArray), func(r *resp3.Reader) error { return nil })
+	assertError(t, resp3.ErrKnownType, err)
+	if ty, err := rr.Discard(false); err != nil || ty != resp3.TypeArray {
+		t.Errorf("got (%q, %v), expected (%q, nil)", ty, err, resp3.TypeArray)
+	}
+}
+
+func TestReaderEOLError(t *testing.T) {
+	for _, c := range []struct {
+		in  string
+		msg string
+	}{
+		{"$3\r\nfoo\n+", `expected \r\n, got \x0a\x2b`},
+		{"$3\r\nfooAB", `expected \r\n, got \x41\x42`},
+		{"$3\r\nfoo\r", `expected \r\n, got \x0d followed by EOF`},
+		{"$3\r\nfoo", `expected \r\n, got EOF`},
+		{"+foo\n", `expected \r\n, got \x6f\x0a`},
+	} {
+		rr, _ := newTestReader(c.in)
+		_, err := rr.ReadValue()
+		assertError(t, resp3.ErrUnexpectedEOL, err)
+		if err != nil && !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("got error %q for %q, expected it to contain %q", err, c.in, c.msg)
+		}
+	}
+}
+
+func TestReaderReadLeadingAttribute(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		m    map[string]string
+		ok   bool
+		err  error
+		rest string
+	}{
+		{err: io.EOF},
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: ":1\r\n", rest: ":1\r\n"},
+		{in: "|0\r\n+OK\r\n", m: map[string]string{}, ok: true, rest: "+OK\r\n"},
+		{
+			in:   "|2\r\n+key-popularity\r\n,0.5\r\n$3\r\nttl\r\n:10\r\n+OK\r\n",
+			m:    map[string]string{"key-popularity": "0.5", "ttl": "10"},
+			ok:   true,
+			rest: "+OK\r\n",
+		},
+		{in: "|?\r\n+a\r\n_\r\n.\r\n:1\r\n", m: map[string]string{"a": ""}, ok: true, rest: ":1\r\n"},
+		{in: "|1\r\n+a\r\n*0\r\n", err: resp3.ErrTypeMismatch},
+		{in: "|1\r\n+a\r\n", err: resp3.ErrUnexpectedEOL},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		m, ok, err := rr.ReadLeadingAttribute()
+		assertError(t, c.err, err)
+		if ok != c.ok || !reflect.DeepEqual(m, c.m) {
+			t.Errorf("got (%#v, %t) for %q, expected (%#v, %t)", m, ok, c.in, c.m, c.ok)
+		}
+		if c.err != nil {
+			continue
+		}
+		if got := rest(); got != c.rest {
+			t.Errorf("got %q left in input, expected %q", got, c.rest)
+		}
+	}
+}
+
+func TestReaderNextIsPush(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		push bool
+		err  error
+	}{
+		{err: io.EOF},
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: ">1\r\n+a\r\n", push: true},
+		{in: ">?\r\n", push: true},
+		{in: "*1\r\n+a\r\n"},
+		{in: "*-1\r\n"},
+		{in: "+OK\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		push, err := rr.NextIsPush()
+		assertError(t, c.err, err)
+		if push != c.push {
+			t.Errorf("got %t for %q, expected %t", push, c.in, c.push)
+		}
+		if got := rest(); got != c.in {
+			t.Errorf("got %q left in input, expected %q", got, c.in)
+		}
+	}
+}
+
+func TestReaderReadPushMessage(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		m    *resp3.PushMessage
+		err  error
+		rest string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "*1\r\n", err: resp3.ErrUnexpectedType},
+		{in: ">0\r\n", err: resp3.ErrInvalidPushMessage},
+		{in: ">?\r\n", err: resp3.ErrInvalidPushMessage},
+		{
+			in: ">3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n",
+			m:  &resp3.PushMessage{Kind: "message", Channel: "news", Payload: []byte("hello")},
+		},
+		{
+			in: ">4\r\n+pmessage\r\n+n*\r\n+news\r\n$5\r\nhello\r\n",
+			m:  &resp3.PushMessage{Kind: "pmessage", Pattern: "n*", Channel: "news", Payload: []byte("hello")},
+		},
+		{
+			in: ">3\r\n+smessage\r\n+news\r\n$0\r\n\r\n",
+			m:  &resp3.PushMessage{Kind: "smessage", Channel: "news", Payload: []byte{}},
+		},
+		{
+			in: ">3\r\n+subscribe\r\n+news\r\n:1\r\n",
+			m:  &resp3.PushMessage{Kind: "subscribe", Channel: "news", Count: 1},
+		},
+		{
+			in: ">3\r\n+punsubscribe\r\n+n*\r\n:0\r\n",
+			m:  &resp3.PushMessage{Kind: "punsubscribe", Pattern: "n*"},
+		},
+		{in: ">3\r\n+subscribe\r\n+news\r\n+1\r\n", err: resp3.ErrUnexpectedType},
+		{in: ">3\r\n+message\r\n+news\r\n", err: resp3.ErrUnexpectedEOL},
+		{in: ">2\r\n+message\r\n+news\r\n:1\r\n", err: resp3.ErrInvalidPushMessage, rest: ":1\r\n"},
+		{in: ">2\r\n+invalidate\r\n*1\r\n+key\r\n:1\r\n", err: resp3.ErrInvalidPushMessage, rest: ":1\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		m, err := rr.ReadPushMessage()
+		assertError(t, c.err, err)
+		if !reflect.DeepEqual(m, c.m) {
+			t.Errorf("got %#v for %q, expected %#v", m, c.in, c.m)
+		}
+		if got := rest(); c.rest != "" && got != c.rest {
+			t.Errorf("got %q left in input, expected %q", got, c.rest)
+		}
+	}
+
+	rr, _ := newTestReader("*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n")
+	rr.Protocol = 2
+	m, err := rr.ReadPushMessage()
+	assertError(t, nil, err)
+	if m == nil || m.Kind != "message" || m.Channel != "news" || string(m.Payload) != "hello" {
+		t.Errorf("got %#v, expected message on channel news", m)
+	}
+}
+
+func TestReaderPeekErrorCode(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		code string
+		err  error
+	}{
+		{err: io.EOF},
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: "+OK\r\n", err: resp3.ErrUnexpectedType},
+		{in: "!3\r\nERR\r\n", err: resp3.ErrUnexpectedType},
+		{in: "-ERR", err: resp3.ErrUnexpectedEOL},
+		{in: "-\r\n"},
+		{in: "-ERR\r\n", code: "ERR"},
+		{in: "-ERR\n", code: "ERR"},
+		{in: "-ERR unknown command\r\n", code: "ERR"},
+		{in: "-MOVED 3999 127.0.0.1:6381\r\n", code: "MOVED"},
+		{in: "-" + strings.Repeat("A", 5000) + "\r\n", err: bufio.ErrBufferFull},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		code, err := rr.PeekErrorCode()
+		assertError(t, c.err, err)
+		if code != c.code {
+			t.Errorf("got %q for %q, expected %q", code, c.in, c.code)
+		}
+		if got := rest(); got != c.in {
+			t.Errorf("got %q left in input, expected %q", got, c.in)
+		}
+	}
+}
+
+func TestReaderPeekN(t *testing.T) {
+	rr, rest := newTestReaderWithRest("*?\r\n:1\r\n.\r\n")
+
+	b, err := rr.PeekN(2)
+	assertError(t, nil, err)
+	assertBytes(t, "*?", b)
+
+	b, err = rr.PeekN(0)
+	assertError(t, nil, err)
+	assertBytes(t, "", b)
+
+	b, err = rr.PeekN(20)
+	assertError(t, io.EOF, err)
+	assertBytes(t, "*?\r\n:1\r\n.\r\n", b)
+
+	_, err = rr.PeekN(5000)
+	assertError(t, bufio.ErrBufferFull, err)
+
+	if got := rest(); got != "*?\r\n:1\r\n.\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, "*?\r\n:1\r\n.\r\n")
+	}
+
+	rr = resp3.NewReader(bufio.NewReaderSize(strings.NewReader(strings.Repeat("+", 5000)), 8192))
+	b, err = rr.PeekN(5000)
+	assertError(t, nil, err)
+	if len(b) != 5000 {
+		t.Errorf("got %d bytes, expected %d", len(b), 5000)
+	}
+}
+
+func TestReaderScratchSize(t *testing.T) {
+	bigNumber := strings.Repeat("1234567890", 10)
+	in := "(" + bigNumber + "\r\n,1.5\r\n#t\r\n+OK\r\n"
+
+	r := strings.NewReader(in)
+	rr := resp3.NewReader(r)
+	rr.ScratchSize = 128
+
+	var n big.Int
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Reset(in)
+		rr.Reset(r)
+		assertError(t, nil, rr.ReadBigNumber(&n))
+		f, err := rr.ReadDouble()
+		assertError(t, nil, err)
+		if f != 1.5 {
+			t.Errorf("got %f, expected 1.5", f)
+		}
+		b, err := rr.ReadBoolean()
+		assertError(t, nil, err)
+		if !b {
+			t.Error("got false, expected true")
+		}
+		_, err = rr.Discard(false)
+		assertError(t, nil, err)
+	})
+	if allocs != 0 {
+		t.Errorf("got %.0f allocations, expected 0", allocs)
+	}
+	if got := n.String(); got != bigNumber {
+		t.Errorf("got %s, expected %s", got, bigNumber)
+	}
+}
+
+func TestReaderReadDoubleAllocs(t *testing.T) {
+	const in = ",1.5\r\n"
+
+	r := strings.NewReader(in)
+	rr := resp3.NewReader(r)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Reset(in)
+		rr.Reset(r)
+		f, err := rr.ReadDouble()
+		assertError(t, nil, err)
+		if f != 1.5 {
+			t.Errorf("got %f, expected 1.5", f)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("got %.0f allocations, expected 0", allocs)
+	}
+}
+
+func TestReaderReadVerbatimStringErrorPreview(t *testing.T) {
+	for _, c := range []struct {
+		in  string
+		msg string
+	}{
+		{"=0\r\n\r\n", `invalid verbatim string: ""`},
+		{"=3\r\nbar\r\n", `invalid verbatim string: "bar"`},
+		{"=16\r\nabcdefghijklmnop\r\n", `invalid verbatim string: "abcdefghijklmnop"`},
+		{"=17\r\nabcdefghijklmnopq\r\n", `invalid verbatim string: "abcdefghijklmnop..."`},
+		{"=18\r\nabcdefghijklmno\u00e4q\r\n", `invalid verbatim string: "abcdefghijklmno..."`},
+	} {
+		rr, _ := newTestReader(c.in)
+		_, err := rr.ReadVerbatimString(nil)
+		assertError(t, resp3.ErrInvalidVerbatimString, err)
+		if err != nil && err.Error() != c.msg {
+			t.Errorf("got message %q, expected %q", err.Error(), c.msg)
+		}
+	}
+}
+
+func TestReaderStrictBlobErrors(t *testing.T) {
+	rr, rest := newTestReaderWithRest("!0\r\n\r\n!0\r\n\r\n!3\r\nERR\r\n$0\r\n\r\n:1\r\n")
+
+	b, _, err := rr.ReadBlobError(nil)
+	assertError(t, nil, err)
+	assertBytes(t, "", b)
+
+	rr.StrictBlobErrors = true
+	_, _, err = rr.ReadBlobError(nil)
+	assertError(t, resp3.ErrEmptyError, err)
+
+	b, _, err = rr.ReadBlobError(nil)
+	assertError(t, nil, err)
+	assertBytes(t, "ERR", b)
+
+	b, _, err = rr.ReadBlobString(nil)
+	assertError(t, nil, err)
+	assertBytes(t, "", b)
+
+	if got := rest(); got != ":1\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, ":1\r\n")
+	}
+}
+
+func TestReaderBlobLengthMismatch(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		read func(*resp3.Reader) error
+	}{
+		{"ReadBlobString", func(rr *resp3.Reader) error {
+			_, _, err := rr.ReadBlobString(nil)
+			return err
+		}},
+		{"ReadBlobStringCtx", func(rr *resp3.Reader) error {
+			_, _, err := rr.ReadBlobStringCtx(context.Background(), nil)
+			return err
+		}},
+		{"ReadBlobStringN", func(rr *resp3.Reader) error {
+			_, err := rr.ReadBlobStringN(make([]byte, 16))
+			return err
+		}},
+		{"ReadValue", func(rr *resp3.Reader) error {
+			_, err := rr.ReadValue()
+			return err
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rr, _ := newTestReader("$3\r\nhello\r\n")
+			err := c.read(rr)
+			assertError(t, resp3.ErrUnexpectedEOL, err)
+			const expected = "unexpected EOL: expected \\r\\n, got \\x6c\\x6f after blob body of declared length 3, " +
+				"declared length may not match body"
+			if err != nil && err.Error() != expected {
+				t.Errorf("got message %q, expected %q", err.Error(), expected)
+			}
+		})
+	}
+}
+
+func TestReaderDiscardToDepth(t *testing.T) {
+	for _, c := range []struct {
+		in       string
+		maxDepth int
+		left     resp3.Type
+		err      error
+		rest     string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: "*2\r\n:1\r\n", err: resp3.ErrUnexpectedEOL},
+
+		{in: "+OK\r\n:1\r\n", rest: ":1\r\n"},
+		{in: "$?\r\n;2\r\nhe\r\n;0\r\n:1\r\n", rest: ":1\r\n"},
+		{in: "*-1\r\n:1\r\n", maxDepth: -1, rest: ":1\r\n"},
+		{in: "*1\r\n:1\r\n:2\r\n", maxDepth: -1, left: resp3.TypeArray, rest: "*1\r\n:1\r\n:2\r\n"},
+		{in: "*1\r\n:1\r\n:2\r\n", rest: ":2\r\n"},
+
+		{in: "*3\r\n:1\r\n*2\r\n:2\r\n*1\r\n:3\r\n:4\r\n", maxDepth: 2, rest: ""},
+		{
+			in:       "*3\r\n:1\r\n*2\r\n:2\r\n*1\r\n:3\r\n:4\r\n",
+			maxDepth: 1,
+			left:     resp3.TypeArray,
+			rest:     "*1\r\n:3\r\n:4\r\n",
+		},
+		{
+			in:   "*3\r\n:1\r\n*2\r\n:2\r\n*1\r\n:3\r\n:4\r\n",
+			left: resp3.TypeArray,
+			rest: "*2\r\n:2\r\n*1\r\n:3\r\n:4\r\n",
+		},
+		{
+			in:   "%?\r\n+a\r\n:1\r\n+b\r\n~1\r\n:2\r\n.\r\n",
+			left: resp3.TypeSet,
+			rest: "~1\r\n:2\r\n.\r\n",
+		},
+		{in: "%?\r\n+a\r\n:1\r\n+b\r\n~1\r\n:2\r\n.\r\n", maxDepth: 1},
+		{in: "|1\r\n+a\r\n:1\r\n:2\r\n", rest: ":2\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		left, err := rr.DiscardToDepth(c.maxDepth)
+		assertError(t, c.err, err)
+		if left != c.left {
+			t.Errorf("got %q for %q, expected %q", left, c.in, c.left)
+		}
+		if c.err == nil {
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
+		}
+	}
+}
+
+func TestReaderReadMapFields(t *testing.T) {
+	var (
+		name   string
+		length int64
+		seen   []string
+	)
+	fields := map[string]func(r *resp3.Reader) error{
+		"name": func(r *resp3.Reader) error {
+			seen = append(seen, "name")
+			b, _, err := r.ReadBlobString(nil)
+			name = string(b)
+			return err
+		},
+		"length": func(r *resp3.Reader) error {
+			seen = append(seen, "length")
+			var err error
+			length, err = r.ReadNumber()
+			return err
+		},
+	}
+
+	for _, c := range []struct {
+		in       string
+		protocol uint8
+		err      error
+		seen     []string
+		rest     string
+	}{
+		{
+			in:   "%3\r\n+length\r\n:10\r\n$6\r\ngroups\r\n*2\r\n:1\r\n:2\r\n$4\r\nname\r\n$3\r\nabc\r\n+OK\r\n",
+			seen: []string{"length", "name"},
+			rest: "+OK\r\n",
+		},
+		{
+			in:   "%?\r\n+name\r\n$3\r\nabc\r\n+length\r\n:10\r\n.\r\n",
+			seen: []string{"name", "length"},
+		},
+		{
+			in:       "*4\r\n+name\r\n$3\r\nabc\r\n+length\r\n:10\r\n",
+			protocol: 2,
+			seen:     []string{"name", "length"},
+		},
+		{in: "%0\r\n"},
+		{in: "*2\r\n+name\r\n$3\r\nabc\r\n", err: resp3.ErrUnexpectedType},
+		{in: "%1\r\n:1\r\n:2\r\n", err: resp3.ErrUnexpectedType},
+		{in: "%1\r\n+name\r\n:1\r\n", err: resp3.ErrUnexpectedType, seen: []string{"name"}},
+	} {
+		name, length, seen = "", 0, nil
+
+		rr, rest := newTestReaderWithRest(c.in)
+		rr.Protocol = c.protocol
+		assertError(t, c.err, rr.ReadMapFields(fields))
+		if !reflect.DeepEqual(seen, c.seen) {
+			t.Errorf("got fields %v for %q, expected %v", seen, c.in, c.seen)
+		}
+		if c.err != nil || len(c.seen) == 0 {
+			continue
+		}
+		if name != "abc" || length != 10 {
+			t.Errorf("got name %q and length %d for %q, expected %q and %d", name, length, c.in, "abc", 10)
+		}
+		if got := rest(); got != c.rest {
+			t.Errorf("got %q left in input, expected %q", got, c.rest)
+		}
+	}
+}
+
+func TestReaderReadN(t *testing.T) {
+	rr, rest := newTestReaderWithRest(":1\r\n:2\r\n:3\r\n+OK\r\n")
+
+	var got []int64
+	err := rr.ReadN(3, func(i int, r *resp3.Reader) error {
+		if i != len(got) {
+			t.Errorf("got index %d, expected %d", i, len(got))
+		}
+		n, err := r.ReadNumber()
+		got = append(got, n)
+		return err
+	})
+	assertError(t, nil, err)
+	if !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Errorf("got %v, expected [1 2 3]", got)
+	}
+	if got := rest(); got != "+OK\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, "+OK\r\n")
+	}
+
+	rr, _ = newTestReader("+OK\r\n")
+	calls := 0
+	err = rr.ReadN(2, func(int, *resp3.Reader) error {
+		calls++
+		_, err := rr.ReadNumber()
+		return err
+	})
+	assertError(t, resp3.ErrUnexpectedType, err)
+	if calls != 1 {
+		t.Errorf("got %d calls, expected 1", calls)
+	}
+
+	fail := func(int, *resp3.Reader) error {
+		t.Error("unexpected call")
+		return nil
+	}
+	assertError(t, nil, rr.ReadN(0, fail))
+	assertError(t, resp3.ErrOutOfRange, rr.ReadN(-1, fail))
+
+	assertError(t, resp3.ErrAggregateTooLong, rr.ReadN(resp3.DefaultMaxValueCount+1, fail))
+	assertError(t, resp3.ErrAggregateTooLong, rr.ReadN(math.MaxInt32, fail))
+
+	rr.MaxReplyNodes = 10
+	assertError(t, resp3.ErrAggregateTooLong, rr.ReadN(11, fail))
+
+	rr.MaxReplyNodes = resp3.DefaultMaxValueCount * 2
+	errStop := errors.New("stop")
+	calls = 0
+	err = rr.ReadN(resp3.DefaultMaxValueCount+1, func(int, *resp3.Reader) error {
+		calls++
+		return errStop
+	})
+	assertError(t, errStop, err)
+	if calls != 1 {
+		t.Errorf("got %d calls, expected 1", calls)
+	}
+}
+
+func TestReaderLastWasChunked(t *testing.T) {
+	rr, _ := newTestReader("$?\r\n;2\r\nhe\r\n;0\r\n*?\r\n:1\r\n.\r\n$2\r\nhe\r\n*1\r\n:1\r\n*-1\r\n")
+
+	if rr.LastWasChunked() {
+		t.Error("got true before reading, expected false")
+	}
+
+	for _, c := range []struct {
+		ty      resp3.Type
+		chunked bool
+	}{
+		{resp3.TypeBlobString, true},
+		{resp3.TypeBlobChunk, false},
+		{resp3.TypeBlobChunk, false},
+		{resp3.TypeArray, true},
+		{resp3.TypeNumber, false},
+		{resp3.TypeEnd, false},
+		{resp3.TypeBlobString, false},
+		{resp3.TypeArray, false},
+		{resp3.TypeNumber, false},
+		{resp3.TypeNull, false},
+	} {
+		ty, err := rr.Discard(false)
+		assertError(t, nil, err)
+		if ty != c.ty {
+			t.Errorf("got type %q, expected %q", ty, c.ty)
+		}
+		if got := rr.LastWasChunked(); got != c.chunked {
+			t.Errorf("got %t after reading %q, expected %t", got, ty, c.chunked)
+		}
+	}
+}
+
+// noProgressReader returns the data from r and afterwards returns (0, nil) on every read.
+type noProgressReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (n *noProgressReader) Read(b []byte) (int, error) {
+	n.reads++
+	if nn, err := n.r.Read(b); nn > 0 {
+		return nn, err
+	}
+	return 0, nil
+}
+
+func TestReaderNoProgress(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   string
+		read func(*resp3.Reader) error
+	}{
+		{
+			name: "Peek",
+			read: func(rr *resp3.Reader) error {
+				_, err := rr.Peek()
+				return err
+			},
+		},
+		{
+			name: "BlobString",
+			in:   "$10\r\nhello",
+			read: func(rr *resp3.Reader) error {
+				_, _, err := rr.ReadBlobString(nil)
+				return err
+			},
+		},
+		{
+			name: "BlobStringCtx",
+			in:   "$10\r\nhello",
+			read: func(rr *resp3.Reader) error {
+				_, _, err := rr.ReadBlobStringCtx(context.Background(), nil)
+				return err
+			},
+		},
+		{
+			name: "Discard",
+			in:   "*2\r\n:1\r\n",
+			read: func(rr *resp3.Reader) error {
+				_, err := rr.Discard(true)
+				return err
+			},
+		},
+		{
+			name: "Number",
+			in:   ":123",
+			read: func(rr *resp3.Reader) error {
+				_, err := rr.ReadNumber()
+				return err
+			},
+		},
+		{
+			name: "SimpleString",
+			in:   "+OK",
+			read: func(rr *resp3.Reader) error {
+				_, err := rr.ReadSimpleString(nil)
+				return err
+			},
+		},
+		{
+			name: "SkipLine",
+			in:   "|hello",
+			read: func(rr *resp3.Reader) error {
+				return rr.SkipLine()
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := &noProgressReader{r: strings.NewReader(c.in)}
+			assertError(t, resp3.ErrNoProgress, c.read(resp3.NewReader(r)))
+			if r.reads > 1000 {
+				t.Errorf("got %d reads, expected reads to be aborted earlier", r.reads)
+			}
+		})
+	}
+}
+
+func TestReaderDoubleDecimalSeparator(t *testing.T) {
+	rr, _ := newTestReader(",1,5\r\n")
+	_, err := rr.ReadDouble()
+	assertError(t, resp3.ErrInvalidDouble, err)
+	if err != nil && !strings.Contains(err.Error(), "1,5") {
+		t.Errorf("got error %q, expected error to contain the value", err)
+	}
+
+	for _, c := range []struct {
+		in  string
+		f   float64
+		b   string
+		err error
+	}{
+		{in: ",1,5\r\n", f: 1.5, b: "1.5"},
+		{in: ",-0,25\r\n", f: -0.25, b: "-0.25"},
+		{in: ",10\r\n", f: 10, b: "10"},
+		{in: ",inf\r\n", f: math.Inf(1), b: "inf"},
+		{in: ",1.5\r\n", f: 1.5, b: "1.5"},
+		{in: ",1,5,0\r\n", err: resp3.ErrInvalidDouble},
+	} {
+		rr, _ := newTestReader(c.in + c.in)
+		rr.DoubleDecimalSeparator = ','
+
+		f, err := rr.ReadDouble()
+		assertError(t, c.err, err)
+		if f != c.f {
+			t.Errorf("got %v for %q, expected %v", f, c.in, c.f)
+		}
+		if err != nil && !strings.Contains(err.Error(), strings.TrimSpace(c.in[1:])) {
+			t.Errorf("got error %q, expected error to contain the original value", err)
+		}
+		if err != nil {
+			continue
+		}
+
+		b, err := rr.ReadDoubleBytes(nil)
+		assertError(t, c.err, err)
+		assertBytes(t, c.b, b)
+	}
+
+	rr, _ = newTestReader(",1.5\r\n")
+	rr.DoubleDecimalSeparator = '.'
+	f, err := rr.ReadDouble()
+	assertError(t, nil, err)
+	if f != 1.5 {
+		t.Errorf("got %v, expected 1.5", f)
+	}
+}
+
+func TestReaderResync(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		err  error
+		rest string
+	}{
+		{err: io.EOF},
+		{in: "garbage", err: resp3.ErrUnexpectedEOL},
+		{in: "garbage\r\n", err: io.EOF},
+		{in: "garbage\r\nmore garbage\r\n", err: io.EOF},
+		{in: "llo\r\n+OK\r\n", rest: "+OK\r\n"},
+		{in: "llo\r\ngarbage\r\n:1\r\n+OK\r\n", rest: ":1\r\n+OK\r\n"},
+		{in: "+OK\r\n:1\r\n", rest: "+OK\r\n:1\r\n"},
+		{in: "a\rb\r\n$5\r\nhello\r\n", rest: "$5\r\nhello\r\n"},
+		{in: "\x01junk\n+OK\r\n:5\r\n", rest: "+OK\r\n:5\r\n"},
+		{in: "a\nb\r\n:1\r\n", rest: ":1\r\n"},
+		{in: "x" + strings.Repeat("+", 8192) + "\r\n:1\r\n", rest: ":1\r\n"},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		assertError(t, c.err, rr.Resync())
+		if c.err != nil {
+			continue
+		}
+		if got := rest(); got != c.rest {
+			t.Errorf("got %q left in input for %q, expected %q", got, c.in, c.rest)
+		}
+	}
+
+	rr, _ := newTestReader("lo\r\n%1\r\n+a\r\n:1\r\n")
+	_, err := rr.ReadSimpleString(nil)
+	assertError(t, resp3.ErrInvalidType, err)
+	assertError(t, nil, rr.Resync())
+	m, err := rr.ReadMapStringAny(nil)
+	assertError(t, nil, err)
+	if len(m) != 1 || m["a"] != int64(1) {
+		t.Errorf("got %v after resync, expected map[a:1]", m)
+	}
+
+	rr, rest := newTestReaderWithRest("xx\r\n@1\r\n")
+	assertError(t, nil, rr.RegisterType('@', func(r *resp3.Reader) error { return r.SkipLine() }))
+	assertError(t, nil, rr.Resync())
+	if got := rest(); got != "@1\r\n" {
+		t.Errorf("got %q left in input, expected %q", got, "@1\r\n")
+	}
+}
+
+func TestReaderEffectiveSingleReadSizeLimit(t *testing.T) {
+	for _, c := range []struct {
+		limit, expected int
+	}{
+		{0, resp3.DefaultSingleReadSizeLimit},
+		{-1, -1},
+		{-100, -1},
+		{1, 1},
+		{1024, 1024},
+	} {
+		rr := resp3.NewReader(strings.NewReader(""))
+		rr.SingleReadSizeLimit = c.limit
+		if got := rr.EffectiveSingleReadSizeLimit(); got != c.expected {
+			t.Errorf("got %d for limit %d, expected %d", got, c.limit, c.expected)
+		}
+	}
+
+	rr, _ := newTestReader("+hello\r\n+hello\r\n")
+	rr.SingleReadSizeLimit = 5
+	_, err := rr.ReadSimpleString(make([]byte, 0, rr.EffectiveSingleReadSizeLimit()))
+	assertError(t, nil, err)
+	rr.SingleReadSizeLimit = 4
+	_, err = rr.ReadSimpleString(make([]byte, 0, rr.EffectiveSingleReadSizeLimit()))
+	assertError(t, resp3.ErrSingleReadSizeLimitExceeded, err)
+}
+
+func TestReaderProtocol(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		in    string
+		read  func(*resp3.Reader) (string, error)
+		s     string
+		err   error
+		resp3 error
+	}{
+		{
+			name: "BigNumber",
+			in:   "$5\r\n-1234\r\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				var n big.Int
+				err := rr.ReadBigNumber(&n)
+				return n.String(), err
+			},
+			s:     "-1234",
+			resp3: resp3.ErrUnexpectedType,
+		},
+		{
+			name: "BigNumberInvalid",
+			in:   "$1\r\na\r\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				return "", rr.ReadBigNumber(new(big.Int))
+			},
+			err:   resp3.ErrInvalidBigNumber,
+			resp3: resp3.ErrUnexpectedType,
+		},
+		{
+			name: "BooleanFalse",
+			in:   ":0\r\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				b, err := rr.ReadBoolean()
+				return strconv.FormatBool(b), err
+			},
+			s:     "false",
+			resp3: resp3.ErrUnexpectedType,
+		},
+		{
+			name: "BooleanTrue",
+			in:   ":1\r\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				b, err := rr.ReadBoolean()
+				return strconv.FormatBool(b), err
+			},
+			s:     "true",
+			resp3: resp3.ErrUnexpectedType,
+		},
+		{
+			name: "Double",
+			in:   "$3\r\n1.5\r\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				f, err := rr.ReadDouble()
+				return strconv.FormatFloat(f, 'f', -1, 64), err
+			},
+			s:     "1.5",
+			resp3: resp3.ErrUnexpectedType,
+		},
+		{
+			name: "DoubleInvalid",
+			in:   "$1\r\na\r\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				_, err := rr.ReadDouble()
+				return "", err
+			},
+			err:   resp3.ErrInvalidDouble,
+			resp3: resp3.ErrUnexpectedType,
+		},
+		{
+			name: "Map",
+			in:   "*4\r\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				n, _, err := rr.ReadMapHeader()
+				return strconv.FormatInt(n, 10), err
+			},
+			s:     "2",
+			resp3: resp3.ErrUnexpectedType,
+		},
+		{
+			name: "MapOdd",
+			in:   "*3\r\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				n, _, err := rr.ReadMapHeader()
+				return strconv.FormatInt(n, 10), err
+			},
+			s:     "0",
+			err:   resp3.ErrInvalidAggregateTypeLength,
+			resp3: resp3.ErrUnexpectedType,
+		},
+		{
+			name: "Push",
+			in:   "*2\r\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				n, _, err := rr.ReadPushHeader()
+				return strconv.FormatInt(n, 10), err
+			},
+			s:     "2",
+			resp3: resp3.ErrUnexpectedType,
+		},
+		{
+			name: "Set",
+			in:   "*2\r\n",
+			read: func(rr *resp3.Reader) (string, error) {
+				n, _, err := rr.ReadSetHeader()
+				return strconv.FormatInt(n, 10), err
+			},
+			s:     "2",
+			resp3: resp3.ErrUnexpectedType,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rr, _ := newTestReader(c.in)
+			_, err := c.read(rr)
+			assertError(t, c.resp3, err)
+
+			rr, _ = newTestReader(c.in)
+			rr.Protocol = 2
+			s, err := c.read(rr)
+			assertError(t, c.err, err)
+			if s != c.s {
+				t.Errorf("got %q, expected %q", s, c.s)
+			}
+		})
+	}
+}
@@ -4,30 +4,76 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 var (
 	// ErrSingleReadSizeLimitExceeded is returned when reading blob or simple values longer than the configured limit.
 	ErrSingleReadSizeLimitExceeded = errors.New("single read size limit exceeded")
 
+	// ErrAggregateTooLong is returned by Reader when nested aggregates declare more values than allowed by
+	// MaxReplyNodes.
+	ErrAggregateTooLong = errors.New("aggregate too long")
+
+	// ErrClosed is returned when reading from a Reader or writing to a Writer that was reset using a nil io.Reader or
+	// io.Writer, or when using a ChunkWriter that was already closed.
+	ErrClosed = errors.New("reader or writer is closed")
+
+	// ErrDuplicateSetMember is returned by Reader when reading a set that contains the same member more than once.
+	ErrDuplicateSetMember = errors.New("duplicate set member")
+
+	// ErrElementCountMismatch is returned by Writer when Debug is enabled and the number of written elements does not
+	// match the declared size of an aggregate.
+	ErrElementCountMismatch = errors.New("element count mismatch")
+
+	// ErrEmptyCommand is returned when writing a command without any arguments.
+	ErrEmptyCommand = errors.New("command must have at least one argument")
+
+	// ErrEmptyError is returned by Writer when writing an empty error while StrictErrors is true or a nil error using
+	// WriteGoError and by Reader when reading an empty blob error while StrictBlobErrors is true.
+	ErrEmptyError = errors.New("error must not be empty")
+
 	// ErrInvalidAggregateTypeLength is returned when reading or writing an aggregate type header with invalid length.
 	ErrInvalidAggregateTypeLength = errors.New("invalid aggregate type length")
 
 	// ErrInvalidBigNumber is returned when decoding an invalid big number.
 	ErrInvalidBigNumber = errors.New("invalid big number")
 
+	// ErrInvalidBlobChunkLength is returned when reading a blob chunk with a negative length.
+	//
+	// Unlike for blob strings, a length of -1 does not denote a null value for blob chunks.
+	//
+	// ErrInvalidBlobChunkLength wraps ErrInvalidBlobLength, so errors.Is matches both.
+	ErrInvalidBlobChunkLength = fmt.Errorf("%w: blob chunk length must be >= 0", ErrInvalidBlobLength)
+
 	// ErrInvalidBlobLength is returned when reading or writing a blob string with an invalid length.
+	//
+	// Blob chunks with an invalid length use ErrInvalidBlobChunkLength, which wraps ErrInvalidBlobLength.
 	ErrInvalidBlobLength = errors.New("blob string length must be >= 0")
 
 	// ErrInvalidBoolean is returned when decoding an invalid boolean.
 	ErrInvalidBoolean = errors.New("invalid boolean")
 
-	// ErrInvalidDouble is returned when decoding an invalid double.
+	// ErrInvalidDouble is returned when decoding an invalid double and by Writer.WriteDouble when DoublePositiveInf is
+	// invalid.
 	ErrInvalidDouble = errors.New("invalid double")
 
+	// ErrInvalidDoubleFormat is returned by Writer.WriteDouble when the Writer was configured with an unsupported
+	// format using WithDoubleFormat.
+	ErrInvalidDoubleFormat = errors.New("invalid double format")
+
+	// ErrInvalidErrorCode is returned when writing an error with a code that does not consist of uppercase letters.
+	ErrInvalidErrorCode = errors.New("error codes must consist of one or more uppercase letters")
+
+	// ErrInvalidLineEnding is returned by Writer when writing using a LineEnding that is neither \r\n nor \n.
+	ErrInvalidLineEnding = errors.New("line ending must be either \r\n or \n")
+
 	// ErrInvalidNumber is returned when decoding an invalid number.
 	ErrInvalidNumber = errors.New("invalid number")
 
+	// ErrInvalidPushMessage is returned when reading a pub/sub push message with an unknown kind or invalid shape.
+	ErrInvalidPushMessage = errors.New("invalid push message")
+
 	// ErrInvalidSimpleValue is returned when decoding or encoding a simple error/string that contains either \r or \n.
 	ErrInvalidSimpleValue = errors.New("simple errors/strings must not contain \r or \n or both")
 
@@ -38,16 +84,125 @@ var (
 	// less than 3 characters.
 	ErrInvalidVerbatimString = errors.New("invalid verbatim string")
 
+	// ErrKnownType is returned by Reader.RegisterType when trying to register a custom type for the prefix byte of a
+	// known type.
+	ErrKnownType = errors.New("can not register known type")
+
+	// ErrNoProgress is returned by Reader when the underlying io.Reader repeatedly returns neither data nor an error.
+	//
+	// Reads are aborted after 100 consecutive empty reads instead of retrying forever. ErrNoProgress is the same
+	// value as io.ErrNoProgress.
+	ErrNoProgress = io.ErrNoProgress
+
+	// ErrOutOfRange is returned when reading a number that is outside of the expected range.
+	ErrOutOfRange = errors.New("number out of range")
+
 	// ErrOverflow is returned when decoding a number that overflows or underflows an int64.
 	ErrOverflow = errors.New("number overflowed")
 
+	// ErrReplyTooLarge is returned by Reader when the size of a value exceeds the configured MaxReplyBytes.
+	ErrReplyTooLarge = errors.New("reply too large")
+
+	// ErrTooManyChunks is returned by Reader when a streamed value consists of more chunks or elements than allowed by
+	// MaxChunks.
+	ErrTooManyChunks = errors.New("too many chunks")
+
+	// ErrTypeMismatch is returned when a value can not be converted from or to a Go type.
+	ErrTypeMismatch = errors.New("type mismatch")
+
 	// ErrUnexpectedEOL is returned when reading a line that does not end in \r.\n
 	ErrUnexpectedEOL = errors.New("unexpected EOL")
 
-	// ErrUnexpectedType is returned by Reader when encountering an unknown type.
+	// ErrUnexpectedType is returned by Reader when encountering an unknown type and by Writer when writing a Value
+	// with an unknown type.
 	ErrUnexpectedType = errors.New("encountered unexpected RESP type")
+
+	// ErrUnknownVerbatimFormat is returned when reading a verbatim string with a format that is not allowed.
+	ErrUnknownVerbatimFormat = errors.New("unknown verbatim string format")
+
+	// ErrValueTooDeep is returned by Writer.WriteValue when writing a value that is nested too deeply, for example
+	// because it contains a cycle, and by Reader when reading aggregates nested deeper than Reader.MaxReplyDepth.
+	ErrValueTooDeep = errors.New("value nested too deeply")
 )
 
+// RedisError represents an error value (either a simple or a blob error) read from a RESP stream.
+type RedisError struct {
+	s string
+	n int
+}
+
+var _ error = (*RedisError)(nil)
+
+func newRedisError(b []byte) *RedisError {
+	s := string(b)
+	n := strings.IndexByte(s, ' ')
+	if n < 0 {
+		n = len(s)
+	}
+	return &RedisError{s: s, n: n}
+}
+
+// Code returns the error code, which is the first space-delimited token of the error, e.g. ERR or WRONGTYPE.
+func (e *RedisError) Code() string {
+	return e.s[:e.n]
+}
+
+// Error implements the error interface and returns the full error including the error code.
+func (e *RedisError) Error() string {
+	return e.s
+}
+
+// Message returns the error message without the error code.
+func (e *RedisError) Message() string {
+	if e.n == len(e.s) {
+		return ""
+	}
+	return e.s[e.n+1:]
+}
+
+// PushMessage represents a pub/sub message sent using the RESP push type, as returned by Reader.ReadPushMessage.
+type PushMessage struct {
+	// Kind is the kind of the message, for example message, pmessage or subscribe.
+	Kind string
+
+	// Channel is the channel the message was sent to, or the channel that was subscribed or unsubscribed.
+	Channel string
+
+	// Pattern is the pattern matching the channel for pmessage, or the pattern that was subscribed or unsubscribed.
+	Pattern string
+
+	// Payload is the content of the message.
+	Payload []byte
+
+	// Count is the number of active subscriptions for subscribe and unsubscribe messages.
+	Count int64
+}
+
+// VerbatimString represents a verbatim string read from a RESP stream.
+type VerbatimString struct {
+	// Format is the 3 character format of the string, for example txt or mkd.
+	Format string
+
+	// Text is the content of the string, without the format prefix.
+	Text string
+}
+
+// isBigNumber checks if b is a decimal integer with an optional sign, as accepted by big.Int.SetString.
+func isBigNumber(b []byte) bool {
+	if len(b) > 0 && (b[0] == '-' || b[0] == '+') {
+		b = b[1:]
+	}
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // Type is an enum of the known RESP types with the values of the constants being the single-byte prefix characters.
 type Type byte
 
@@ -117,10 +272,29 @@ func (t Type) String() string {
 	return string(t)
 }
 
+// IsValidTypeByte returns true if b is the prefix byte of one of the RESP types defined in this package.
+func IsValidTypeByte(b byte) bool {
+	return types[b] != TypeInvalid
+}
+
+// ValidTypeBytes returns all RESP types defined in this package, ordered by their prefix byte.
+//
+// A new slice is returned on each call, so it can be freely modified by the caller.
+func ValidTypeBytes() []Type {
+	var ts []Type
+	for _, t := range types {
+		if t != TypeInvalid {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
 // ReadWriter embeds a Reader and a Writer in a single allocation for an io.ReadWriter.
 //
 // A single Reader and a single Writer method can be called concurrently, given the Read and Write methods of the
-// underlying io.ReadWriter are safe for concurrent use.
+// underlying io.ReadWriter are safe for concurrent use. Flush counts as a Writer method and must not be called
+// concurrently with writes.
 type ReadWriter struct {
 	Reader
 	Writer
@@ -133,10 +307,50 @@ func NewReadWriter(rw io.ReadWriter) *ReadWriter {
 	return &rrw
 }
 
+// NewReadWriterBuffered returns a new ReadWriter that uses the given io.ReadWriter and buffers writes using a buffer
+// of the given size, as if the Writer was configured using WithBuffer.
+//
+// Only writes are buffered, reads are handled the same as for NewReadWriter. Buffered data must be written to the
+// underlying io.ReadWriter using Flush.
+func NewReadWriterBuffered(rw io.ReadWriter, writeBufSize int) *ReadWriter {
+	var rrw ReadWriter
+	WithBuffer(writeBufSize)(&rrw.Writer)
+	rrw.Reset(rw)
+	return &rrw
+}
+
+// Flush writes any data buffered by the embedded Writer to the underlying io.ReadWriter, by calling Writer.Flush.
+//
+// Flush is defined explicitly, instead of relying on the method being promoted from the embedded Writer, so that it
+// stays unambiguous even if Reader gets a method with the same name. Like all Writer methods, Flush must not be
+// called concurrently with writes.
+func (rrw *ReadWriter) Flush() error {
+	return rrw.Writer.Flush()
+}
+
 // Reset resets the embedded Reader and Writer to use the given io.ReadWriter.
 //
+// If rw is nil, all references to the previous io.ReadWriter are released and all following reads and writes return
+// ErrClosed until Reset is called again. See Reader.Reset for an example.
+//
 // Reset must not be called concurrently with any other method.
 func (rrw *ReadWriter) Reset(rw io.ReadWriter) {
+	if rw == nil {
+		rrw.Reader.Reset(nil)
+		rrw.Writer.Reset(nil)
+		return
+	}
 	rrw.Reader.Reset(rw)
 	rrw.Writer.Reset(rw)
 }
+
+// closed is used as io.Reader and io.Writer after calling Reset with a nil value.
+type closed struct{}
+
+func (closed) Read([]byte) (int, error) {
+	return 0, ErrClosed
+}
+
+func (closed) Write([]byte) (int, error) {
+	return 0, ErrClosed
+}
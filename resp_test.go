@@ -5,7 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"math"
 	"math/big"
 	"strconv"
 	"strings"
@@ -232,6 +232,21 @@ func copyReaderToWriter(tb testing.TB, rw *resp3.ReadWriter, buf []byte) {
 	}
 }
 
+func TestDoubleNegativeZeroRoundTrip(t *testing.T) {
+	var b bytes.Buffer
+	rw := resp3.NewReadWriter(&b)
+	if err := rw.WriteDouble(math.Copysign(0, -1)); err != nil {
+		t.Fatalf("failed to write double: %s", err)
+	}
+	f, err := rw.ReadDouble()
+	if err != nil {
+		t.Fatalf("failed to read double: %s", err)
+	}
+	if f != 0 || !math.Signbit(f) {
+		t.Errorf("got %f, expected -0", f)
+	}
+}
+
 func TestTypeString(t *testing.T) {
 	for ty := resp3.Type(0); ty < ^resp3.Type(0); ty++ {
 		if ts := ty.String(); ts != fmt.Sprint(ty) {
@@ -240,6 +255,41 @@ func TestTypeString(t *testing.T) {
 	}
 }
 
+func TestValidTypeBytes(t *testing.T) {
+	ts := resp3.ValidTypeBytes()
+	if len(ts) != 17 {
+		t.Errorf("got %d types, expected %d", len(ts), 17)
+	}
+	for i := 1; i < len(ts); i++ {
+		if ts[i-1] >= ts[i] {
+			t.Errorf("got %q before %q, expected types to be ordered", ts[i-1], ts[i])
+		}
+	}
+
+	valid := make(map[byte]bool)
+	for _, ty := range ts {
+		valid[byte(ty)] = true
+	}
+	for b := 0; b < 256; b++ {
+		if got := resp3.IsValidTypeByte(byte(b)); got != valid[byte(b)] {
+			t.Errorf("got %t for %q, expected %t", got, byte(b), valid[byte(b)])
+		}
+	}
+	for _, ty := range []resp3.Type{resp3.TypeArray, resp3.TypeBlobChunk, resp3.TypeEnd, resp3.TypeVerbatimString} {
+		if !resp3.IsValidTypeByte(byte(ty)) {
+			t.Errorf("got false for %q, expected true", ty)
+		}
+	}
+	if resp3.IsValidTypeByte(byte(resp3.TypeInvalid)) || resp3.IsValidTypeByte('A') {
+		t.Error("got true for invalid type byte, expected false")
+	}
+
+	ts[0] = resp3.TypeInvalid
+	if resp3.ValidTypeBytes()[0] == resp3.TypeInvalid {
+		t.Error("modifying the returned slice changed the result of following calls")
+	}
+}
+
 type simpleReadWriter struct {
 	io.Reader
 	io.Writer
@@ -318,11 +368,54 @@ func TestReadWriter(t *testing.T) {
 	}
 }
 
+func TestReadWriterBuffered(t *testing.T) {
+	var out bytes.Buffer
+
+	rw := resp3.NewReadWriterBuffered(&simpleReadWriter{
+		Reader: strings.NewReader("+OK\r\n"),
+		Writer: &out,
+	}, 64)
+
+	if err := rw.WriteSimpleString([]byte("hello")); err != nil {
+		t.Fatalf("failed to write simple string: %s", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("got %q before flush, expected no output", out.String())
+	}
+
+	b, err := rw.ReadSimpleString(nil)
+	if err != nil {
+		t.Fatalf("failed to read simple string: %s", err)
+	}
+	assertBytes(t, "OK", b)
+
+	if err := rw.Flush(); err != nil {
+		t.Fatalf("failed to flush: %s", err)
+	}
+	assertBytes(t, "+hello\r\n", out.Bytes())
+}
+
+func TestReadWriterFlush(t *testing.T) {
+	var out bytes.Buffer
+
+	rw := resp3.NewReadWriter(&simpleReadWriter{
+		Reader: strings.NewReader(""),
+		Writer: &out,
+	})
+	if err := rw.Flush(); err != nil {
+		t.Errorf("got error %q from unbuffered flush, expected no error", err)
+	}
+
+	rw.Reset(nil)
+	assertError(t, resp3.ErrClosed, rw.Flush())
+}
+
 func BenchmarkReadWriter(b *testing.B) {
 	in := strings.NewReader(testReadWriterInput)
+	out := &resp3.CountingWriter{}
 	srw := &simpleReadWriter{
 		Reader: in,
-		Writer: ioutil.Discard,
+		Writer: out,
 	}
 
 	rw := resp3.NewReadWriter(nil)
@@ -339,4 +432,8 @@ func BenchmarkReadWriter(b *testing.B) {
 
 		copyReaderToWriter(b, rw, buf)
 	}
+
+	if written, _ := out.Counts(); written != int64(b.N*len(testReadWriterInput)) {
+		b.Errorf("got %d written bytes, expected %d", written, b.N*len(testReadWriterInput))
+	}
 }
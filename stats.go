@@ -0,0 +1,21 @@
+package resp3
+
+// Stats holds the number of values read by a Reader for each type.
+//
+// See Reader.Stats for details on how values are counted.
+//
+// Stats is not safe for concurrent use. Reading counts while a Reader updates the Stats must be synchronized with the
+// reads, for example by only scraping the Stats between replies.
+type Stats struct {
+	counts [256]uint64
+}
+
+// Count returns the number of values of the given type that were read.
+func (s *Stats) Count(ty Type) uint64 {
+	return s.counts[ty]
+}
+
+// Reset sets all counts to 0.
+func (s *Stats) Reset() {
+	s.counts = [256]uint64{}
+}
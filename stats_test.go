@@ -0,0 +1,60 @@
+package resp3_test
+
+import (
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+func TestReaderStats(t *testing.T) {
+	rr, _ := newTestReader("*3\r\n:1\r\n$-1\r\n%1\r\n+a\r\n#t\r\n$?\r\n;2\r\nhe\r\n;0\r\n~?\r\n,1.5\r\n.\r\n_\r\n")
+
+	var stats resp3.Stats
+	rr.Stats = &stats
+
+	for i := 0; i < 3; i++ {
+		if _, err := rr.Peek(); err != nil {
+			t.Fatalf("failed to peek: %s", err)
+		}
+	}
+	if got := stats.Count(resp3.TypeArray); got != 0 {
+		t.Errorf("got %d arrays after peek, expected 0", got)
+	}
+
+	if _, err := rr.ReadValue(); err != nil {
+		t.Fatalf("failed to read value: %s", err)
+	}
+	if _, err := rr.Discard(true); err != nil {
+		t.Fatalf("failed to discard value: %s", err)
+	}
+	if _, err := rr.Discard(true); err != nil {
+		t.Fatalf("failed to discard value: %s", err)
+	}
+	if err := rr.ReadNull(); err != nil {
+		t.Fatalf("failed to read null: %s", err)
+	}
+
+	for ty, expected := range map[resp3.Type]uint64{
+		resp3.TypeArray:          1,
+		resp3.TypeBlobChunk:      2,
+		resp3.TypeBlobString:     2,
+		resp3.TypeBoolean:        1,
+		resp3.TypeDouble:         1,
+		resp3.TypeEnd:            1,
+		resp3.TypeMap:            1,
+		resp3.TypeNull:           1,
+		resp3.TypeNumber:         1,
+		resp3.TypeSet:            1,
+		resp3.TypeSimpleString:   1,
+		resp3.TypeVerbatimString: 0,
+	} {
+		if got := stats.Count(ty); got != expected {
+			t.Errorf("got count %d for %q, expected %d", got, ty, expected)
+		}
+	}
+
+	stats.Reset()
+	if got := stats.Count(resp3.TypeArray); got != 0 {
+		t.Errorf("got %d arrays after reset, expected 0", got)
+	}
+}
@@ -0,0 +1,314 @@
+package resp3
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// structField describes a struct field tagged with a resp3 struct tag.
+type structField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+// structInfo holds the tagged fields of a struct type, in the order of declaration.
+type structInfo struct {
+	fields []structField
+	byName map[string]int
+}
+
+var structInfoCache sync.Map // map[reflect.Type]*structInfo
+
+func getStructInfo(t reflect.Type) *structInfo {
+	if si, ok := structInfoCache.Load(t); ok {
+		return si.(*structInfo)
+	}
+
+	si := &structInfo{byName: make(map[string]int)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		tag, ok := f.Tag.Lookup("resp3")
+		if !ok || tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.IndexByte(tag, ','); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		if name == "" {
+			name = f.Name
+		}
+		sf := structField{name: name, index: i}
+		for _, opt := range strings.Split(opts, ",") {
+			if opt == "omitempty" {
+				sf.omitEmpty = true
+			}
+		}
+		si.byName[name] = len(si.fields)
+		si.fields = append(si.fields, sf)
+	}
+
+	actual, _ := structInfoCache.LoadOrStore(t, si)
+	return actual.(*structInfo)
+}
+
+// ReadInto reads a map and stores its values in the struct pointed to by v.
+//
+// Fields are matched to map keys using the resp3 struct tag, for example:
+//
+//	type Info struct {
+//		Name    string `resp3:"name"`
+//		Port    int    `resp3:"port"`
+//		Enabled bool   `resp3:"enabled"`
+//	}
+//
+// Only exported fields with a resp3 tag are considered. If the tag has no name, the field name is used instead.
+// Map keys without matching field are skipped and fields without matching map key are left unchanged.
+//
+// The following field types are supported: string, []byte, bool, all int, uint and float types as well as structs,
+// which are decoded from nested maps. Values can be any scalar type and are converted to the field type by parsing
+// their textual representation using the strconv package, so a blob string "123" can be stored in an int field.
+// Null values set the field to its zero value.
+//
+// If a value can not be converted to the type of the matching field, or if v is not a non-nil pointer to a struct,
+// an error wrapping ErrTypeMismatch is returned.
+func (rr *Reader) ReadInto(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: expected non-nil pointer to struct, got %T", ErrTypeMismatch, v)
+	}
+	return rr.readStruct(rv.Elem())
+}
+
+func (rr *Reader) readStruct(v reflect.Value) error {
+	n, chunked, err := rr.ReadMapHeader()
+	if err != nil {
+		return err
+	}
+
+	si := getStructInfo(v.Type())
+
+	var buf [64]byte
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil || !more {
+				return err
+			}
+		}
+
+		key, _, err := rr.readScalarText(buf[:0])
+		if err != nil {
+			return err
+		}
+
+		idx, ok := si.byName[string(key)]
+		if !ok {
+			if _, err := rr.Discard(true); err != nil {
+				return err
+			}
+			continue
+		}
+
+		f := si.fields[idx]
+		if err := rr.readField(v.Field(f.index)); err != nil {
+			return fmt.Errorf("failed to decode field %s: %w", f.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (rr *Reader) readField(fv reflect.Value) error {
+	t, err := rr.Peek()
+	if err != nil {
+		return wrapEOF(err, "")
+	}
+
+	if fv.Kind() == reflect.Struct {
+		if t == TypeNull {
+			fv.Set(reflect.Zero(fv.Type()))
+			return rr.ReadNull()
+		}
+		return rr.readStruct(fv)
+	}
+
+	if fv.Kind() == reflect.Bool && t == TypeNumber && (rr.BooleanFromInteger || rr.resp2()) {
+		v, err := rr.ReadBoolean()
+		if err != nil {
+			return err
+		}
+		fv.SetBool(v)
+		return nil
+	}
+
+	var buf [64]byte
+	b, t, err := rr.readScalarText(buf[:0])
+	if err != nil {
+		return err
+	}
+	if t == TypeNull {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(string(b))
+		return nil
+	case reflect.Bool:
+		v, err := strconv.ParseBool(string(b))
+		if err != nil {
+			return fmt.Errorf("%w: can not convert %q to bool", ErrTypeMismatch, b)
+		}
+		fv.SetBool(v)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(string(b), 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: can not convert %q to %s", ErrTypeMismatch, b, fv.Type())
+		}
+		fv.SetInt(v)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(string(b), 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: can not convert %q to %s", ErrTypeMismatch, b, fv.Type())
+		}
+		fv.SetUint(v)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(string(b), fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: can not convert %q to %s", ErrTypeMismatch, b, fv.Type())
+		}
+		fv.SetFloat(v)
+		return nil
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			fv.SetBytes(append([]byte(nil), b...))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: unsupported field type %s", ErrTypeMismatch, fv.Type())
+}
+
+// readScalarText reads a scalar value and appends its textual representation to dst.
+//
+// Big numbers, booleans, doubles and numbers are read using the corresponding typed methods, so that they are
+// validated the same way and their canonical representation is appended to dst.
+//
+// For null values, dst is returned unchanged.
+func (rr *Reader) readScalarText(dst []byte) ([]byte, Type, error) {
+	t, err := rr.Peek()
+	if err != nil {
+		return nil, t, wrapEOF(err, "")
+	}
+
+	switch t {
+	case TypeBlobString:
+		var chunked bool
+		if dst, chunked, err = rr.ReadBlobString(dst); err == nil && chunked {
+			dst, err = rr.ReadBlobChunks(dst)
+		}
+	case TypeBigNumber:
+		var n big.Int
+		if err = rr.ReadBigNumber(&n); err == nil {
+			dst = n.Append(dst, 10)
+		}
+	case TypeBoolean:
+		var b bool
+		if b, err = rr.ReadBoolean(); err == nil {
+			dst = strconv.AppendBool(dst, b)
+		}
+	case TypeDouble:
+		var f float64
+		if f, err = rr.ReadDouble(); err == nil {
+			dst = strconv.AppendFloat(dst, f, 'g', -1, 64)
+		}
+	case TypeNumber:
+		var n int64
+		if n, err = rr.ReadNumber(); err == nil {
+			dst = strconv.AppendInt(dst, n, 10)
+		}
+	case TypeSimpleString:
+		dst, err = rr.readSimple(t, dst)
+	case TypeNull:
+		err = rr.ReadNull()
+	case TypeVerbatimString:
+		n := len(dst)
+		if dst, err = rr.ReadVerbatimString(dst); err == nil {
+			dst = append(dst[:n], dst[n+verbatimPrefixLength+1:]...)
+		}
+	default:
+		return nil, t, fmt.Errorf("%w: expected scalar value, got %q", ErrTypeMismatch, t)
+	}
+
+	if err != nil {
+		return nil, t, err
+	}
+	return dst, t, nil
+}
+
+// WriteStruct writes the struct v, or the struct pointed to by v, as a map.
+//
+// Only exported fields with a resp3 struct tag are written, using the tag as map key, in the order in which they are
+// declared. If the tag has no name, the field name is used instead. Field values are written using WriteValue, so
+// nested structs are written as nested maps.
+//
+// If the tag contains the omitempty option, the field is skipped if it has the zero value for its type, for example:
+//
+//	type Info struct {
+//		Name  string `resp3:"name"`
+//		Error string `resp3:"error,omitempty"`
+//	}
+//
+// If v is not a struct or a non-nil pointer to a struct, an error wrapping ErrTypeMismatch is returned.
+func (rw *Writer) WriteStruct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: expected struct or non-nil pointer to struct, got %T", ErrTypeMismatch, v)
+	}
+	return rw.writeStruct(rv)
+}
+
+func (rw *Writer) writeStruct(v reflect.Value) error {
+	si := getStructInfo(v.Type())
+
+	var n int64
+	for _, f := range si.fields {
+		if !f.omitEmpty || !v.Field(f.index).IsZero() {
+			n++
+		}
+	}
+
+	if err := rw.WriteMapHeader(n); err != nil {
+		return err
+	}
+
+	for _, f := range si.fields {
+		fv := v.Field(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		if err := rw.WriteValue(f.name); err != nil {
+			return err
+		}
+		if err := rw.writeReflectValue(fv); err != nil {
+			return fmt.Errorf("failed to encode field %s: %w", f.name, err)
+		}
+	}
+
+	return nil
+}
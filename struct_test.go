@@ -0,0 +1,268 @@
+package resp3_test
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+type testStructInner struct {
+	A string `resp3:"a"`
+}
+
+type testStruct struct {
+	String    string          `resp3:"string"`
+	Bytes     []byte          `resp3:"bytes"`
+	Bool      bool            `resp3:"bool"`
+	Int       int             `resp3:"int"`
+	Int8      int8            `resp3:"int8"`
+	Uint16    uint16          `resp3:"uint16"`
+	Float32   float32         `resp3:"float32"`
+	Float64   float64         `resp3:"float64"`
+	Inner     testStructInner `resp3:"inner"`
+	NoName    string          `resp3:",omitempty"`
+	Ignored   string          `resp3:"-"`
+	Untagged  string
+	Unsupport []string `resp3:"unsupported"`
+
+	unexported string `resp3:"unexported"`
+}
+
+func TestReaderReadInto(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   string
+		v    testStruct
+		err  error
+		rest string
+	}{
+		{name: "Empty", in: "%0\r\n"},
+		{name: "EOF", err: resp3.ErrUnexpectedEOL},
+		{name: "NoMap", in: "*0\r\n", err: resp3.ErrUnexpectedType},
+		{
+			name: "BlobStrings",
+			in: "%9\r\n" +
+				"$6\r\nstring\r\n$5\r\nhello\r\n" +
+				"$5\r\nbytes\r\n$5\r\nworld\r\n" +
+				"$4\r\nbool\r\n$4\r\ntrue\r\n" +
+				"$3\r\nint\r\n$4\r\n-123\r\n" +
+				"$4\r\nint8\r\n$3\r\n127\r\n" +
+				"$6\r\nuint16\r\n$5\r\n65535\r\n" +
+				"$7\r\nfloat32\r\n$3\r\n1.5\r\n" +
+				"$7\r\nfloat64\r\n$4\r\n-inf\r\n" +
+				"$6\r\nNoName\r\n$1\r\nx\r\n",
+			v: testStruct{
+				String:  "hello",
+				Bytes:   []byte("world"),
+				Bool:    true,
+				Int:     -123,
+				Int8:    127,
+				Uint16:  65535,
+				Float32: 1.5,
+				Float64: math.Inf(-1),
+				NoName:  "x",
+			},
+		},
+		{
+			name: "NativeTypes",
+			in: "%6\r\n" +
+				"+string\r\n=9\r\ntxt:hello\r\n" +
+				"+bool\r\n#t\r\n" +
+				"+int\r\n:-123\r\n" +
+				"+uint16\r\n(65535\r\n" +
+				"+float64\r\n,1.25\r\n" +
+				"+bytes\r\n$?\r\n;2\r\nwo\r\n;3\r\nrld\r\n;0\r\n",
+			v: testStruct{
+				String:  "hello",
+				Bytes:   []byte("world"),
+				Bool:    true,
+				Int:     -123,
+				Uint16:  65535,
+				Float64: 1.25,
+			},
+		},
+		{
+			name: "Streamed",
+			in:   "%?\r\n+string\r\n+hello\r\n+int\r\n:1\r\n.\r\n",
+			v:    testStruct{String: "hello", Int: 1},
+		},
+		{
+			name: "RESP2",
+			in:   "*4\r\n$6\r\nstring\r\n$5\r\nhello\r\n$3\r\nint\r\n$1\r\n1\r\n",
+			err:  resp3.ErrUnexpectedType,
+		},
+		{
+			name: "Nested",
+			in:   "%1\r\n+inner\r\n%2\r\n+a\r\n+hello\r\n+b\r\n+world\r\n",
+			v:    testStruct{Inner: testStructInner{A: "hello"}},
+		},
+		{
+			name: "Null",
+			in:   "%2\r\n+string\r\n_\r\n+inner\r\n_\r\n",
+		},
+		{
+			name: "Skipped",
+			in: "%5\r\n" +
+				"+unknown\r\n*2\r\n:1\r\n%1\r\n:1\r\n:2\r\n" +
+				"+Ignored\r\n+x\r\n" +
+				"+Untagged\r\n+x\r\n" +
+				"+unexported\r\n+x\r\n" +
+				"+int\r\n:1\r\n",
+			v: testStruct{Int: 1},
+		},
+		{name: "InvalidBool", in: "%1\r\n+bool\r\n+maybe\r\n", err: resp3.ErrTypeMismatch},
+		{name: "InvalidInt", in: "%1\r\n+int\r\n+1.5\r\n", err: resp3.ErrTypeMismatch},
+		{name: "InvalidUint", in: "%1\r\n+uint16\r\n:-1\r\n", err: resp3.ErrTypeMismatch},
+		{name: "InvalidFloat", in: "%1\r\n+float32\r\n+abc\r\n", err: resp3.ErrTypeMismatch},
+		{name: "Overflow", in: "%1\r\n+int8\r\n:128\r\n", err: resp3.ErrTypeMismatch},
+		{
+			name: "NativeTypesAsString",
+			in:   "%1\r\n+string\r\n,1.50\r\n",
+			v:    testStruct{String: "1.5"},
+		},
+		{name: "InvalidNativeBigNumber", in: "%1\r\n+string\r\n(1x\r\n", err: resp3.ErrInvalidBigNumber},
+		{name: "InvalidNativeBool", in: "%1\r\n+bool\r\n#x\r\n", err: resp3.ErrInvalidBoolean},
+		{name: "InvalidNativeDouble", in: "%1\r\n+string\r\n,abc\r\n", err: resp3.ErrInvalidDouble},
+		{name: "InvalidNativeNumber", in: "%1\r\n+string\r\n:abc\r\n", err: resp3.ErrInvalidNumber},
+		{name: "NumberAsBool", in: "%1\r\n+bool\r\n:2\r\n", err: resp3.ErrTypeMismatch},
+		{name: "Aggregate", in: "%1\r\n+string\r\n*0\r\n", err: resp3.ErrTypeMismatch},
+		{name: "Unsupported", in: "%1\r\n+unsupported\r\n+a\r\n", err: resp3.ErrTypeMismatch},
+		{name: "AggregateKey", in: "%1\r\n*0\r\n+a\r\n", err: resp3.ErrTypeMismatch},
+		{name: "Rest", in: "%1\r\n+int\r\n:1\r\n+OK\r\n", v: testStruct{Int: 1}, rest: "+OK\r\n"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rr, rest := newTestReaderWithRest(c.in)
+			var v testStruct
+			assertError(t, c.err, rr.ReadInto(&v))
+			if c.err != nil {
+				return
+			}
+			if !reflect.DeepEqual(v, c.v) {
+				t.Errorf("got %#v, expected %#v", v, c.v)
+			}
+			if got := rest(); got != c.rest {
+				t.Errorf("got %q left in input, expected %q", got, c.rest)
+			}
+		})
+	}
+
+	t.Run("BooleanFromInteger", func(t *testing.T) {
+		rr, _ := newTestReader("%1\r\n+bool\r\n:2\r\n")
+		rr.BooleanFromInteger = true
+		var v testStruct
+		assertError(t, nil, rr.ReadInto(&v))
+		if !v.Bool {
+			t.Error("got false, expected true")
+		}
+	})
+
+	t.Run("DoubleDecimalSeparator", func(t *testing.T) {
+		rr, _ := newTestReader("%1\r\n+float64\r\n,1;25\r\n")
+		rr.DoubleDecimalSeparator = ';'
+		var v testStruct
+		assertError(t, nil, rr.ReadInto(&v))
+		if v.Float64 != 1.25 {
+			t.Errorf("got %f, expected 1.25", v.Float64)
+		}
+	})
+
+	t.Run("InvalidTarget", func(t *testing.T) {
+		for _, v := range []interface{}{nil, testStruct{}, (*testStruct)(nil), new(int)} {
+			rr, _ := newTestReader("%0\r\n")
+			assertError(t, resp3.ErrTypeMismatch, rr.ReadInto(v))
+		}
+	})
+}
+
+func TestWriterWriteStruct(t *testing.T) {
+	type inner struct {
+		A int `resp3:"a"`
+	}
+
+	type omit struct {
+		A string `resp3:"a,omitempty"`
+		B int    `resp3:"b,omitempty"`
+		C *inner `resp3:"c,omitempty"`
+		D string `resp3:"d"`
+	}
+
+	for _, c := range []struct {
+		name string
+		v    interface{}
+		s    string
+		err  error
+	}{
+		{
+			name: "Fields",
+			v: testStruct{
+				String:  "hello",
+				Bytes:   []byte("world"),
+				Bool:    true,
+				Int:     -1,
+				Int8:    8,
+				Uint16:  16,
+				Float32: 1.5,
+				Float64: 2.5,
+				Inner:   testStructInner{A: "a"},
+				NoName:  "x",
+				Ignored: "ignored",
+			},
+			s: "%11\r\n" +
+				"$6\r\nstring\r\n$5\r\nhello\r\n" +
+				"$5\r\nbytes\r\n$5\r\nworld\r\n" +
+				"$4\r\nbool\r\n#t\r\n" +
+				"$3\r\nint\r\n:-1\r\n" +
+				"$4\r\nint8\r\n:8\r\n" +
+				"$6\r\nuint16\r\n:16\r\n" +
+				"$7\r\nfloat32\r\n,1.5\r\n" +
+				"$7\r\nfloat64\r\n,2.5\r\n" +
+				"$5\r\ninner\r\n%1\r\n$1\r\na\r\n$1\r\na\r\n" +
+				"$6\r\nNoName\r\n$1\r\nx\r\n" +
+				"$11\r\nunsupported\r\n_\r\n",
+		},
+		{
+			name: "Pointer",
+			v:    &inner{A: 1},
+			s:    "%1\r\n$1\r\na\r\n:1\r\n",
+		},
+		{
+			name: "OmitEmpty",
+			v:    omit{},
+			s:    "%1\r\n$1\r\nd\r\n$0\r\n\r\n",
+		},
+		{
+			name: "OmitEmptySet",
+			v:    omit{A: "a", B: 1, C: &inner{A: 2}},
+			s: "%4\r\n$1\r\na\r\n$1\r\na\r\n$1\r\nb\r\n:1\r\n" +
+				"$1\r\nc\r\n%1\r\n$1\r\na\r\n:2\r\n$1\r\nd\r\n$0\r\n\r\n",
+		},
+		{name: "Nil", v: nil, err: resp3.ErrTypeMismatch},
+		{name: "NilPointer", v: (*inner)(nil), err: resp3.ErrTypeMismatch},
+		{name: "NoStruct", v: 1, err: resp3.ErrTypeMismatch},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rw, assert := newTestWriter(t)
+			assert(c.s, c.err, rw.WriteStruct(c.v))
+		})
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		in := testStruct{String: "hello", Int: 1, Float64: 1.5, Inner: testStructInner{A: "a"}}
+
+		var b bytes.Buffer
+		if err := resp3.NewWriter(&b).WriteStruct(in); err != nil {
+			t.Fatalf("failed to write struct: %s", err)
+		}
+
+		var out testStruct
+		if err := resp3.NewReader(&b).ReadInto(&out); err != nil {
+			t.Fatalf("failed to read struct: %s", err)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("got %#v, expected %#v", out, in)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package resp3
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Token represents a single RESP frame as returned by Reader.Next.
+//
+// Depending on Type, only some of the fields are set:
+//
+//	TypeArray, TypeAttribute, TypeMap,    Len is the number of elements (or key-value pairs for attributes and maps).
+//	TypePush, TypeSet                     For streamed aggregates Len is -1 and Chunked is true.
+//	TypeBigNumber                         BigNumber holds the number.
+//	TypeBlobChunk                         Bytes holds the content of the chunk.
+//	TypeBlobError, TypeBlobString         Bytes holds the content and Len its length. For chunked blobs Bytes is nil,
+//	                                      Len is -1 and Chunked is true.
+//	TypeBoolean                           Bool holds the value.
+//	TypeDouble                            Double holds the value.
+//	TypeEnd                               No fields are set.
+//	TypeNull                              No fields are set.
+//	TypeNumber                            Number holds the value.
+//	TypeSimpleError, TypeSimpleString     Bytes holds the content.
+//	TypeVerbatimString                    Bytes holds the content, including the format prefix.
+type Token struct {
+	// Type is the type of the token.
+	Type Type
+
+	// Len is the length of an aggregate or blob.
+	Len int64
+
+	// Chunked is true for chunked blobs and streamed aggregates.
+	Chunked bool
+
+	// BigNumber is set for big numbers.
+	BigNumber *big.Int
+
+	// Bool is set for booleans.
+	Bool bool
+
+	// Bytes is set for blob chunks, blobs, simple errors, simple strings and verbatim strings.
+	//
+	// The slice is only valid until the next call to Next.
+	Bytes []byte
+
+	// Double is set for doubles.
+	Double float64
+
+	// Number is set for numbers.
+	Number int64
+}
+
+// Next reads the next frame in the stream and returns it as Token.
+//
+// In contrast to ReadValue and Discard, Next never reads nested values. Instead every element of an aggregate is
+// returned by its own call to Next after the token for the aggregate header. The end of a streamed aggregate is
+// returned as token of type TypeEnd.
+//
+// Chunked blobs are returned as a token for the blob header followed by tokens of type TypeBlobChunk for each chunk.
+// The last, empty chunk is returned as token of type TypeEnd.
+//
+// If there are no more values, Next returns io.EOF.
+func (rr *Reader) Next() (Token, error) {
+	t, err := rr.Peek()
+	if err != nil {
+		return Token{}, err
+	}
+
+	tok := Token{Type: t}
+
+	switch t {
+	case TypeArray, TypeAttribute, TypeMap, TypePush, TypeSet:
+		tok.Len, tok.Chunked, err = rr.readAggregateHeader(t)
+	case TypeBigNumber:
+		tok.BigNumber = new(big.Int)
+		err = rr.ReadBigNumber(tok.BigNumber)
+	case TypeBlobChunk:
+		var last bool
+		if tok.Bytes, last, err = rr.ReadBlobChunk(rr.tokenBuf[:0]); last {
+			tok = Token{Type: TypeEnd}
+		}
+	case TypeBlobError, TypeBlobString:
+		if tok.Bytes, tok.Chunked, err = rr.readChunkableBlob(t, rr.tokenBuf[:0]); tok.Chunked {
+			tok.Bytes, tok.Len = nil, -1
+		} else {
+			tok.Len = int64(len(tok.Bytes))
+		}
+	case TypeBoolean:
+		tok.Bool, err = rr.ReadBoolean()
+	case TypeDouble:
+		tok.Double, err = rr.ReadDouble()
+	case TypeEnd:
+		err = rr.ReadEnd()
+	case TypeNull:
+		err = rr.ReadNull()
+	case TypeNumber:
+		tok.Number, err = rr.ReadNumber()
+	case TypeSimpleError, TypeSimpleString:
+		tok.Bytes, err = rr.readSimple(t, rr.tokenBuf[:0])
+	case TypeVerbatimString:
+		tok.Bytes, err = rr.ReadVerbatimString(rr.tokenBuf[:0])
+	default:
+		err = fmt.Errorf("%w: got %q", ErrUnexpectedType, t)
+	}
+
+	if err != nil {
+		return Token{}, wrapEOF(err, "")
+	}
+	if tok.Bytes != nil {
+		rr.tokenBuf = tok.Bytes
+	}
+	return tok, nil
+}
@@ -0,0 +1,97 @@
+package resp3_test
+
+import (
+	"io"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+func TestReaderNext(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		in     string
+		tokens []resp3.Token
+		err    error
+	}{
+		{name: "Empty", err: io.EOF},
+		{name: "Invalid", in: "A", err: resp3.ErrInvalidType},
+		{
+			name: "Scalars",
+			in: "(123\r\n#t\r\n,1.5\r\n_\r\n$-1\r\n:-10\r\n+OK\r\n-ERR\r\n=7\r\ntxt:abc\r\n" +
+				"$5\r\nhello\r\n!3\r\nERR\r\n",
+			tokens: []resp3.Token{
+				{Type: resp3.TypeBigNumber, BigNumber: big.NewInt(123)},
+				{Type: resp3.TypeBoolean, Bool: true},
+				{Type: resp3.TypeDouble, Double: 1.5},
+				{Type: resp3.TypeNull},
+				{Type: resp3.TypeNull},
+				{Type: resp3.TypeNumber, Number: -10},
+				{Type: resp3.TypeSimpleString, Bytes: []byte("OK")},
+				{Type: resp3.TypeSimpleError, Bytes: []byte("ERR")},
+				{Type: resp3.TypeVerbatimString, Bytes: []byte("txt:abc")},
+				{Type: resp3.TypeBlobString, Len: 5, Bytes: []byte("hello")},
+				{Type: resp3.TypeBlobError, Len: 3, Bytes: []byte("ERR")},
+			},
+			err: io.EOF,
+		},
+		{
+			name: "Aggregates",
+			in:   "*2\r\n%1\r\n+a\r\n:1\r\n~0\r\n|1\r\n+a\r\n+b\r\n>1\r\n+message\r\n",
+			tokens: []resp3.Token{
+				{Type: resp3.TypeArray, Len: 2},
+				{Type: resp3.TypeMap, Len: 1},
+				{Type: resp3.TypeSimpleString, Bytes: []byte("a")},
+				{Type: resp3.TypeNumber, Number: 1},
+				{Type: resp3.TypeSet},
+				{Type: resp3.TypeAttribute, Len: 1},
+				{Type: resp3.TypeSimpleString, Bytes: []byte("a")},
+				{Type: resp3.TypeSimpleString, Bytes: []byte("b")},
+				{Type: resp3.TypePush, Len: 1},
+				{Type: resp3.TypeSimpleString, Bytes: []byte("message")},
+			},
+			err: io.EOF,
+		},
+		{
+			name: "Streamed",
+			in:   "*?\r\n$?\r\n;2\r\nhe\r\n;3\r\nllo\r\n;0\r\n.\r\n",
+			tokens: []resp3.Token{
+				{Type: resp3.TypeArray, Len: -1, Chunked: true},
+				{Type: resp3.TypeBlobString, Len: -1, Chunked: true},
+				{Type: resp3.TypeBlobChunk, Bytes: []byte("he")},
+				{Type: resp3.TypeBlobChunk, Bytes: []byte("llo")},
+				{Type: resp3.TypeEnd},
+				{Type: resp3.TypeEnd},
+			},
+			err: io.EOF,
+		},
+		{
+			name:   "Incomplete",
+			in:     "*1\r\n$5\r\nhel",
+			tokens: []resp3.Token{{Type: resp3.TypeArray, Len: 1}},
+			err:    resp3.ErrUnexpectedEOL,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rr, _ := newTestReader(c.in)
+			for i := 0; ; i++ {
+				tok, err := rr.Next()
+				if err != nil {
+					assertError(t, c.err, err)
+					if i != len(c.tokens) {
+						t.Errorf("got %d tokens, expected %d", i, len(c.tokens))
+					}
+					return
+				}
+				if i >= len(c.tokens) {
+					t.Fatalf("got unexpected token %#v", tok)
+				}
+				if expected := c.tokens[i]; !reflect.DeepEqual(tok, expected) {
+					t.Errorf("got token %#v, expected %#v", tok, expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package resp3
+
+import (
+	"fmt"
+	"math"
+)
+
+// Value is a reusable representation of a RESP value and all its nested values, as read by Reader.ReadValueInto.
+//
+// Depending on the Type only some fields are used:
+//
+//	TypeArray, TypePush, TypeSet        Elems
+//	TypeBigNumber                       Bytes (decimal representation)
+//	TypeBlobError, TypeSimpleError      Bytes
+//	TypeBlobString, TypeSimpleString    Bytes
+//	TypeBoolean                         Bool
+//	TypeDouble                          Double
+//	TypeMap                             Elems (keys and values alternating)
+//	TypeNull                            none
+//	TypeNumber                          Int
+//	TypeVerbatimString                  Bytes (including the format prefix)
+//
+// Chunked blobs and streamed aggregates are stored like their non-chunked and non-streamed counterparts.
+//
+// When reading into a Value, the backing arrays of Bytes and Elems, including those of nested values, are reused, so
+// that reading many values of similar shape into the same Value does not allocate.
+type Value struct {
+	// Type is the type of the value.
+	Type Type
+
+	// Bool is the value of booleans.
+	Bool bool
+
+	// Bytes holds the content of big numbers, blobs, simple values and verbatim strings.
+	Bytes []byte
+
+	// Double is the value of doubles.
+	Double float64
+
+	// Elems holds the elements of aggregates. For maps keys and values are stored alternating, starting with the
+	// first key.
+	Elems []Value
+
+	// Int is the value of numbers.
+	Int int64
+}
+
+// Reset resets v to the zero value, but retains the backing arrays of Bytes and Elems for reuse.
+func (v *Value) Reset() {
+	*v = Value{Bytes: v.Bytes[:0], Elems: v.Elems[:0]}
+}
+
+// appendElem appends a new element to v.Elems, reusing elements beyond the length of v.Elems if possible, and returns
+// a pointer to it.
+func (v *Value) appendElem() *Value {
+	if len(v.Elems) < cap(v.Elems) {
+		v.Elems = v.Elems[:len(v.Elems)+1]
+	} else {
+		v.Elems = append(v.Elems, Value{})
+	}
+	return &v.Elems[len(v.Elems)-1]
+}
+
+// ReadValueInto reads the next value, including all nested values, into v, reusing the memory held by v.
+//
+// See Value for details on how the different types are stored. Like in ReadValue attributes preceding a value are
+// discarded and the same limits apply.
+//
+// If the next type is a blob chunk or an end marker, ErrUnexpectedType is returned. If an error is returned, the
+// content of v is undefined.
+func (rr *Reader) ReadValueInto(v *Value) error {
+	rr.beginReply()
+	defer rr.endReply()
+
+	t, err := rr.Peek()
+	for err == nil && t == TypeAttribute {
+		if _, err := rr.Discard(true); err != nil {
+			return err
+		}
+		t, err = rr.Peek()
+	}
+	if err != nil {
+		return wrapEOF(err, "")
+	}
+
+	v.Reset()
+	v.Type = t
+
+	switch t {
+	case TypeArray, TypeMap, TypePush, TypeSet:
+		err = rr.readAggregateInto(t, v)
+	case TypeBigNumber:
+		v.Bytes, err = rr.ReadBigNumberBytes(v.Bytes)
+	case TypeBlobError, TypeBlobString:
+		var chunked bool
+		v.Bytes, chunked, err = rr.readChunkableBlob(t, v.Bytes)
+		if err == nil && chunked {
+			v.Bytes, err = rr.ReadBlobChunks(v.Bytes)
+		}
+	case TypeBoolean:
+		v.Bool, err = rr.ReadBoolean()
+	case TypeDouble:
+		v.Double, err = rr.ReadDouble()
+	case TypeNull:
+		err = rr.ReadNull()
+	case TypeNumber:
+		v.Int, err = rr.ReadNumber()
+	case TypeSimpleError, TypeSimpleString:
+		v.Bytes, err = rr.readSimple(t, v.Bytes)
+	case TypeVerbatimString:
+		v.Bytes, err = rr.ReadVerbatimString(v.Bytes)
+	default:
+		err = fmt.Errorf("%w: got %q", ErrUnexpectedType, t)
+	}
+
+	return err
+}
+
+func (rr *Reader) readAggregateInto(t Type, v *Value) error {
+	n, chunked, err := rr.readAggregateHeader(t)
+	if err != nil {
+		return err
+	}
+	nodes := int64(-1)
+	if !chunked {
+		if t == TypeMap && n > math.MaxInt64/2 {
+			n = math.MaxInt64
+		} else if t == TypeMap {
+			n *= 2
+		}
+		nodes = n
+	}
+	prev, err := rr.enterAggregate(t, nodes)
+	if err != nil {
+		return err
+	}
+	defer rr.leaveAggregate(prev)
+	for i := int64(0); chunked || i < n; i++ {
+		if chunked {
+			if more, err := rr.moreChunks(i); err != nil {
+				return err
+			} else if !more {
+				break
+			}
+		}
+		if err := rr.ReadValueInto(v.appendElem()); err != nil {
+			return err
+		}
+		if chunked && t == TypeMap {
+			if err := rr.ReadValueInto(v.appendElem()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteValueTree writes v, including all nested values.
+//
+// The fields of v are interpreted as described in the documentation of Value. If v is a map or an attribute, the
+// number of elements must be even. Otherwise an error wrapping ErrInvalidAggregateTypeLength is returned. Values of
+// type TypeVerbatimString must include the 3 character format prefix, followed by a colon.
+//
+// Values are written using the same methods as when writing them manually, so Protocol and Debug are respected for
+// all nested values.
+func (rw *Writer) WriteValueTree(v *Value) error {
+	switch v.Type {
+	case TypeArray:
+		if err := rw.WriteArrayHeader(int64(len(v.Elems))); err != nil {
+			return err
+		}
+	case TypeAttribute, TypeMap:
+		if len(v.Elems)%2 != 0 {
+			return fmt.Errorf("%w: %q with odd number of elements %d", ErrInvalidAggregateTypeLength, v.Type,
+				len(v.Elems))
+		}
+		writeHeader := rw.WriteMapHeader
+		if v.Type == TypeAttribute {
+			writeHeader = rw.WriteAttributeHeader
+		}
+		if err := writeHeader(int64(len(v.Elems) / 2)); err != nil {
+			return err
+		}
+	case TypePush:
+		if err := rw.WritePushHeader(int64(len(v.Elems))); err != nil {
+			return err
+		}
+	case TypeSet:
+		if err := rw.WriteSetHeader(int64(len(v.Elems))); err != nil {
+			return err
+		}
+	case TypeBigNumber:
+		return rw.writeBigNumberBytes(v.Bytes)
+	case TypeBlobError:
+		return rw.WriteBlobError(v.Bytes)
+	case TypeBlobString:
+		return rw.WriteBlobString(v.Bytes)
+	case TypeBoolean:
+		return rw.WriteBoolean(v.Bool)
+	case TypeDouble:
+		return rw.WriteDouble(v.Double)
+	case TypeNull:
+		return rw.WriteNull()
+	case TypeNumber:
+		return rw.WriteNumber(v.Int)
+	case TypeSimpleError:
+		return rw.WriteSimpleError(v.Bytes)
+	case TypeSimpleString:
+		return rw.WriteSimpleString(v.Bytes)
+	case TypeVerbatimString:
+		return rw.writeVerbatimStringBytes(v.Bytes)
+	default:
+		return fmt.Errorf("%w: got %q", ErrUnexpectedType, v.Type)
+	}
+	for i := range v.Elems {
+		if err := rw.WriteValueTree(&v.Elems[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
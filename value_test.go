@@ -0,0 +1,189 @@
+package resp3_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp3"
+)
+
+// normalizeValue replaces empty slices in v and all nested values with nil, so that values can be compared using
+// reflect.DeepEqual.
+func normalizeValue(v resp3.Value) resp3.Value {
+	if len(v.Bytes) == 0 {
+		v.Bytes = nil
+	}
+	if len(v.Elems) == 0 {
+		v.Elems = nil
+	}
+	elems := v.Elems
+	v.Elems = nil
+	for _, e := range elems {
+		v.Elems = append(v.Elems, normalizeValue(e))
+	}
+	return v
+}
+
+func TestReaderReadValueInto(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		v    resp3.Value
+		err  error
+		rest string
+	}{
+		{err: resp3.ErrUnexpectedEOL},
+		{in: "A", err: resp3.ErrInvalidType},
+		{in: ".\r\n", err: resp3.ErrUnexpectedType},
+		{in: ";0\r\n", err: resp3.ErrUnexpectedType},
+		{in: "*2\r\n:1\r\n", err: resp3.ErrUnexpectedEOL},
+
+		{in: "(12345678901234567890\r\n", v: resp3.Value{Type: resp3.TypeBigNumber, Bytes: []byte("12345678901234567890")}},
+		{in: "!3\r\nERR\r\n", v: resp3.Value{Type: resp3.TypeBlobError, Bytes: []byte("ERR")}},
+		{in: "$5\r\nhello\r\n", v: resp3.Value{Type: resp3.TypeBlobString, Bytes: []byte("hello")}},
+		{in: "$?\r\n;2\r\nhe\r\n;3\r\nllo\r\n;0\r\n", v: resp3.Value{Type: resp3.TypeBlobString, Bytes: []byte("hello")}},
+		{in: "#t\r\n", v: resp3.Value{Type: resp3.TypeBoolean, Bool: true}},
+		{in: ",1.5\r\n", v: resp3.Value{Type: resp3.TypeDouble, Double: 1.5}},
+		{in: "_\r\n", v: resp3.Value{Type: resp3.TypeNull}},
+		{in: "$-1\r\n", v: resp3.Value{Type: resp3.TypeNull}},
+		{in: ":-10\r\n", v: resp3.Value{Type: resp3.TypeNumber, Int: -10}},
+		{in: "-ERR hello\r\n", v: resp3.Value{Type: resp3.TypeSimpleError, Bytes: []byte("ERR hello")}},
+		{in: "+OK\r\n", v: resp3.Value{Type: resp3.TypeSimpleString, Bytes: []byte("OK")}},
+		{in: "=9\r\ntxt:hello\r\n", v: resp3.Value{Type: resp3.TypeVerbatimString, Bytes: []byte("txt:hello")}},
+
+		{in: "*0\r\n", v: resp3.Value{Type: resp3.TypeArray}},
+		{
+			in: "*2\r\n:1\r\n~1\r\n+a\r\n:2\r\n",
+			v: resp3.Value{Type: resp3.TypeArray, Elems: []resp3.Value{
+				{Type: resp3.TypeNumber, Int: 1},
+				{Type: resp3.TypeSet, Elems: []resp3.Value{{Type: resp3.TypeSimpleString, Bytes: []byte("a")}}},
+			}},
+			rest: ":2\r\n",
+		},
+		{
+			in: ">?\r\n:1\r\n:2\r\n.\r\n",
+			v: resp3.Value{Type: resp3.TypePush, Elems: []resp3.Value{
+				{Type: resp3.TypeNumber, Int: 1},
+				{Type: resp3.TypeNumber, Int: 2},
+			}},
+		},
+		{
+			in: "%1\r\n+a\r\n:1\r\n",
+			v: resp3.Value{Type: resp3.TypeMap, Elems: []resp3.Value{
+				{Type: resp3.TypeSimpleString, Bytes: []byte("a")},
+				{Type: resp3.TypeNumber, Int: 1},
+			}},
+		},
+		{
+			in: "%?\r\n+a\r\n:1\r\n.\r\n",
+			v: resp3.Value{Type: resp3.TypeMap, Elems: []resp3.Value{
+				{Type: resp3.TypeSimpleString, Bytes: []byte("a")},
+				{Type: resp3.TypeNumber, Int: 1},
+			}},
+		},
+		{in: "%?\r\n+a\r\n.\r\n", err: resp3.ErrUnexpectedType},
+		{
+			in: "|1\r\n+ttl\r\n:10\r\n:1\r\n",
+			v:  resp3.Value{Type: resp3.TypeNumber, Int: 1},
+		},
+	} {
+		rr, rest := newTestReaderWithRest(c.in)
+		var v resp3.Value
+		err := rr.ReadValueInto(&v)
+		assertError(t, c.err, err)
+		if c.err != nil {
+			continue
+		}
+		if got := normalizeValue(v); !reflect.DeepEqual(got, c.v) {
+			t.Errorf("got %#v for %q, expected %#v", got, c.in, c.v)
+		}
+		if got := rest(); got != c.rest {
+			t.Errorf("got %q left in input, expected %q", got, c.rest)
+		}
+	}
+}
+
+func TestReaderReadValueIntoReuse(t *testing.T) {
+	const in = "*3\r\n$5\r\nhello\r\n%1\r\n+key\r\n:1\r\n~?\r\n+a\r\n+b\r\n.\r\n"
+
+	r := strings.NewReader(in)
+	rr := resp3.NewReader(r)
+
+	var v resp3.Value
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Reset(in)
+		rr.Reset(r)
+		if err := rr.ReadValueInto(&v); err != nil {
+			t.Fatalf("failed to read value: %s", err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("got %.0f allocations, expected 0", allocs)
+	}
+
+	v.Reset()
+	if v.Type != resp3.TypeInvalid || len(v.Bytes) != 0 || len(v.Elems) != 0 {
+		t.Errorf("got %#v after reset, expected empty value", v)
+	}
+}
+
+func TestWriterWriteValueTree(t *testing.T) {
+	for _, in := range []string{
+		"(12345678901234567890\r\n",
+		"!3\r\nERR\r\n",
+		"$5\r\nhello\r\n",
+		"#t\r\n",
+		",1.5\r\n",
+		"_\r\n",
+		":-10\r\n",
+		"-ERR hello\r\n",
+		"+OK\r\n",
+		"=9\r\ntxt:hello\r\n",
+		"*0\r\n",
+		"*2\r\n:1\r\n~1\r\n+a\r\n",
+		">2\r\n:1\r\n:2\r\n",
+		"%1\r\n+a\r\n*1\r\n_\r\n",
+	} {
+		rr := resp3.NewReader(strings.NewReader(in))
+		var v resp3.Value
+		if err := rr.ReadValueInto(&v); err != nil {
+			t.Fatalf("failed to read %q: %s", in, err)
+		}
+
+		rw, assert := newTestWriter(t)
+		assert(in, nil, rw.WriteValueTree(&v))
+	}
+
+	rw, assert := newTestWriter(t)
+
+	assert("", resp3.ErrInvalidAggregateTypeLength, rw.WriteValueTree(&resp3.Value{
+		Type:  resp3.TypeMap,
+		Elems: []resp3.Value{{Type: resp3.TypeNull}},
+	}))
+	assert("", resp3.ErrInvalidBigNumber, rw.WriteValueTree(&resp3.Value{
+		Type:  resp3.TypeBigNumber,
+		Bytes: []byte("1.5"),
+	}))
+	assert("", resp3.ErrInvalidVerbatimString, rw.WriteValueTree(&resp3.Value{
+		Type:  resp3.TypeVerbatimString,
+		Bytes: []byte("txt"),
+	}))
+	assert("", resp3.ErrUnexpectedType, rw.WriteValueTree(&resp3.Value{}))
+	assert("", resp3.ErrUnexpectedType, rw.WriteValueTree(&resp3.Value{Type: resp3.TypeEnd}))
+
+	v := resp3.Value{Type: resp3.TypeMap, Elems: []resp3.Value{
+		{Type: resp3.TypeSimpleString, Bytes: []byte("a")},
+		{Type: resp3.TypeVerbatimString, Bytes: []byte("txt:hello")},
+		{Type: resp3.TypeSimpleString, Bytes: []byte("b")},
+		{Type: resp3.TypeBigNumber, Bytes: []byte("-123")},
+	}}
+
+	rw.Protocol = 2
+	assert("*4\r\n+a\r\n$5\r\nhello\r\n+b\r\n$4\r\n-123\r\n", nil, rw.WriteValueTree(&v))
+
+	rw.Protocol = 3
+	rw.Debug = true
+	assert("*1\r\n", nil, rw.WriteArrayHeader(1))
+	assert("%2\r\n+a\r\n=9\r\ntxt:hello\r\n+b\r\n(-123\r\n", nil, rw.WriteValueTree(&v))
+	assert("", nil, rw.Verify())
+}
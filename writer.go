@@ -1,17 +1,92 @@
 package resp3
 
 import (
+	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"math"
 	"math/big"
+	"net"
+	"reflect"
 	"strconv"
+	"strings"
 )
 
 // Writer wraps an io.Writer and provides methods for writing the RESP protocol.
 type Writer struct {
+	// Protocol specifies the version of the RESP protocol that is written.
+	//
+	// If Protocol is 2, values that have no RESP2 equivalent are written using their RESP2 fallback:
+	//
+	//	Big numbers and doubles are written as blob strings
+	//	Booleans are written as numbers (1 for true, 0 for false)
+	//	Map headers are written as array headers with twice the number of elements
+	//	Null is written as a null blob string ($-1)
+	//	Push and set headers are written as array headers
+	//	Verbatim strings are written as blob strings without the format prefix
+	//
+	// All other values, including attributes, blob errors and streamed values, are written unchanged.
+	//
+	// For all other values, including 0, RESP3 is used.
+	Protocol uint8
+
+	// DoubleAlwaysDecimal ensures that doubles are always written with at least one fractional digit.
+	//
+	// By default WriteDouble(1) writes ",1\r\n", which some clients can not distinguish from an integer. If
+	// DoubleAlwaysDecimal is true, ",1.0\r\n" is written instead.
+	DoubleAlwaysDecimal bool
+
+	// DoublePositiveInf is written for positive infinity instead of "inf", for example "+inf".
+	//
+	// The value must be parsed as positive infinity by strconv.ParseFloat, so that it can still be read using
+	// Reader.ReadDouble, and must not contain \r or \n. If DoublePositiveInf is empty, "inf" is used. For any other
+	// value WriteDouble returns an error wrapping ErrInvalidDouble.
+	DoublePositiveInf []byte
+
+	// LineEnding is written at the end of each line instead of \r\n and must be either \r\n or \n.
+	//
+	// Lines terminated by a single \n are not valid RESP and should only be used with peers that explicitly accept
+	// them, for example a Reader with AllowBareLF set to true. If LineEnding is empty, \r\n is used. For any other
+	// value all writes return ErrInvalidLineEnding.
+	//
+	// The EncodedLen functions always assume \r\n and do not account for LineEnding.
+	LineEnding []byte
+
+	// SimpleStringReplacement is used by WriteSimpleStringSanitized to replace \r and \n.
+	//
+	// If SimpleStringReplacement is 0, \r or \n, a space is used.
+	SimpleStringReplacement byte
+
+	// StrictErrors makes WriteBlobError and WriteSimpleError return ErrEmptyError when called with an empty error.
+	//
+	// Empty errors are valid RESP, but are almost always caused by a bug where an error path forgot to set a message.
+	StrictErrors bool
+
+	// Debug enables validation of the number of elements written for aggregates and chunked blobs.
+	//
+	// If Debug is true, the Writer keeps track of the number of elements that are still outstanding for each aggregate
+	// header written and returns an error wrapping ErrElementCountMismatch instead of writing a value that does not
+	// fit the declared structure, for example when writing an end marker while elements of a nested array are still
+	// outstanding. Verify can be used to check that all aggregates and chunked blobs were completed.
+	//
+	// Debug is meant to catch encoding bugs in tests and should not be enabled in production code.
+	Debug bool
+
 	w   io.Writer
+	bw  *bufio.Writer
 	buf []byte
+
+	doubleFormat byte
+	doublePrec   int
+
+	maxRetainedBuffer int
+
+	// valueDepth is the nesting depth of the value currently being written by WriteValue.
+	valueDepth int
+
+	// pending holds the number of outstanding elements for each open aggregate or chunked blob when Debug is true.
+	pending []int64
 }
 
 // NewWriter returns a *Writer that uses the given io.Writer for writes.
@@ -21,58 +96,438 @@ func NewWriter(w io.Writer) *Writer {
 	return &rw
 }
 
+// WriterOption configures a Writer created using NewWriterWithOptions.
+type WriterOption func(*Writer)
+
+// WithBuffer configures the Writer to buffer writes to the underlying io.Writer using a buffer of the given size.
+//
+// Buffered data must be written to the underlying io.Writer using Flush.
+//
+// If size is <= 0, writes are not buffered.
+func WithBuffer(size int) WriterOption {
+	return func(rw *Writer) {
+		if size <= 0 {
+			rw.bw = nil
+			return
+		}
+		rw.bw = bufio.NewWriterSize(nil, size)
+	}
+}
+
+// WithDoubleFormat configures the format and precision used when writing doubles.
+//
+// The format and precision are interpreted as by strconv.FormatFloat. Only the formats 'e', 'E', 'f', 'g' and 'G'
+// are allowed, as other formats can not be represented in RESP. For any other format WriteDouble returns an error
+// wrapping ErrInvalidDoubleFormat.
+//
+// By default doubles are written using the format 'f' and the smallest precision necessary to represent the value
+// exactly (-1).
+func WithDoubleFormat(format byte, prec int) WriterOption {
+	return func(rw *Writer) {
+		rw.doubleFormat = format
+		rw.doublePrec = prec
+	}
+}
+
+// WithMaxRetainedBuffer configures the maximum capacity of the internal buffer used for encoding values that is
+// retained between writes.
+//
+// If encoding a value grows the internal buffer beyond n bytes, the buffer is released after the write, so that
+// writing a single large value does not keep the memory allocated for the lifetime of the Writer.
+//
+// If n is <= 0, the buffer is always retained. This is the default.
+func WithMaxRetainedBuffer(n int) WriterOption {
+	return func(rw *Writer) {
+		rw.maxRetainedBuffer = n
+	}
+}
+
+// WithProtocol configures the Writer to write the given version of the RESP protocol.
+//
+// See the documentation of the Protocol field for details.
+func WithProtocol(p uint8) WriterOption {
+	return func(rw *Writer) {
+		rw.Protocol = p
+	}
+}
+
+// NewWriterWithOptions returns a *Writer that uses the given io.Writer for writes and is configured using the given
+// options.
+//
+// Options are applied in order, so later options overwrite earlier ones.
+func NewWriterWithOptions(w io.Writer, opts ...WriterOption) *Writer {
+	var rw Writer
+	for _, opt := range opts {
+		opt(&rw)
+	}
+	rw.Reset(w)
+	return &rw
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+//
+// If the Writer was not configured using WithBuffer, Flush does nothing.
+func (rw *Writer) Flush() error {
+	if _, ok := rw.w.(closed); ok {
+		return ErrClosed
+	}
+	if rw.bw == nil {
+		return nil
+	}
+	return rw.bw.Flush()
+}
+
 // Reset sets the underlying io.Writer to w and resets all internal state.
+//
+// Any buffered data that was not flushed is discarded. Options given to NewWriterWithOptions are retained.
+//
+// If w is nil, all references to the previous io.Writer are released and all following writes return ErrClosed until
+// Reset is called again. This allows reusing Writers, for example using a sync.Pool, without keeping the underlying
+// connections alive. See Reader.Reset for an example.
 func (rw *Writer) Reset(w io.Writer) {
+	rw.pending = rw.pending[:0]
+	if w == nil {
+		if rw.bw != nil {
+			rw.bw.Reset(closed{})
+		}
+		rw.w = closed{}
+		return
+	}
+	if rw.bw != nil {
+		rw.bw.Reset(w)
+		rw.w = rw.bw
+		return
+	}
 	rw.w = w
 }
 
+// maxExactDoubleInteger is the largest integer up to which all integers can be represented exactly as float64.
+const maxExactDoubleInteger = 1 << 53
+
+func (rw *Writer) appendDouble(dst []byte, f float64) []byte {
+	start := len(dst)
+	isInt := f == math.Trunc(f) && math.Abs(f) <= maxExactDoubleInteger && (f != 0 || !math.Signbit(f))
+	if rw.doubleFormat == 0 && isInt {
+		// fast path for integer values (except for -0), which are formatted the same by strconv.AppendFloat, but slower
+		dst = strconv.AppendInt(dst, int64(f), 10)
+	} else if rw.doubleFormat == 0 {
+		dst = strconv.AppendFloat(dst, f, 'f', -1, 64)
+	} else {
+		dst = strconv.AppendFloat(dst, f, rw.doubleFormat, rw.doublePrec, 64)
+	}
+	if rw.DoubleAlwaysDecimal && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		dst = appendFractionalDigit(dst, start)
+	}
+	return dst
+}
+
+// appendFractionalDigit inserts ".0" into the number starting at dst[start:] if the number has no fractional part.
+func appendFractionalDigit(dst []byte, start int) []byte {
+	num := dst[start:]
+	if bytes.IndexByte(num, '.') >= 0 {
+		return dst
+	}
+	i := bytes.IndexAny(num, "eE")
+	if i < 0 {
+		return append(dst, '.', '0')
+	}
+	i += start
+	dst = append(dst, 0, 0)
+	copy(dst[i+2:], dst[i:])
+	dst[i], dst[i+1] = '.', '0'
+	return dst
+}
+
+// appendEOL appends the configured LineEnding to dst.
+func (rw *Writer) appendEOL(dst []byte) []byte {
+	if rw.bareLF() {
+		return append(dst, '\n')
+	}
+	return append(dst, '\r', '\n')
+}
+
+// bareLF returns true if lines are terminated using a single \n.
+func (rw *Writer) bareLF() bool {
+	return len(rw.LineEnding) == 1 && rw.LineEnding[0] == '\n'
+}
+
+// checkLineEnding returns ErrInvalidLineEnding if LineEnding is neither empty nor one of \r\n and \n.
+func (rw *Writer) checkLineEnding() error {
+	switch string(rw.LineEnding) {
+	case "", "\r\n", "\n":
+		return nil
+	default:
+		return ErrInvalidLineEnding
+	}
+}
+
+// checkDoubleFormat returns an error wrapping ErrInvalidDoubleFormat if the format configured using WithDoubleFormat
+// is not supported.
+func (rw *Writer) checkDoubleFormat() error {
+	switch rw.doubleFormat {
+	case 0, 'e', 'E', 'f', 'g', 'G':
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidDoubleFormat, rw.doubleFormat)
+	}
+}
+
+// checkDoublePositiveInf returns an error wrapping ErrInvalidDouble if DoublePositiveInf is neither empty nor a valid
+// representation of positive infinity.
+func (rw *Writer) checkDoublePositiveInf() error {
+	if len(rw.DoublePositiveInf) == 0 {
+		return nil
+	}
+	// strconv.ParseFloat also rejects any \r or \n
+	if f, err := strconv.ParseFloat(string(rw.DoublePositiveInf), 64); err != nil || !math.IsInf(f, 1) {
+		return fmt.Errorf("%w: DoublePositiveInf %q is not positive infinity", ErrInvalidDouble, rw.DoublePositiveInf)
+	}
+	return nil
+}
+
+func (rw *Writer) writeBuf() error {
+	if err := rw.checkLineEnding(); err != nil {
+		return err
+	}
+	_, err := rw.w.Write(rw.buf)
+	if rw.maxRetainedBuffer > 0 && cap(rw.buf) > rw.maxRetainedBuffer {
+		rw.buf = nil
+	}
+	return err
+}
+
+// minVectoredWriteSize is the minimum size of a blob body for which the body is written directly to the underlying
+// io.Writer instead of being copied into the internal buffer.
+const minVectoredWriteSize = 16 * 1024
+
+var crlfBytes = []byte("\r\n")
+var lfBytes = []byte("\n")
+
+// eolBytes returns the configured LineEnding.
+func (rw *Writer) eolBytes() []byte {
+	if rw.bareLF() {
+		return lfBytes
+	}
+	return crlfBytes
+}
+
+// writeConst writes b, which must end in \r\n, replacing the line ending with the configured LineEnding.
+func (rw *Writer) writeConst(b []byte) error {
+	if rw.bareLF() {
+		rw.buf = append(rw.buf[:0], b[:len(b)-len("\r\n")]...)
+		rw.buf = append(rw.buf, '\n')
+		return rw.writeBuf()
+	}
+	if err := rw.checkLineEnding(); err != nil {
+		return err
+	}
+	_, err := rw.w.Write(b)
+	return err
+}
+
+// vectored returns true if a blob body of n bytes should be written directly to the underlying io.Writer.
+//
+// This is only done for io.Writers implementing io.ReaderFrom, like *bufio.Writer, *net.TCPConn or *net.UnixConn,
+// which either buffer writes themselves or can write multiple buffers using a single system call.
+func (rw *Writer) vectored(n int) bool {
+	if n < minVectoredWriteSize {
+		return false
+	}
+	_, ok := rw.w.(io.ReaderFrom)
+	return ok
+}
+
+// writeBufVectored writes the header in rw.buf, followed by body and the line ending.
+//
+// For network connections the buffers are written using a single writev system call.
+func (rw *Writer) writeBufVectored(body []byte) error {
+	if err := rw.checkLineEnding(); err != nil {
+		return err
+	}
+	bufs := net.Buffers{rw.buf, body, rw.eolBytes()}
+	_, err := bufs.WriteTo(rw.w)
+	return err
+}
+
+func (rw *Writer) resp2() bool {
+	return rw.Protocol == 2
+}
+
+const (
+	// pendingStream marks a streamed aggregate in Writer.pending.
+	pendingStream = -1
+
+	// pendingChunks marks a chunked blob in Writer.pending.
+	pendingChunks = -2
+)
+
+// checkWrite validates writing a value of type t if Debug is set.
+//
+// For aggregate headers n is the number of elements, or -1 for streamed aggregates. For blob chunks n is the length
+// of the chunk and for blob strings and errors -1 indicates a chunked blob. For all other types n is ignored.
+func (rw *Writer) checkWrite(t Type, n int64) error {
+	if !rw.Debug {
+		return nil
+	}
+
+	top := int64(0)
+	if len(rw.pending) > 0 {
+		top = rw.pending[len(rw.pending)-1]
+	}
+
+	switch t {
+	case TypeBlobChunk:
+		if top != pendingChunks {
+			return fmt.Errorf("%w: blob chunk written outside of chunked blob", ErrElementCountMismatch)
+		}
+		if n == 0 {
+			rw.pending = rw.pending[:len(rw.pending)-1]
+		}
+		return nil
+	case TypeEnd:
+		switch {
+		case top > 0:
+			return fmt.Errorf("%w: end written with %d elements outstanding", ErrElementCountMismatch, top)
+		case top != pendingStream:
+			return fmt.Errorf("%w: end written outside of streamed aggregate", ErrElementCountMismatch)
+		}
+		rw.pending = rw.pending[:len(rw.pending)-1]
+		return nil
+	}
+
+	if top == pendingChunks {
+		return fmt.Errorf("%w: %q written inside chunked blob", ErrElementCountMismatch, t)
+	}
+
+	// attributes are not counted as elements of the surrounding aggregate
+	if t != TypeAttribute && top > 0 {
+		if top--; top == 0 {
+			rw.pending = rw.pending[:len(rw.pending)-1]
+		} else {
+			rw.pending[len(rw.pending)-1] = top
+		}
+	}
+
+	switch t {
+	case TypeArray, TypePush, TypeSet:
+		if n < 0 {
+			rw.pending = append(rw.pending, pendingStream)
+		} else if n > 0 {
+			rw.pending = append(rw.pending, n)
+		}
+	case TypeAttribute, TypeMap:
+		if n < 0 {
+			rw.pending = append(rw.pending, pendingStream)
+		} else if n > math.MaxInt64/2 {
+			rw.pending = append(rw.pending, math.MaxInt64)
+		} else if n > 0 {
+			rw.pending = append(rw.pending, n*2)
+		}
+	case TypeBlobError, TypeBlobString:
+		if n < 0 {
+			rw.pending = append(rw.pending, pendingChunks)
+		}
+	}
+
+	return nil
+}
+
+// Verify checks that all aggregates and chunked blobs written so far were completed.
+//
+// If Debug is true and there are outstanding elements, an error wrapping ErrElementCountMismatch is returned. If Debug
+// is false, Verify always returns nil.
+func (rw *Writer) Verify() error {
+	if len(rw.pending) == 0 {
+		return nil
+	}
+	switch top := rw.pending[len(rw.pending)-1]; top {
+	case pendingChunks:
+		return fmt.Errorf("%w: chunked blob was not terminated", ErrElementCountMismatch)
+	case pendingStream:
+		return fmt.Errorf("%w: streamed aggregate was not terminated", ErrElementCountMismatch)
+	default:
+		return fmt.Errorf("%w: %d elements outstanding", ErrElementCountMismatch, top)
+	}
+}
+
 func (rw *Writer) writeAggregateHeader(t Type, n int64) error {
 	if n < 0 {
 		return ErrInvalidAggregateTypeLength
 	}
+	if err := rw.checkWrite(t, n); err != nil {
+		return err
+	}
 	return rw.writeNumber(t, n)
 }
 
 func (rw *Writer) writeAggregateStreamHeader(t Type) error {
-	rw.buf = append(rw.buf[:0], byte(t), '?', '\r', '\n')
-	_, err := rw.w.Write(rw.buf)
-	return err
+	if err := rw.checkWrite(t, -1); err != nil {
+		return err
+	}
+	rw.buf = rw.appendEOL(append(rw.buf[:0], byte(t), '?'))
+	return rw.writeBuf()
 }
 
 func (rw *Writer) writeBlobStreamHeader(t Type) error {
-	rw.buf = append(rw.buf[:0], byte(t), '?', '\r', '\n')
-	_, err := rw.w.Write(rw.buf)
-	return err
+	if err := rw.checkWrite(t, -1); err != nil {
+		return err
+	}
+	rw.buf = rw.appendEOL(append(rw.buf[:0], byte(t), '?'))
+	return rw.writeBuf()
 }
 
 func (rw *Writer) writeBlob(t Type, s []byte) error {
+	if err := rw.checkWrite(t, int64(len(s))); err != nil {
+		return err
+	}
 	rw.buf = rw.buf[:0]
 	rw.buf = append(rw.buf, byte(t))
 	rw.buf = strconv.AppendUint(rw.buf, uint64(len(s)), 10)
-	rw.buf = append(rw.buf, '\r', '\n')
+	rw.buf = rw.appendEOL(rw.buf)
+	if rw.vectored(len(s)) {
+		return rw.writeBufVectored(s)
+	}
 	rw.buf = append(rw.buf, s...)
-	rw.buf = append(rw.buf, '\r', '\n')
-	_, err := rw.w.Write(rw.buf)
-	return err
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
+}
+
+// writeBlobStringString writes s as blob string like writeBlob, without converting s to a []byte.
+func (rw *Writer) writeBlobStringString(s string) error {
+	if err := rw.checkWrite(TypeBlobString, 0); err != nil {
+		return err
+	}
+	rw.buf = rw.buf[:0]
+	rw.buf = append(rw.buf, byte(TypeBlobString))
+	rw.buf = strconv.AppendUint(rw.buf, uint64(len(s)), 10)
+	rw.buf = rw.appendEOL(rw.buf)
+	rw.buf = append(rw.buf, s...)
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
 }
 
 func (rw *Writer) writeNumber(t Type, n int64) error {
 	rw.buf = append(rw.buf[:0], byte(t))
 	rw.buf = strconv.AppendInt(rw.buf, n, 10)
-	rw.buf = append(rw.buf, '\r', '\n')
-	_, err := rw.w.Write(rw.buf)
-	return err
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
 }
 
 func (rw *Writer) writeSimple(t Type, s []byte) error {
 	if bytes.ContainsAny(s, "\r\n") {
 		return ErrInvalidSimpleValue
 	}
+	return rw.writeSimpleUnsafe(t, s)
+}
+
+func (rw *Writer) writeSimpleUnsafe(t Type, s []byte) error {
+	if err := rw.checkWrite(t, 0); err != nil {
+		return err
+	}
 	rw.buf = append(rw.buf[:0], byte(t))
 	rw.buf = append(rw.buf, s...)
-	rw.buf = append(rw.buf, '\r', '\n')
-	_, err := rw.w.Write(rw.buf)
-	return err
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
 }
 
 // WriteArrayHeader writes an array header for an array of length n.
@@ -82,6 +537,11 @@ func (rw *Writer) WriteArrayHeader(n int64) error {
 	return rw.writeAggregateHeader(TypeArray, n)
 }
 
+// WriteArrayHeaderInt is like WriteArrayHeader, but takes the length as int, as returned by len.
+func (rw *Writer) WriteArrayHeaderInt(n int) error {
+	return rw.WriteArrayHeader(int64(n))
+}
+
 // WriteArrayStreamHeader writes an array header for a streamed array.
 func (rw *Writer) WriteArrayStreamHeader() error {
 	return rw.writeAggregateStreamHeader(TypeArray)
@@ -94,26 +554,95 @@ func (rw *Writer) WriteAttributeHeader(n int64) error {
 	return rw.writeAggregateHeader(TypeAttribute, n)
 }
 
+// WriteAttributeHeaderInt is like WriteAttributeHeader, but takes the length as int, as returned by len.
+func (rw *Writer) WriteAttributeHeaderInt(n int) error {
+	return rw.WriteAttributeHeader(int64(n))
+}
+
 // WriteAttributeStreamHeader writes an attribute header for a streamed attribute.
 func (rw *Writer) WriteAttributeStreamHeader() error {
 	return rw.writeAggregateStreamHeader(TypeAttribute)
 }
 
+// WriteAttributeStringString writes an attribute with the entries of m, writing both keys and values as blob strings.
+//
+// The attributed value must be written after the attribute using the usual methods. The order in which the entries
+// are written is unspecified.
+func (rw *Writer) WriteAttributeStringString(m map[string]string) error {
+	if err := rw.WriteAttributeHeader(int64(len(m))); err != nil {
+		return err
+	}
+	for k, v := range m {
+		if err := rw.writeBlobStringString(k); err != nil {
+			return err
+		}
+		if err := rw.writeBlobStringString(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // WriteBigNumber writes n using the RESP big number type.
 func (rw *Writer) WriteBigNumber(n *big.Int) error {
+	if rw.resp2() {
+		var buf [64]byte
+		return rw.writeBlob(TypeBlobString, n.Append(buf[:0], 10))
+	}
+	if err := rw.checkWrite(TypeBigNumber, 0); err != nil {
+		return err
+	}
 	rw.buf = append(rw.buf[:0], byte(TypeBigNumber))
 	rw.buf = n.Append(rw.buf, 10)
-	rw.buf = append(rw.buf, '\r', '\n')
-	_, err := rw.w.Write(rw.buf)
-	return err
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
+}
+
+// WriteBigNumberString writes the decimal integer s using the RESP big number type.
+//
+// s must consist of one or more decimal digits with an optional leading + or -. Otherwise ErrInvalidBigNumber is
+// returned.
+func (rw *Writer) WriteBigNumberString(s string) error {
+	rw.buf = append(rw.buf[:0], byte(TypeBigNumber))
+	rw.buf = append(rw.buf, s...)
+	if !isBigNumber(rw.buf[1:]) {
+		return fmt.Errorf("%w: %s", ErrInvalidBigNumber, truncateErrorValue(rw.buf[1:]))
+	}
+	if rw.resp2() {
+		return rw.writeBlobStringString(s)
+	}
+	if err := rw.checkWrite(TypeBigNumber, 0); err != nil {
+		return err
+	}
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
+}
+
+// writeBigNumberBytes is like WriteBigNumberString, but takes a []byte.
+func (rw *Writer) writeBigNumberBytes(b []byte) error {
+	if !isBigNumber(b) {
+		return fmt.Errorf("%w: %s", ErrInvalidBigNumber, truncateErrorValue(b))
+	}
+	if rw.resp2() {
+		return rw.writeBlob(TypeBlobString, b)
+	}
+	if err := rw.checkWrite(TypeBigNumber, 0); err != nil {
+		return err
+	}
+	rw.buf = append(rw.buf[:0], byte(TypeBigNumber))
+	rw.buf = append(rw.buf, b...)
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
 }
 
 // WriteBlobChunk writes the byte slice s as blob string chunk.
 func (rw *Writer) WriteBlobChunk(s []byte) error {
 	if len(s) == 0 {
-		rw.buf = append(rw.buf[:0], byte(TypeBlobChunk), '0', '\r', '\n')
-		_, err := rw.w.Write(rw.buf)
-		return err
+		if err := rw.checkWrite(TypeBlobChunk, 0); err != nil {
+			return err
+		}
+		rw.buf = rw.appendEOL(append(rw.buf[:0], byte(TypeBlobChunk), '0'))
+		return rw.writeBuf()
 	}
 	return rw.writeBlob(TypeBlobChunk, s)
 }
@@ -124,10 +653,48 @@ func (rw *Writer) WriteBlobErrorStreamHeader() error {
 }
 
 // WriteBlobError writes the byte slice s as blob string.
+//
+// If StrictErrors is true and s is empty, ErrEmptyError is returned.
 func (rw *Writer) WriteBlobError(s []byte) error {
+	if rw.StrictErrors && len(s) == 0 {
+		return ErrEmptyError
+	}
 	return rw.writeBlob(TypeBlobError, s)
 }
 
+// WriteBlobErrorCode writes a blob error consisting of the given error code, followed by a space and msg.
+//
+// The code must consist of one or more uppercase ASCII letters, for example "ERR" or "WRONGTYPE". Otherwise
+// ErrInvalidErrorCode is returned.
+func (rw *Writer) WriteBlobErrorCode(code string, msg []byte) error {
+	if !isValidErrorCode(code) {
+		return ErrInvalidErrorCode
+	}
+	if err := rw.checkWrite(TypeBlobError, 0); err != nil {
+		return err
+	}
+	rw.buf = append(rw.buf[:0], byte(TypeBlobError))
+	rw.buf = strconv.AppendUint(rw.buf, uint64(len(code)+1+len(msg)), 10)
+	rw.buf = rw.appendEOL(rw.buf)
+	rw.buf = append(rw.buf, code...)
+	rw.buf = append(rw.buf, ' ')
+	rw.buf = append(rw.buf, msg...)
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
+}
+
+func isValidErrorCode(code string) bool {
+	if code == "" {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < 'A' || code[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
 // WriteBlobStringStreamHeader writes a blob error stream header.
 func (rw *Writer) WriteBlobStringStreamHeader() error {
 	return rw.writeBlobStreamHeader(TypeBlobString)
@@ -141,76 +708,269 @@ func (rw *Writer) WriteBlobString(s []byte) error {
 var boolFalseBytes = []byte("#f\r\n")
 var boolTrueBytes = []byte("#t\r\n")
 
+var boolFalseRESP2Bytes = []byte(":0\r\n")
+var boolTrueRESP2Bytes = []byte(":1\r\n")
+
 // WriteBoolean writes the boolean b using the RESP boolean type.
 func (rw *Writer) WriteBoolean(b bool) error {
-	if b {
-		_, err := rw.w.Write(boolTrueBytes)
+	if err := rw.checkWrite(TypeBoolean, 0); err != nil {
 		return err
 	}
-	_, err := rw.w.Write(boolFalseBytes)
-	return err
+	if rw.resp2() {
+		if b {
+			return rw.writeConst(boolTrueRESP2Bytes)
+		}
+		return rw.writeConst(boolFalseRESP2Bytes)
+	}
+	if b {
+		return rw.writeConst(boolTrueBytes)
+	}
+	return rw.writeConst(boolFalseBytes)
 }
 
 var doubleInfBytes = []byte(",inf\r\n")
+var doubleNaNBytes = []byte(",nan\r\n")
 var doubleNegativeInfBytes = []byte(",-inf\r\n")
 
 // WriteDouble writes the number f using the RESP double type.
+//
+// Infinite values are written as "inf" (or DoublePositiveInf, if set) and "-inf" and NaN is written as "nan".
 func (rw *Writer) WriteDouble(f float64) error {
-	if math.IsInf(f, 1) {
-		_, err := rw.w.Write(doubleInfBytes)
+	if err := rw.checkDoubleFormat(); err != nil {
 		return err
 	}
-	if math.IsInf(f, -1) {
-		_, err := rw.w.Write(doubleNegativeInfBytes)
+	if err := rw.checkDoublePositiveInf(); err != nil {
+		return err
+	}
+	if rw.resp2() {
+		return rw.writeDoubleRESP2(f)
+	}
+	if err := rw.checkWrite(TypeDouble, 0); err != nil {
 		return err
 	}
+	if math.IsNaN(f) {
+		return rw.writeConst(doubleNaNBytes)
+	}
+	if math.IsInf(f, 1) {
+		if len(rw.DoublePositiveInf) > 0 {
+			rw.buf = append(rw.buf[:0], byte(TypeDouble))
+			rw.buf = append(rw.buf, rw.DoublePositiveInf...)
+			rw.buf = rw.appendEOL(rw.buf)
+			return rw.writeBuf()
+		}
+		return rw.writeConst(doubleInfBytes)
+	}
+	if math.IsInf(f, -1) {
+		return rw.writeConst(doubleNegativeInfBytes)
+	}
 	rw.buf = append(rw.buf[:0], byte(TypeDouble))
-	rw.buf = strconv.AppendFloat(rw.buf, f, 'f', -1, 64)
-	rw.buf = append(rw.buf, '\r', '\n')
-	_, err := rw.w.Write(rw.buf)
-	return err
+	rw.buf = rw.appendDouble(rw.buf, f)
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
+}
+
+func (rw *Writer) writeDoubleRESP2(f float64) error {
+	var buf [32]byte
+	switch {
+	case math.IsInf(f, 1) && len(rw.DoublePositiveInf) > 0:
+		return rw.writeBlob(TypeBlobString, rw.DoublePositiveInf)
+	case math.IsInf(f, 1):
+		return rw.writeBlob(TypeBlobString, append(buf[:0], "inf"...))
+	case math.IsInf(f, -1):
+		return rw.writeBlob(TypeBlobString, append(buf[:0], "-inf"...))
+	case math.IsNaN(f):
+		return rw.writeBlob(TypeBlobString, append(buf[:0], "nan"...))
+	default:
+		return rw.writeBlob(TypeBlobString, rw.appendDouble(buf[:0], f))
+	}
 }
 
 var endBytes = []byte(".\r\n")
 
 // WriteEnd writes a RESP end value.
 func (rw *Writer) WriteEnd() error {
-	_, err := rw.w.Write(endBytes)
-	return err
+	if err := rw.checkWrite(TypeEnd, 0); err != nil {
+		return err
+	}
+	return rw.writeConst(endBytes)
+}
+
+// WriteGoError writes the message of the given error as a simple error.
+//
+// Any \r or \n in the error message is replaced with a space, so that the message always fits in a single line.
+//
+// If err is nil, an error wrapping ErrEmptyError is returned and nothing is written.
+func (rw *Writer) WriteGoError(err error) error {
+	if err == nil {
+		return fmt.Errorf("%w: can not write nil error", ErrEmptyError)
+	}
+	if err := rw.checkWrite(TypeSimpleError, 0); err != nil {
+		return err
+	}
+	msg := err.Error()
+	rw.buf = append(rw.buf[:0], byte(TypeSimpleError))
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c == '\r' || c == '\n' {
+			rw.buf = append(rw.buf, ' ')
+		} else {
+			rw.buf = append(rw.buf, c)
+		}
+	}
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
+}
+
+// WriteInteger writes n using the RESP number type, also known as integer.
+func (rw *Writer) WriteInteger(n int64) error {
+	if err := rw.checkWrite(TypeNumber, 0); err != nil {
+		return err
+	}
+	return rw.writeNumber(TypeNumber, n)
+}
+
+// WriteLine writes a single line consisting of the given prefix byte followed by payload.
+//
+// This can be used to write types that are not supported by Writer, for example types added by protocol extensions.
+// WriteLine performs no validation beyond checking that payload contains neither \r nor \n, in which case
+// ErrInvalidSimpleValue is returned. In particular the prefix is not checked against the known types.
+func (rw *Writer) WriteLine(prefix byte, payload []byte) error {
+	return rw.writeSimple(Type(prefix), payload)
+}
+
+// WriteMapAny writes a map with the entries of m, writing keys as blob strings and values using WriteValue.
+//
+// This can be used to write maps with values of different types, like the reply to HELLO. The order in which the
+// entries are written is unspecified.
+func (rw *Writer) WriteMapAny(m map[string]interface{}) error {
+	if err := rw.WriteMapHeader(int64(len(m))); err != nil {
+		return err
+	}
+	for k, v := range m {
+		if err := rw.writeBlobStringString(k); err != nil {
+			return err
+		}
+		if err := rw.WriteValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // WriteMapHeader writes a map header for a map with n field-value items.
 //
 // If n is < 0, ErrInvalidAggregateTypeLength is returned.
 func (rw *Writer) WriteMapHeader(n int64) error {
+	if rw.resp2() {
+		if n > math.MaxInt64/2 {
+			return ErrInvalidAggregateTypeLength
+		}
+		return rw.writeAggregateHeader(TypeArray, n*2)
+	}
 	return rw.writeAggregateHeader(TypeMap, n)
 }
 
+// WriteMapHeaderInt is like WriteMapHeader, but takes the length as int, as returned by len.
+func (rw *Writer) WriteMapHeaderInt(n int) error {
+	return rw.WriteMapHeader(int64(n))
+}
+
 // WriteMapStreamHeader writes a map header for a streamed map.
 func (rw *Writer) WriteMapStreamHeader() error {
 	return rw.writeAggregateStreamHeader(TypeMap)
 }
 
 var nullBytes = []byte("_\r\n")
+var nullRESP2Bytes = []byte("$-1\r\n")
+var nullRESP2ArrayBytes = []byte("*-1\r\n")
 
 // WriteNull writes a RESP null value.
 func (rw *Writer) WriteNull() error {
-	_, err := rw.w.Write(nullBytes)
-	return err
+	if err := rw.checkWrite(TypeNull, 0); err != nil {
+		return err
+	}
+	if rw.resp2() {
+		return rw.writeConst(nullRESP2Bytes)
+	}
+	return rw.writeConst(nullBytes)
 }
 
-// WriteNumber writes the number i using the RESP integer type.
+// WriteNullTyped writes a RESP null value like WriteNull, but allows choosing the RESP2 representation.
+//
+// If Protocol is 2 and forAggregate is true, the null is written as null array ("*-1\r\n"). Otherwise it is written
+// as null blob string ("$-1\r\n"), same as WriteNull. Some RESP2 clients use this to distinguish a missing value
+// from a missing collection. For RESP3 the null type is always used.
+func (rw *Writer) WriteNullTyped(forAggregate bool) error {
+	if !rw.resp2() || !forAggregate {
+		return rw.WriteNull()
+	}
+	if err := rw.checkWrite(TypeNull, 0); err != nil {
+		return err
+	}
+	return rw.writeConst(nullRESP2ArrayBytes)
+}
+
+// WriteNumber writes n using the RESP number type. It is equivalent to WriteInteger and kept for compatibility.
 func (rw *Writer) WriteNumber(n int64) error {
-	return rw.writeNumber(TypeNumber, n)
+	return rw.WriteInteger(n)
+}
+
+// WritePipeline writes the given commands, each as an array of blob strings, using a single write to the underlying
+// io.Writer.
+//
+// If any command has no arguments, ErrEmptyCommand is returned and nothing is written.
+func (rw *Writer) WritePipeline(cmds ...[][]byte) error {
+	for i, cmd := range cmds {
+		if len(cmd) == 0 {
+			return fmt.Errorf("%w: command at index %d", ErrEmptyCommand, i)
+		}
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+	rw.buf = rw.buf[:0]
+	for _, cmd := range cmds {
+		if err := rw.appendCommand(cmd); err != nil {
+			return err
+		}
+	}
+	return rw.writeBuf()
+}
+
+func (rw *Writer) appendCommand(args [][]byte) error {
+	if err := rw.checkWrite(TypeArray, int64(len(args))); err != nil {
+		return err
+	}
+	rw.buf = append(rw.buf, byte(TypeArray))
+	rw.buf = strconv.AppendInt(rw.buf, int64(len(args)), 10)
+	rw.buf = rw.appendEOL(rw.buf)
+	for _, arg := range args {
+		if err := rw.checkWrite(TypeBlobString, 0); err != nil {
+			return err
+		}
+		rw.buf = append(rw.buf, byte(TypeBlobString))
+		rw.buf = strconv.AppendInt(rw.buf, int64(len(arg)), 10)
+		rw.buf = rw.appendEOL(rw.buf)
+		rw.buf = append(rw.buf, arg...)
+		rw.buf = rw.appendEOL(rw.buf)
+	}
+	return nil
 }
 
 // WritePushHeader writes a push header for a push array with n items.
 //
 // If n is < 0, ErrInvalidAggregateTypeLength is returned.
 func (rw *Writer) WritePushHeader(n int64) error {
+	if rw.resp2() {
+		return rw.writeAggregateHeader(TypeArray, n)
+	}
 	return rw.writeAggregateHeader(TypePush, n)
 }
 
+// WritePushHeaderInt is like WritePushHeader, but takes the length as int, as returned by len.
+func (rw *Writer) WritePushHeaderInt(n int) error {
+	return rw.WritePushHeader(int64(n))
+}
+
 // WritePushStreamHeader writes a set header for a streamed push.
 func (rw *Writer) WritePushStreamHeader() error {
 	return rw.writeAggregateStreamHeader(TypePush)
@@ -220,26 +980,310 @@ func (rw *Writer) WritePushStreamHeader() error {
 //
 // If n is < 0, ErrInvalidAggregateTypeLength is returned.
 func (rw *Writer) WriteSetHeader(n int64) error {
+	if rw.resp2() {
+		return rw.writeAggregateHeader(TypeArray, n)
+	}
 	return rw.writeAggregateHeader(TypeSet, n)
 }
 
+// WriteSetHeaderInt is like WriteSetHeader, but takes the length as int, as returned by len.
+func (rw *Writer) WriteSetHeaderInt(n int) error {
+	return rw.WriteSetHeader(int64(n))
+}
+
 // WriteSetStreamHeader writes a set header for a streamed set.
 func (rw *Writer) WriteSetStreamHeader() error {
 	return rw.writeAggregateStreamHeader(TypeSet)
 }
 
+// WriteSetStringSet writes a set containing the keys of m as blob strings.
+//
+// The order in which the keys are written is unspecified. Use WriteSetStrings with sorted keys if a deterministic
+// order is required.
+func (rw *Writer) WriteSetStringSet(m map[string]struct{}) error {
+	if err := rw.WriteSetHeader(int64(len(m))); err != nil {
+		return err
+	}
+	for k := range m {
+		if err := rw.writeBlobStringString(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSetStrings writes a set containing the given keys as blob strings, in the given order.
+func (rw *Writer) WriteSetStrings(keys ...string) error {
+	if err := rw.WriteSetHeader(int64(len(keys))); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := rw.writeBlobStringString(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // WriteSimpleError writes the byte slice s as a simple error.
-// If s contains \r or \n, ErrInvalidSimpleValue is returned.
+// If s contains \r or \n, ErrInvalidSimpleValue is returned. If StrictErrors is true and s is empty, ErrEmptyError is
+// returned.
 func (rw *Writer) WriteSimpleError(s []byte) error {
+	if rw.StrictErrors && len(s) == 0 {
+		return ErrEmptyError
+	}
 	return rw.writeSimple(TypeSimpleError, s)
 }
 
+// WriteSimpleErrorCode writes a simple error consisting of the given error code, followed by a space and msg.
+//
+// The code must consist of one or more uppercase ASCII letters, for example "ERR" or "WRONGTYPE". Otherwise
+// ErrInvalidErrorCode is returned. If msg contains \r or \n, ErrInvalidSimpleValue is returned.
+func (rw *Writer) WriteSimpleErrorCode(code string, msg []byte) error {
+	if !isValidErrorCode(code) {
+		return ErrInvalidErrorCode
+	}
+	if bytes.ContainsAny(msg, "\r\n") {
+		return ErrInvalidSimpleValue
+	}
+	if err := rw.checkWrite(TypeSimpleError, 0); err != nil {
+		return err
+	}
+	rw.buf = append(rw.buf[:0], byte(TypeSimpleError))
+	rw.buf = append(rw.buf, code...)
+	rw.buf = append(rw.buf, ' ')
+	rw.buf = append(rw.buf, msg...)
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
+}
+
+// WriteSimpleErrorUnsafe writes the byte slice s as a simple error without checking s for \r or \n.
+//
+// WARNING: The caller must guarantee that s contains neither \r nor \n. Otherwise the written data is invalid and
+// can be used to inject arbitrary values into the stream. Only use this for constant or otherwise known-safe values.
+func (rw *Writer) WriteSimpleErrorUnsafe(s []byte) error {
+	return rw.writeSimpleUnsafe(TypeSimpleError, s)
+}
+
 // WriteSimpleString writes the byte slice s as a simple string.
 // If s contains \r or \n, ErrInvalidSimpleValue is returned.
 func (rw *Writer) WriteSimpleString(s []byte) error {
 	return rw.writeSimple(TypeSimpleString, s)
 }
 
+// WriteSimpleStringSanitized writes the byte slice s as a simple string, replacing each \r and \n in s with
+// SimpleStringReplacement.
+//
+// The replacement is done while copying s into the internal buffer, so s itself is never modified.
+func (rw *Writer) WriteSimpleStringSanitized(s []byte) error {
+	if err := rw.checkWrite(TypeSimpleString, 0); err != nil {
+		return err
+	}
+	repl := rw.SimpleStringReplacement
+	if repl == 0 || repl == '\r' || repl == '\n' {
+		repl = ' '
+	}
+	rw.buf = append(rw.buf[:0], byte(TypeSimpleString))
+	rw.buf = append(rw.buf, s...)
+	for i, c := range rw.buf[1:] {
+		if c == '\r' || c == '\n' {
+			rw.buf[i+1] = repl
+		}
+	}
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
+}
+
+// WriteSimpleStringUnsafe writes the byte slice s as a simple string without checking s for \r or \n.
+//
+// WARNING: The caller must guarantee that s contains neither \r nor \n. Otherwise the written data is invalid and
+// can be used to inject arbitrary values into the stream. Only use this for constant or otherwise known-safe values.
+func (rw *Writer) WriteSimpleStringUnsafe(s []byte) error {
+	return rw.writeSimpleUnsafe(TypeSimpleString, s)
+}
+
+// WriteStringMatrix writes an array containing an array for each row in rows, with the strings of the row written as
+// blob strings.
+func (rw *Writer) WriteStringMatrix(rows [][]string) error {
+	if err := rw.WriteArrayHeader(int64(len(rows))); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := rw.WriteArrayHeader(int64(len(row))); err != nil {
+			return err
+		}
+		for _, s := range row {
+			if err := rw.writeBlobStringString(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteValue writes the Go value v using the RESP type that best matches the type of v.
+//
+// Values are written as follows:
+//
+//	nil, nil pointers, nil slices and nil maps    null
+//	*big.Int, big.Int                              big number
+//	*RedisError                                    simple error (or blob error, if the error contains \r or \n)
+//	error                                          simple error, see WriteGoError
+//	string, []byte                                 blob string
+//	bool                                           boolean
+//	int, int8, int16, int32, int64                 number
+//	uint, uint8, uint16, uint32, uint64, uintptr   number (or big number, if the value overflows an int64)
+//	float32, float64                               double
+//	slices and arrays                              array
+//	maps                                           map
+//	structs                                        map, see WriteStruct
+//
+// Pointers are dereferenced and the value they point to is written.
+//
+// If v has another type, an error wrapping ErrTypeMismatch is returned. If v is nested more than 1000 levels deep, for
+// example because it contains a cycle, an error wrapping ErrValueTooDeep is returned.
+func (rw *Writer) WriteValue(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		return rw.WriteNull()
+	case string:
+		return rw.writeBlobStringString(v)
+	case []byte:
+		if v == nil {
+			return rw.WriteNull()
+		}
+		return rw.WriteBlobString(v)
+	case bool:
+		return rw.WriteBoolean(v)
+	case int:
+		return rw.WriteNumber(int64(v))
+	case int64:
+		return rw.WriteNumber(v)
+	case float64:
+		return rw.WriteDouble(v)
+	case *big.Int:
+		if v == nil {
+			return rw.WriteNull()
+		}
+		return rw.WriteBigNumber(v)
+	case *RedisError:
+		if v == nil {
+			return rw.WriteNull()
+		}
+		s := v.Error()
+		if strings.ContainsAny(s, "\r\n") {
+			return rw.WriteBlobError([]byte(s))
+		}
+		return rw.WriteSimpleError([]byte(s))
+	case error:
+		return rw.WriteGoError(v)
+	}
+	return rw.writeReflectValue(reflect.ValueOf(v))
+}
+
+// WriteValues writes the given values as array, writing each value using WriteValue.
+//
+// This guarantees that the length of the array matches the number of written elements.
+func (rw *Writer) WriteValues(vs ...interface{}) error {
+	if err := rw.WriteArrayHeader(int64(len(vs))); err != nil {
+		return err
+	}
+	for _, v := range vs {
+		if err := rw.WriteValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var bigIntType = reflect.TypeOf(big.Int{})
+
+// maxValueDepth is the maximum nesting depth of values written using WriteValue. This prevents infinite recursion
+// when writing values that contain cycles.
+const maxValueDepth = 1000
+
+func (rw *Writer) writeReflectValue(v reflect.Value) error {
+	if rw.valueDepth >= maxValueDepth {
+		return fmt.Errorf("%w: exceeded maximum depth of %d", ErrValueTooDeep, maxValueDepth)
+	}
+	rw.valueDepth++
+	err := rw.writeReflectValueKind(v)
+	rw.valueDepth--
+	return err
+}
+
+func (rw *Writer) writeReflectValueKind(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return rw.WriteNull()
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return rw.WriteNull()
+		}
+		return rw.WriteValue(v.Elem().Interface())
+	case reflect.String:
+		return rw.WriteValue(v.String())
+	case reflect.Bool:
+		return rw.WriteBoolean(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rw.WriteNumber(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := v.Uint(); u > math.MaxInt64 {
+			return rw.WriteBigNumber(new(big.Int).SetUint64(u))
+		}
+		return rw.WriteNumber(int64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return rw.WriteDouble(v.Float())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return rw.WriteNull()
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Kind() == reflect.Slice {
+				return rw.WriteBlobString(v.Bytes())
+			}
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return rw.WriteBlobString(b)
+		}
+		if err := rw.WriteArrayHeader(int64(v.Len())); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := rw.writeReflectValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if v.IsNil() {
+			return rw.WriteNull()
+		}
+		if err := rw.WriteMapHeader(int64(v.Len())); err != nil {
+			return err
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := rw.writeReflectValue(iter.Key()); err != nil {
+				return err
+			}
+			if err := rw.writeReflectValue(iter.Value()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		if v.Type() == bigIntType {
+			n := new(big.Int)
+			reflect.ValueOf(n).Elem().Set(v)
+			return rw.WriteBigNumber(n)
+		}
+		return rw.writeStruct(v)
+	default:
+		return fmt.Errorf("%w: can not write value of type %s", ErrTypeMismatch, v.Type())
+	}
+}
+
 const verbatimPrefixLength = 3
 
 // WriteVerbatimString writes the byte slice s unvalidated as a verbatim string using p as prefix.
@@ -249,11 +1293,42 @@ func (rw *Writer) WriteVerbatimString(p string, s string) error {
 	if len(p) != verbatimPrefixLength {
 		return ErrInvalidVerbatimString
 	}
+	if err := rw.checkWrite(TypeVerbatimString, 0); err != nil {
+		return err
+	}
+	if rw.resp2() {
+		rw.buf = append(rw.buf[:0], byte(TypeBlobString))
+		rw.buf = strconv.AppendInt(rw.buf, int64(len(s)), 10)
+		rw.buf = rw.appendEOL(rw.buf)
+		rw.buf = append(rw.buf, s...)
+		rw.buf = rw.appendEOL(rw.buf)
+		return rw.writeBuf()
+	}
 	rw.buf = append(rw.buf[:0], byte(TypeVerbatimString))
 	rw.buf = strconv.AppendInt(rw.buf, int64(len(p)+1+len(s)), 10)
-	rw.buf = append(rw.buf, '\r', '\n', p[0], p[1], p[2], ':')
+	rw.buf = rw.appendEOL(rw.buf)
+	rw.buf = append(rw.buf, p[0], p[1], p[2], ':')
 	rw.buf = append(rw.buf, s...)
-	rw.buf = append(rw.buf, '\r', '\n')
-	_, err := rw.w.Write(rw.buf)
-	return err
+	rw.buf = rw.appendEOL(rw.buf)
+	return rw.writeBuf()
+}
+
+// writeVerbatimStringBytes writes b, which must consist of a 3 character prefix, followed by a colon and the text, as
+// a verbatim string.
+func (rw *Writer) writeVerbatimStringBytes(b []byte) error {
+	if len(b) < verbatimPrefixLength+1 || b[verbatimPrefixLength] != ':' {
+		return ErrInvalidVerbatimString
+	}
+	if rw.resp2() {
+		if err := rw.checkWrite(TypeVerbatimString, 0); err != nil {
+			return err
+		}
+		rw.buf = append(rw.buf[:0], byte(TypeBlobString))
+		rw.buf = strconv.AppendInt(rw.buf, int64(len(b)-verbatimPrefixLength-1), 10)
+		rw.buf = rw.appendEOL(rw.buf)
+		rw.buf = append(rw.buf, b[verbatimPrefixLength+1:]...)
+		rw.buf = rw.appendEOL(rw.buf)
+		return rw.writeBuf()
+	}
+	return rw.writeBlob(TypeVerbatimString, b)
 }
@@ -3,8 +3,15 @@ package resp3_test
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
 	"math/big"
+	"net"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/nussjustin/resp3"
@@ -55,6 +62,87 @@ func TestWriterReset(t *testing.T) {
 	assertBytes(t, "hello", b1.Bytes())
 	assertBytes(t, "world", b2.Bytes())
 	assertBytes(t, "!", b3.Bytes())
+
+	w.Reset(nil)
+	assertError(t, resp3.ErrClosed, w.WriteNull())
+	assertError(t, resp3.ErrClosed, w.Flush())
+
+	w = resp3.NewWriterWithOptions(nil, resp3.WithBuffer(64))
+	assertError(t, resp3.ErrClosed, w.WriteNull())
+	assertError(t, resp3.ErrClosed, w.Flush())
+	w.Reset(&b3)
+	assertError(t, nil, w.WriteNull())
+	assertError(t, nil, w.Flush())
+	assertBytes(t, "!_\r\n", b3.Bytes())
+}
+
+func TestNewWriterWithOptions(t *testing.T) {
+	t.Run("Buffer", func(t *testing.T) {
+		var b bytes.Buffer
+		rw := resp3.NewWriterWithOptions(&b, resp3.WithBuffer(64))
+		assertError(t, nil, rw.WriteNumber(1))
+		assertBytes(t, "", b.Bytes())
+		assertError(t, nil, rw.Flush())
+		assertBytes(t, ":1\r\n", b.Bytes())
+
+		var b2 bytes.Buffer
+		assertError(t, nil, rw.WriteNumber(2))
+		rw.Reset(&b2)
+		assertError(t, nil, rw.WriteNumber(3))
+		assertError(t, nil, rw.Flush())
+		assertBytes(t, ":1\r\n", b.Bytes())
+		assertBytes(t, ":3\r\n", b2.Bytes())
+	})
+
+	t.Run("DoubleFormat", func(t *testing.T) {
+		for _, c := range []struct {
+			format byte
+			prec   int
+			f      float64
+			out    string
+		}{
+			{'f', -1, 1.5, ",1.5\r\n"},
+			{'f', 3, 1.5, ",1.500\r\n"},
+			{'e', -1, 1500, ",1.5e+03\r\n"},
+			{'g', 2, 1.2345, ",1.2\r\n"},
+		} {
+			var b bytes.Buffer
+			rw := resp3.NewWriterWithOptions(&b, resp3.WithDoubleFormat(c.format, c.prec))
+			assertError(t, nil, rw.WriteDouble(c.f))
+			assertBytes(t, c.out, b.Bytes())
+		}
+
+		var b bytes.Buffer
+		rw := resp3.NewWriterWithOptions(&b, resp3.WithDoubleFormat('x', -1))
+		assertError(t, resp3.ErrInvalidDoubleFormat, rw.WriteDouble(1.5))
+		assertError(t, resp3.ErrInvalidDoubleFormat, rw.WriteValue(1.5))
+		assertBytes(t, "", b.Bytes())
+		assertError(t, nil, rw.WriteNumber(1))
+		assertBytes(t, ":1\r\n", b.Bytes())
+	})
+
+	t.Run("MaxRetainedBuffer", func(t *testing.T) {
+		var b bytes.Buffer
+		rw := resp3.NewWriterWithOptions(&b, resp3.WithMaxRetainedBuffer(16))
+		assertError(t, nil, rw.WriteBlobString(bytes.Repeat([]byte("a"), 32)))
+		assertError(t, nil, rw.WriteNumber(1))
+		assertBytes(t, "$32\r\n"+strings.Repeat("a", 32)+"\r\n:1\r\n", b.Bytes())
+	})
+
+	t.Run("Protocol", func(t *testing.T) {
+		var b bytes.Buffer
+		rw := resp3.NewWriterWithOptions(&b, resp3.WithProtocol(2))
+		assertError(t, nil, rw.WriteNull())
+		assertBytes(t, "$-1\r\n", b.Bytes())
+	})
+
+	t.Run("Unbuffered", func(t *testing.T) {
+		var b bytes.Buffer
+		rw := resp3.NewWriterWithOptions(&b)
+		assertError(t, nil, rw.WriteNumber(1))
+		assertBytes(t, ":1\r\n", b.Bytes())
+		assertError(t, nil, rw.Flush())
+	})
 }
 
 func TestWriterWrite(t *testing.T) {
@@ -64,19 +152,27 @@ func TestWriterWrite(t *testing.T) {
 	t.Run("Attribute", makeWriteAggregationTest('|',
 		(*resp3.Writer).WriteAttributeHeader,
 		(*resp3.Writer).WriteAttributeStreamHeader))
+	t.Run("AttributeStringString", testWriteAttributeStringString)
 	t.Run("BigNumber", testWriteBigNumber)
+	t.Run("BigNumberString", testWriteBigNumberString)
 	t.Run("Boolean", testWriteBoolean)
 	t.Run("Double", testWriteDouble)
 	t.Run("BlobError", makeWriteBlobTest('!', (*resp3.Writer).WriteBlobError))
+	t.Run("BlobErrorCode", testWriteBlobErrorCode)
 	t.Run("BlobErrorStreamHeader", makeWriteBlobStreamHeader('!', (*resp3.Writer).WriteBlobErrorStreamHeader))
 	t.Run("BlobString", makeWriteBlobTest('$', (*resp3.Writer).WriteBlobString))
 	t.Run("BlobStringStreamHeader", makeWriteBlobStreamHeader('$', (*resp3.Writer).WriteBlobStringStreamHeader))
 	t.Run("BlobChunk", testWriteBlobChunk)
 	t.Run("End", testWriteEnd)
+	t.Run("GoError", testWriteGoError)
+	t.Run("HeaderInt", testWriteHeaderInt)
+	t.Run("Line", testWriteLine)
+	t.Run("MapAny", testWriteMapAny)
 	t.Run("Map", makeWriteAggregationTest('%',
 		(*resp3.Writer).WriteMapHeader,
 		(*resp3.Writer).WriteMapStreamHeader))
 	t.Run("Null", testWriteNull)
+	t.Run("NullTyped", testWriteNullTyped)
 	t.Run("Number", testWriteNumber)
 	t.Run("Push", makeWriteAggregationTest('>',
 		(*resp3.Writer).WritePushHeader,
@@ -84,8 +180,14 @@ func TestWriterWrite(t *testing.T) {
 	t.Run("Set", makeWriteAggregationTest('~',
 		(*resp3.Writer).WriteSetHeader,
 		(*resp3.Writer).WriteSetStreamHeader))
+	t.Run("SetStringSet", testWriteSetStringSet)
+	t.Run("SetStrings", testWriteSetStrings)
 	t.Run("SimpleError", makeWriteSimpleTest('-', (*resp3.Writer).WriteSimpleError))
+	t.Run("SimpleErrorCode", testWriteSimpleErrorCode)
+	t.Run("SimpleErrorUnsafe", makeWriteSimpleUnsafeTest('-', (*resp3.Writer).WriteSimpleErrorUnsafe))
 	t.Run("SimpleString", makeWriteSimpleTest('+', (*resp3.Writer).WriteSimpleString))
+	t.Run("SimpleStringUnsafe", makeWriteSimpleUnsafeTest('+', (*resp3.Writer).WriteSimpleStringUnsafe))
+	t.Run("StringMatrix", testWriteStringMatrix)
 	t.Run("VerbatimString", testWriteVerbatimString)
 }
 
@@ -178,6 +280,62 @@ func makeWriteSimpleTest(ty resp3.Type, write func(*resp3.Writer, []byte) error)
 	}
 }
 
+func makeWriteSimpleUnsafeTest(ty resp3.Type, write func(*resp3.Writer, []byte) error) func(t *testing.T) {
+	return func(t *testing.T) {
+		rw, assert := newTestWriter(t)
+		for _, c := range []struct {
+			ss string
+			s  string
+		}{
+			{"", string(ty) + "\r\n"},
+			{"hello", string(ty) + "hello\r\n"},
+			{"hello world", string(ty) + "hello world\r\n"},
+			{"hello\r\nworld", string(ty) + "hello\r\nworld\r\n"},
+		} {
+			assert(c.s, nil, write(rw, []byte(c.ss)))
+		}
+	}
+}
+
+func testWriteBlobErrorCode(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	for _, c := range []struct {
+		code string
+		msg  string
+		s    string
+		err  error
+	}{
+		{"ERR", "", "!4\r\nERR \r\n", nil},
+		{"ERR", "hello", "!9\r\nERR hello\r\n", nil},
+		{"WRONGTYPE", "hello\r\nworld", "!22\r\nWRONGTYPE hello\r\nworld\r\n", nil},
+		{"", "hello", "", resp3.ErrInvalidErrorCode},
+		{"Err", "hello", "", resp3.ErrInvalidErrorCode},
+		{"ERR ", "hello", "", resp3.ErrInvalidErrorCode},
+		{"ERR1", "hello", "", resp3.ErrInvalidErrorCode},
+	} {
+		assert(c.s, c.err, rw.WriteBlobErrorCode(c.code, []byte(c.msg)))
+	}
+}
+
+func testWriteSimpleErrorCode(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	for _, c := range []struct {
+		code string
+		msg  string
+		s    string
+		err  error
+	}{
+		{"ERR", "", "-ERR \r\n", nil},
+		{"ERR", "hello", "-ERR hello\r\n", nil},
+		{"WRONGTYPE", "hello world", "-WRONGTYPE hello world\r\n", nil},
+		{"ERR", "hello\r\nworld", "", resp3.ErrInvalidSimpleValue},
+		{"", "hello", "", resp3.ErrInvalidErrorCode},
+		{"err", "hello", "", resp3.ErrInvalidErrorCode},
+	} {
+		assert(c.s, c.err, rw.WriteSimpleErrorCode(c.code, []byte(c.msg)))
+	}
+}
+
 func testWriteBigNumber(t *testing.T) {
 	rw, assert := newTestWriter(t)
 	for _, c := range []struct {
@@ -247,6 +405,7 @@ func testWriteDouble(t *testing.T) {
 		{-10, ",-10\r\n"},
 		{-1.1, ",-1.1\r\n"},
 		{-1, ",-1\r\n"},
+		{math.Copysign(0, -1), ",-0\r\n"},
 		{0, ",0\r\n"},
 		{0.1, ",0.1\r\n"},
 		{0.01, ",0.01\r\n"},
@@ -258,6 +417,14 @@ func testWriteDouble(t *testing.T) {
 		{100.123, ",100.123\r\n"},
 		{1000, ",1000\r\n"},
 		{1000.1234, ",1000.1234\r\n"},
+		{math.Inf(1), ",inf\r\n"},
+		{math.Inf(-1), ",-inf\r\n"},
+		{math.NaN(), ",nan\r\n"},
+		{1 << 53, ",9007199254740992\r\n"},
+		{-(1 << 53), ",-9007199254740992\r\n"},
+		{1<<53 + 2, ",9007199254740994\r\n"},
+		{1 << 60, ",1152921504606847000\r\n"},
+		{1e21, ",1000000000000000000000\r\n"},
 	} {
 		assert(c.s, nil, rw.WriteDouble(c.f))
 	}
@@ -268,11 +435,58 @@ func testWriteEnd(t *testing.T) {
 	assert(".\r\n", nil, rw.WriteEnd())
 }
 
+func testWriteGoError(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	for _, c := range []struct {
+		err error
+		s   string
+	}{
+		{errors.New(""), "-\r\n"},
+		{errors.New("ERR something went wrong"), "-ERR something went wrong\r\n"},
+		{errors.New("ERR hello\nworld"), "-ERR hello world\r\n"},
+		{errors.New("ERR hello\rworld"), "-ERR hello world\r\n"},
+		{errors.New("ERR hello\r\nworld\r\n"), "-ERR hello  world  \r\n"},
+		{fmt.Errorf("ERR wrapped: %w", io.EOF), "-ERR wrapped: EOF\r\n"},
+	} {
+		assert(c.s, nil, rw.WriteGoError(c.err))
+	}
+
+	assert("", resp3.ErrEmptyError, rw.WriteGoError(nil))
+}
+
+func testWriteLine(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	for _, c := range []struct {
+		prefix  byte
+		payload string
+		s       string
+		err     error
+	}{
+		{'@', "", "@\r\n", nil},
+		{'@', "hello", "@hello\r\n", nil},
+		{'+', "OK", "+OK\r\n", nil},
+		{'@', "hello\r\nworld", "", resp3.ErrInvalidSimpleValue},
+		{'@', "hello\nworld", "", resp3.ErrInvalidSimpleValue},
+	} {
+		assert(c.s, c.err, rw.WriteLine(c.prefix, []byte(c.payload)))
+	}
+}
+
 func testWriteNull(t *testing.T) {
 	rw, assert := newTestWriter(t)
 	assert("_\r\n", nil, rw.WriteNull())
 }
 
+func testWriteNullTyped(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	assert("_\r\n", nil, rw.WriteNullTyped(false))
+	assert("_\r\n", nil, rw.WriteNullTyped(true))
+
+	rw.Protocol = 2
+	assert("$-1\r\n", nil, rw.WriteNullTyped(false))
+	assert("*-1\r\n", nil, rw.WriteNullTyped(true))
+}
+
 func testWriteNumber(t *testing.T) {
 	rw, assert := newTestWriter(t)
 	for _, c := range []struct {
@@ -289,10 +503,142 @@ func testWriteNumber(t *testing.T) {
 		{100, ":100\r\n"},
 		{1000, ":1000\r\n"},
 	} {
+		assert(c.s, nil, rw.WriteInteger(c.i))
 		assert(c.s, nil, rw.WriteNumber(c.i))
 	}
 }
 
+func testWriteAttributeStringString(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	assert("|0\r\n", nil, rw.WriteAttributeStringString(nil))
+	assert("|1\r\n$3\r\nttl\r\n$2\r\n60\r\n", nil, rw.WriteAttributeStringString(map[string]string{"ttl": "60"}))
+
+	var b bytes.Buffer
+	rw = resp3.NewWriter(&b)
+	assertError(t, nil, rw.WriteAttributeStringString(map[string]string{"a": "1", "b": "2"}))
+	assertError(t, nil, rw.WriteSimpleString([]byte("OK")))
+	if got := b.String(); got != "|2\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n+OK\r\n" &&
+		got != "|2\r\n$1\r\nb\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\n1\r\n+OK\r\n" {
+		t.Errorf("got %q, expected attribute with entries a and b followed by value", got)
+	}
+}
+
+func testWriteBigNumberString(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	for _, c := range []struct {
+		in  string
+		s   string
+		err error
+	}{
+		{"0", "(0\r\n", nil},
+		{"-1", "(-1\r\n", nil},
+		{"+1", "(+1\r\n", nil},
+		{"123456789123456789123456789123456789", "(123456789123456789123456789123456789\r\n", nil},
+
+		{"", "", resp3.ErrInvalidBigNumber},
+		{"-", "", resp3.ErrInvalidBigNumber},
+		{"1.0", "", resp3.ErrInvalidBigNumber},
+		{"1a", "", resp3.ErrInvalidBigNumber},
+		{"1\r\n", "", resp3.ErrInvalidBigNumber},
+	} {
+		assert(c.s, c.err, rw.WriteBigNumberString(c.in))
+	}
+
+	rw.Protocol = 2
+	assert("$3\r\n-12\r\n", nil, rw.WriteBigNumberString("-12"))
+}
+
+func testWriteSetStringSet(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	assert("~0\r\n", nil, rw.WriteSetStringSet(nil))
+	assert("~1\r\n$5\r\nhello\r\n", nil, rw.WriteSetStringSet(map[string]struct{}{"hello": {}}))
+
+	var b bytes.Buffer
+	rw = resp3.NewWriter(&b)
+	assertError(t, nil, rw.WriteSetStringSet(map[string]struct{}{"a": {}, "b": {}}))
+	if got := b.String(); got != "~2\r\n$1\r\na\r\n$1\r\nb\r\n" && got != "~2\r\n$1\r\nb\r\n$1\r\na\r\n" {
+		t.Errorf("got %q, expected set with members a and b", got)
+	}
+}
+
+func testWriteHeaderInt(t *testing.T) {
+	for _, c := range []struct {
+		ty    resp3.Type
+		write func(*resp3.Writer, int) error
+	}{
+		{resp3.TypeArray, (*resp3.Writer).WriteArrayHeaderInt},
+		{resp3.TypeAttribute, (*resp3.Writer).WriteAttributeHeaderInt},
+		{resp3.TypeMap, (*resp3.Writer).WriteMapHeaderInt},
+		{resp3.TypePush, (*resp3.Writer).WritePushHeaderInt},
+		{resp3.TypeSet, (*resp3.Writer).WriteSetHeaderInt},
+	} {
+		rw, assert := newTestWriter(t)
+		assert(string(c.ty)+"0\r\n", nil, c.write(rw, 0))
+		assert(string(c.ty)+"3\r\n", nil, c.write(rw, len([]string{"a", "b", "c"})))
+		assert("", resp3.ErrInvalidAggregateTypeLength, c.write(rw, -1))
+	}
+
+	rw, assert := newTestWriter(t)
+	rw.Protocol = 2
+	assert("*4\r\n", nil, rw.WriteMapHeaderInt(2))
+	assert("*2\r\n", nil, rw.WriteSetHeaderInt(2))
+}
+
+func testWriteMapAny(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	assert("%0\r\n", nil, rw.WriteMapAny(nil))
+	assert("%1\r\n$7\r\nmodules\r\n*0\r\n", nil, rw.WriteMapAny(map[string]interface{}{"modules": []string{}}))
+	assert("%1\r\n$2\r\nch\r\n", resp3.ErrTypeMismatch, rw.WriteMapAny(map[string]interface{}{"ch": make(chan int)}))
+
+	var b bytes.Buffer
+	rw = resp3.NewWriter(&b)
+	hello := map[string]interface{}{
+		"server":  "redis",
+		"version": "6.0.0",
+		"proto":   3,
+		"id":      int64(10),
+		"mode":    "standalone",
+		"role":    "master",
+		"modules": []interface{}{},
+	}
+	assertError(t, nil, rw.WriteMapAny(hello))
+
+	rr := resp3.NewReader(&b)
+	got, err := rr.ReadMapStringAny(nil)
+	assertError(t, nil, err)
+	expected := map[string]interface{}{
+		"server":  "redis",
+		"version": "6.0.0",
+		"proto":   int64(3),
+		"id":      int64(10),
+		"mode":    "standalone",
+		"role":    "master",
+		"modules": []interface{}{},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %#v, expected %#v", got, expected)
+	}
+}
+
+func testWriteSetStrings(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	assert("~0\r\n", nil, rw.WriteSetStrings())
+	assert("~2\r\n$5\r\nhello\r\n$0\r\n\r\n", nil, rw.WriteSetStrings("hello", ""))
+	assert("~3\r\n$1\r\nc\r\n$1\r\na\r\n$1\r\nb\r\n", nil, rw.WriteSetStrings("c", "a", "b"))
+}
+
+func testWriteStringMatrix(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	assert("*0\r\n", nil, rw.WriteStringMatrix(nil))
+	assert("*2\r\n*0\r\n*0\r\n", nil, rw.WriteStringMatrix([][]string{nil, {}}))
+	assert("*2\r\n*2\r\n$1\r\na\r\n$0\r\n\r\n*1\r\n$5\r\nhello\r\n", nil,
+		rw.WriteStringMatrix([][]string{{"a", ""}, {"hello"}}))
+
+	rw.Debug = true
+	assert("*1\r\n*1\r\n$1\r\na\r\n", nil, rw.WriteStringMatrix([][]string{{"a"}}))
+	assert("", nil, rw.Verify())
+}
+
 func testWriteVerbatimString(t *testing.T) {
 	rw, assert := newTestWriter(t)
 	for _, c := range []struct {
@@ -314,3 +660,603 @@ func testWriteVerbatimString(t *testing.T) {
 		assert(c.s, c.err, rw.WriteVerbatimString(c.p, c.v))
 	}
 }
+
+func TestWriterDebug(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		write  func(*resp3.Writer) error
+		err    error
+		verify error
+	}{
+		{
+			name: "Scalars",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteNumber(1)
+				return rw.WriteSimpleString([]byte("OK"))
+			},
+		},
+		{
+			name: "Array",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteArrayHeader(2)
+				_ = rw.WriteNumber(1)
+				return rw.WriteNull()
+			},
+		},
+		{
+			name: "ArrayMissingElements",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteArrayHeader(3)
+				_ = rw.WriteNumber(1)
+				return rw.WriteNumber(2)
+			},
+			verify: resp3.ErrElementCountMismatch,
+		},
+		{
+			name: "Map",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteMapHeader(1)
+				_ = rw.WriteSimpleString([]byte("key"))
+				return rw.WriteValue([]int{1, 2})
+			},
+		},
+		{
+			name: "MapMissingValue",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteMapHeader(1)
+				return rw.WriteSimpleString([]byte("key"))
+			},
+			verify: resp3.ErrElementCountMismatch,
+		},
+		{
+			name: "Attribute",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteArrayHeader(1)
+				_ = rw.WriteAttributeHeader(1)
+				_ = rw.WriteSimpleString([]byte("key"))
+				_ = rw.WriteNumber(1)
+				return rw.WriteNumber(2)
+			},
+		},
+		{
+			name: "Stream",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteArrayStreamHeader()
+				_ = rw.WriteArrayHeader(1)
+				_ = rw.WriteNumber(1)
+				return rw.WriteEnd()
+			},
+		},
+		{
+			name: "StreamNotTerminated",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteArrayStreamHeader()
+				return rw.WriteNumber(1)
+			},
+			verify: resp3.ErrElementCountMismatch,
+		},
+		{
+			name: "EndWithOutstandingElements",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteArrayStreamHeader()
+				_ = rw.WriteArrayHeader(2)
+				_ = rw.WriteNumber(1)
+				return rw.WriteEnd()
+			},
+			err:    resp3.ErrElementCountMismatch,
+			verify: resp3.ErrElementCountMismatch,
+		},
+		{
+			name:  "EndOutsideStream",
+			write: (*resp3.Writer).WriteEnd,
+			err:   resp3.ErrElementCountMismatch,
+		},
+		{
+			name: "ChunkedBlob",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteArrayHeader(1)
+				_ = rw.WriteBlobStringStreamHeader()
+				_ = rw.WriteBlobChunk([]byte("hello"))
+				return rw.WriteBlobChunk(nil)
+			},
+		},
+		{
+			name: "ValueInChunkedBlob",
+			write: func(rw *resp3.Writer) error {
+				_ = rw.WriteBlobStringStreamHeader()
+				return rw.WriteNumber(1)
+			},
+			err:    resp3.ErrElementCountMismatch,
+			verify: resp3.ErrElementCountMismatch,
+		},
+		{
+			name: "ChunkOutsideChunkedBlob",
+			write: func(rw *resp3.Writer) error {
+				return rw.WriteBlobChunk([]byte("hello"))
+			},
+			err: resp3.ErrElementCountMismatch,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rw := resp3.NewWriter(ioutil.Discard)
+			rw.Debug = true
+			assertError(t, c.err, c.write(rw))
+			assertError(t, c.verify, rw.Verify())
+
+			rw.Reset(ioutil.Discard)
+			assertError(t, nil, rw.Verify())
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		rw := resp3.NewWriter(ioutil.Discard)
+		assertError(t, nil, rw.WriteArrayHeader(2))
+		assertError(t, nil, rw.WriteEnd())
+		assertError(t, nil, rw.Verify())
+	})
+}
+
+func TestWriterDoubleAlwaysDecimal(t *testing.T) {
+	for _, c := range []struct {
+		opts []resp3.WriterOption
+		f    float64
+		s    string
+	}{
+		{f: 0, s: ",0.0\r\n"},
+		{f: 1, s: ",1.0\r\n"},
+		{f: -10, s: ",-10.0\r\n"},
+		{f: 1.5, s: ",1.5\r\n"},
+		{f: math.Inf(1), s: ",inf\r\n"},
+		{f: math.Inf(-1), s: ",-inf\r\n"},
+		{opts: []resp3.WriterOption{resp3.WithDoubleFormat('e', -1)}, f: 1000, s: ",1.0e+03\r\n"},
+		{opts: []resp3.WriterOption{resp3.WithDoubleFormat('e', -1)}, f: 1500, s: ",1.5e+03\r\n"},
+		{opts: []resp3.WriterOption{resp3.WithDoubleFormat('G', -1)}, f: 1e21, s: ",1.0E+21\r\n"},
+		{opts: []resp3.WriterOption{resp3.WithProtocol(2)}, f: 2, s: "$3\r\n2.0\r\n"},
+	} {
+		var b bytes.Buffer
+		rw := resp3.NewWriterWithOptions(&b, c.opts...)
+		rw.DoubleAlwaysDecimal = true
+		assertError(t, nil, rw.WriteDouble(c.f))
+		assertBytes(t, c.s, b.Bytes())
+	}
+}
+
+func TestWriterDoublePositiveInf(t *testing.T) {
+	var b bytes.Buffer
+	rw := resp3.NewReadWriter(&b)
+	rw.DoublePositiveInf = []byte("+inf")
+	assertError(t, nil, rw.WriteDouble(math.Inf(1)))
+	assertError(t, nil, rw.WriteDouble(math.Inf(-1)))
+	assertError(t, nil, rw.WriteDouble(math.NaN()))
+	assertBytes(t, ",+inf\r\n,-inf\r\n,nan\r\n", b.Bytes())
+
+	for _, check := range []func(float64) bool{
+		func(f float64) bool { return math.IsInf(f, 1) },
+		func(f float64) bool { return math.IsInf(f, -1) },
+		math.IsNaN,
+	} {
+		f, err := rw.ReadDouble()
+		assertError(t, nil, err)
+		if !check(f) {
+			t.Errorf("got unexpected value %f", f)
+		}
+	}
+
+	rw.Writer.Protocol = 2
+	assertError(t, nil, rw.WriteDouble(math.Inf(1)))
+	assertBytes(t, "$4\r\n+inf\r\n", b.Bytes())
+}
+
+func TestWriterDoublePositiveInfInvalid(t *testing.T) {
+	for _, inf := range []string{"infinity\r\n", "+in\nf", "1.5", "-inf", "abc"} {
+		t.Run(inf, func(t *testing.T) {
+			for _, protocol := range []uint8{2, 3} {
+				var b bytes.Buffer
+				rw := resp3.NewWriter(&b)
+				rw.Protocol = protocol
+				rw.DoublePositiveInf = []byte(inf)
+				assertError(t, resp3.ErrInvalidDouble, rw.WriteDouble(math.Inf(1)))
+				assertError(t, resp3.ErrInvalidDouble, rw.WriteDouble(1.5))
+				assertBytes(t, "", b.Bytes())
+			}
+		})
+	}
+
+	var b bytes.Buffer
+	rw := resp3.NewWriter(&b)
+	rw.DoublePositiveInf = []byte("+Infinity")
+	assertError(t, nil, rw.WriteDouble(math.Inf(1)))
+	assertBytes(t, ",+Infinity\r\n", b.Bytes())
+}
+
+func TestWriterLargeBlobs(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("failed to listen on loopback interface: %s", err)
+	}
+	defer l.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		b, _ := ioutil.ReadAll(conn)
+		received <- b
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %s", err)
+	}
+
+	body := strings.Repeat("a", 64*1024)
+
+	rw := resp3.NewWriter(conn)
+	if err := rw.WriteBlobString([]byte(body)); err != nil {
+		t.Fatalf("failed to write blob string: %s", err)
+	}
+	if err := rw.WriteVerbatimString("txt", body); err != nil {
+		t.Fatalf("failed to write verbatim string: %s", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatalf("failed to close connection: %s", err)
+	}
+
+	expected := "$65536\r\n" + body + "\r\n" + "=65540\r\ntxt:" + body + "\r\n"
+	if got := string(<-received); got != expected {
+		t.Errorf("got %d bytes, expected %d bytes", len(got), len(expected))
+	}
+}
+
+func TestWriterStrictErrors(t *testing.T) {
+	rw, assert := newTestWriter(t)
+
+	assert("-\r\n", nil, rw.WriteSimpleError(nil))
+	assert("!0\r\n\r\n", nil, rw.WriteBlobError(nil))
+
+	rw.StrictErrors = true
+	assert("", resp3.ErrEmptyError, rw.WriteSimpleError(nil))
+	assert("", resp3.ErrEmptyError, rw.WriteBlobError([]byte{}))
+	assert("-ERR\r\n", nil, rw.WriteSimpleError([]byte("ERR")))
+	assert("!3\r\nERR\r\n", nil, rw.WriteBlobError([]byte("ERR")))
+	assert("+\r\n", nil, rw.WriteSimpleString(nil))
+}
+
+func TestWriterWriteSimpleStringSanitized(t *testing.T) {
+	rw, assert := newTestWriter(t)
+
+	in := []byte("hello\r\nworld\n")
+	assert("+hello  world \r\n", nil, rw.WriteSimpleStringSanitized(in))
+	assertBytes(t, "hello\r\nworld\n", in)
+
+	assert("+\r\n", nil, rw.WriteSimpleStringSanitized(nil))
+	assert("+OK\r\n", nil, rw.WriteSimpleStringSanitized([]byte("OK")))
+
+	rw.SimpleStringReplacement = '_'
+	assert("+a__b\r\n", nil, rw.WriteSimpleStringSanitized([]byte("a\r\nb")))
+
+	rw.SimpleStringReplacement = '\n'
+	assert("+a  b\r\n", nil, rw.WriteSimpleStringSanitized([]byte("a\r\nb")))
+}
+
+func TestWriterLineEnding(t *testing.T) {
+	rw, assert := newTestWriter(t)
+
+	rw.LineEnding = []byte("\n")
+	assert("+OK\n", nil, rw.WriteSimpleString([]byte("OK")))
+	assert("$5\nhello\n", nil, rw.WriteBlobString([]byte("hello")))
+	assert("=9\ntxt:hello\n", nil, rw.WriteVerbatimString("txt", "hello"))
+	assert("*2\n:1\n#t\n", nil, func() error {
+		_ = rw.WriteArrayHeader(2)
+		_ = rw.WriteNumber(1)
+		return rw.WriteBoolean(true)
+	}())
+	assert("$?\n;0\n", nil, func() error {
+		_ = rw.WriteBlobStringStreamHeader()
+		return rw.WriteBlobChunk(nil)
+	}())
+	assert("_\n.\n", nil, func() error {
+		_ = rw.WriteNull()
+		return rw.WriteEnd()
+	}())
+
+	rw.LineEnding = []byte("\r\n")
+	assert("_\r\n", nil, rw.WriteNull())
+
+	rw.LineEnding = []byte("\r")
+	assert("", resp3.ErrInvalidLineEnding, rw.WriteNull())
+	assert("", resp3.ErrInvalidLineEnding, rw.WriteSimpleString([]byte("OK")))
+
+	var b bytes.Buffer
+	rw = resp3.NewWriter(&b)
+	rw.LineEnding = []byte("\n")
+	body := strings.Repeat("a", 64*1024)
+	assertError(t, nil, rw.WriteBlobString([]byte(body)))
+	assertError(t, nil, rw.WriteVerbatimString("txt", body))
+
+	rr := resp3.NewReader(&b)
+	rr.AllowBareLF = true
+	s, _, err := rr.ReadBlobString(nil)
+	assertError(t, nil, err)
+	assertBytes(t, body, s)
+	vs, err := rr.ReadVerbatimString(nil)
+	assertError(t, nil, err)
+	assertBytes(t, "txt:"+body, vs)
+}
+
+func TestWriterProtocol(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		write func(*resp3.Writer) error
+		resp2 string
+		resp3 string
+		err   error
+	}{
+		{
+			name:  "BigNumber",
+			write: func(rw *resp3.Writer) error { return rw.WriteBigNumber(big.NewInt(-1234)) },
+			resp2: "$5\r\n-1234\r\n",
+			resp3: "(-1234\r\n",
+		},
+		{
+			name:  "BooleanFalse",
+			write: func(rw *resp3.Writer) error { return rw.WriteBoolean(false) },
+			resp2: ":0\r\n",
+			resp3: "#f\r\n",
+		},
+		{
+			name:  "BooleanTrue",
+			write: func(rw *resp3.Writer) error { return rw.WriteBoolean(true) },
+			resp2: ":1\r\n",
+			resp3: "#t\r\n",
+		},
+		{
+			name:  "Double",
+			write: func(rw *resp3.Writer) error { return rw.WriteDouble(1.5) },
+			resp2: "$3\r\n1.5\r\n",
+			resp3: ",1.5\r\n",
+		},
+		{
+			name:  "DoubleInf",
+			write: func(rw *resp3.Writer) error { return rw.WriteDouble(math.Inf(1)) },
+			resp2: "$3\r\ninf\r\n",
+			resp3: ",inf\r\n",
+		},
+		{
+			name:  "DoubleNegativeInf",
+			write: func(rw *resp3.Writer) error { return rw.WriteDouble(math.Inf(-1)) },
+			resp2: "$4\r\n-inf\r\n",
+			resp3: ",-inf\r\n",
+		},
+		{
+			name:  "Map",
+			write: func(rw *resp3.Writer) error { return rw.WriteMapHeader(2) },
+			resp2: "*4\r\n",
+			resp3: "%2\r\n",
+		},
+		{
+			name:  "MapTooLarge",
+			write: func(rw *resp3.Writer) error { return rw.WriteMapHeader(math.MaxInt64/2 + 1) },
+			resp3: "%4611686018427387904\r\n",
+			err:   resp3.ErrInvalidAggregateTypeLength,
+		},
+		{
+			name:  "Null",
+			write: (*resp3.Writer).WriteNull,
+			resp2: "$-1\r\n",
+			resp3: "_\r\n",
+		},
+		{
+			name:  "Push",
+			write: func(rw *resp3.Writer) error { return rw.WritePushHeader(2) },
+			resp2: "*2\r\n",
+			resp3: ">2\r\n",
+		},
+		{
+			name:  "Set",
+			write: func(rw *resp3.Writer) error { return rw.WriteSetHeader(2) },
+			resp2: "*2\r\n",
+			resp3: "~2\r\n",
+		},
+		{
+			name:  "VerbatimString",
+			write: func(rw *resp3.Writer) error { return rw.WriteVerbatimString("txt", "hello") },
+			resp2: "$5\r\nhello\r\n",
+			resp3: "=9\r\ntxt:hello\r\n",
+		},
+		{
+			name:  "Attribute",
+			write: func(rw *resp3.Writer) error { return rw.WriteAttributeHeader(1) },
+			resp2: "|1\r\n",
+			resp3: "|1\r\n",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rw, assert := newTestWriter(t)
+			for _, p := range []uint8{0, 3} {
+				rw.Protocol = p
+				assert(c.resp3, nil, c.write(rw))
+			}
+			rw.Protocol = 2
+			assert(c.resp2, c.err, c.write(rw))
+		})
+	}
+}
+
+func TestWriterWritePipeline(t *testing.T) {
+	var b bytes.Buffer
+	w := &countingWriter{w: &b}
+	rw := resp3.NewWriter(w)
+
+	assertError(t, nil, rw.WritePipeline(
+		[][]byte{[]byte("SET"), []byte("key"), []byte("value")},
+		[][]byte{[]byte("GET"), []byte("key")},
+	))
+	assertBytes(t, "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n", b.Bytes())
+	if w.writes != 1 {
+		t.Errorf("got %d writes, expected 1", w.writes)
+	}
+
+	b.Reset()
+	assertError(t, resp3.ErrEmptyCommand, rw.WritePipeline([][]byte{[]byte("PING")}, nil))
+	assertBytes(t, "", b.Bytes())
+
+	assertError(t, nil, rw.WritePipeline())
+}
+
+type countingWriter struct {
+	w      io.Writer
+	writes int
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	c.writes++
+	return c.w.Write(b)
+}
+
+func TestWriterWriteValue(t *testing.T) {
+	type namedString string
+
+	one := 1
+
+	for _, c := range []struct {
+		name string
+		v    interface{}
+		s    string
+		err  error
+	}{
+		{name: "Nil", s: "_\r\n"},
+		{name: "NilPointer", v: (*int)(nil), s: "_\r\n"},
+		{name: "NilSlice", v: []string(nil), s: "_\r\n"},
+		{name: "NilBytes", v: []byte(nil), s: "_\r\n"},
+		{name: "NilMap", v: map[string]string(nil), s: "_\r\n"},
+		{name: "NilBigInt", v: (*big.Int)(nil), s: "_\r\n"},
+		{name: "String", v: "hello", s: "$5\r\nhello\r\n"},
+		{name: "NamedString", v: namedString("hello"), s: "$5\r\nhello\r\n"},
+		{name: "Bytes", v: []byte("hello"), s: "$5\r\nhello\r\n"},
+		{name: "ByteArray", v: [3]byte{'a', 'b', 'c'}, s: "$3\r\nabc\r\n"},
+		{name: "Bool", v: true, s: "#t\r\n"},
+		{name: "Int", v: -1, s: ":-1\r\n"},
+		{name: "Int8", v: int8(-8), s: ":-8\r\n"},
+		{name: "Int64", v: int64(64), s: ":64\r\n"},
+		{name: "Uint32", v: uint32(32), s: ":32\r\n"},
+		{name: "Uint64", v: uint64(math.MaxUint64), s: "(18446744073709551615\r\n"},
+		{name: "Float32", v: float32(1.5), s: ",1.5\r\n"},
+		{name: "Float64", v: 2.5, s: ",2.5\r\n"},
+		{name: "BigInt", v: big.NewInt(-123), s: "(-123\r\n"},
+		{name: "BigIntValue", v: *big.NewInt(123), s: "(123\r\n"},
+		{name: "Pointer", v: &one, s: ":1\r\n"},
+		{name: "Error", v: errors.New("ERR hello\nworld"), s: "-ERR hello world\r\n"},
+		{name: "Slice", v: []interface{}{"a", 1, nil, []int{2}}, s: "*4\r\n$1\r\na\r\n:1\r\n_\r\n*1\r\n:2\r\n"},
+		{name: "Array", v: [2]bool{true, false}, s: "*2\r\n#t\r\n#f\r\n"},
+		{name: "Map", v: map[string]int{"a": 1}, s: "%1\r\n$1\r\na\r\n:1\r\n"},
+		{name: "Struct", v: struct {
+			A string `resp3:"a"`
+		}{"b"}, s: "%1\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{name: "Chan", v: make(chan int), err: resp3.ErrTypeMismatch},
+		{name: "NestedFunc", v: []interface{}{func() {}}, s: "*1\r\n", err: resp3.ErrTypeMismatch},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rw, assert := newTestWriter(t)
+			assert(c.s, c.err, rw.WriteValue(c.v))
+		})
+	}
+
+	t.Run("RedisError", func(t *testing.T) {
+		rr := resp3.NewReader(strings.NewReader("-WRONGTYPE wrong type\r\n"))
+		re, err := rr.ReadErrorReply()
+		assertError(t, nil, err)
+
+		rw, assert := newTestWriter(t)
+		assert("-WRONGTYPE wrong type\r\n", nil, rw.WriteValue(re))
+	})
+
+	t.Run("RedisErrorMultiLine", func(t *testing.T) {
+		rr := resp3.NewReader(strings.NewReader("!10\r\nERR a\r\nb c\r\n"))
+		re, err := rr.ReadErrorReply()
+		assertError(t, nil, err)
+
+		rw, assert := newTestWriter(t)
+		assert("!10\r\nERR a\r\nb c\r\n", nil, rw.WriteValue(re))
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		type node struct {
+			Next *node `resp3:"next"`
+		}
+
+		n := &node{}
+		n.Next = n
+
+		s := []interface{}{nil}
+		s[0] = s
+
+		m := map[string]interface{}{}
+		m["m"] = m
+
+		for _, v := range []interface{}{n, s, m} {
+			var b bytes.Buffer
+			rw := resp3.NewWriter(&b)
+			assertError(t, resp3.ErrValueTooDeep, rw.WriteValue(v))
+		}
+	})
+}
+
+func TestWriterWriteValues(t *testing.T) {
+	rw, assert := newTestWriter(t)
+	assert("*0\r\n", nil, rw.WriteValues())
+	assert("*3\r\n$1\r\na\r\n:1\r\n_\r\n", nil, rw.WriteValues("a", 1, nil))
+	assert("*2\r\n#t\r\n*1\r\n:2\r\n", nil, rw.WriteValues(true, []int{2}))
+	assert("*2\r\n$1\r\na\r\n", resp3.ErrTypeMismatch, rw.WriteValues("a", make(chan int)))
+}
+
+func BenchmarkWriterWriteDouble(b *testing.B) {
+	for _, c := range []struct {
+		name string
+		f    float64
+	}{
+		{"Integer", 1234567},
+		{"Fraction", 1234.567},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			rw := resp3.NewWriter(ioutil.Discard)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := rw.WriteDouble(c.f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWriterWriteSimpleString(b *testing.B) {
+	for _, c := range []struct {
+		name  string
+		write func(*resp3.Writer, []byte) error
+	}{
+		{"Safe", (*resp3.Writer).WriteSimpleString},
+		{"Unsafe", (*resp3.Writer).WriteSimpleStringUnsafe},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			rw := resp3.NewWriter(ioutil.Discard)
+			s := []byte("OK")
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := c.write(rw, s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}